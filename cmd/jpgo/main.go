@@ -11,6 +11,10 @@ Evaluate the JMESPath expression against JSON data from a file:
 
 	jp.go -input /tmp/data.json "foo.bar.baz"
 
+Print, after the result, the time spent evaluating each node of the expression:
+
+	jp.go -profile -input /tmp/data.json "sort_by(foo, &bar)[*].baz"
+
 This program can also be used as an executable to the jp-compliance
 runner (github.com/jmespath-community/jmespath.test).
 */
@@ -22,8 +26,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"text/tabwriter"
 
 	"github.com/jmespath-community/go-jmespath/pkg/api"
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
 	"github.com/jmespath-community/go-jmespath/pkg/parsing"
 	"github.com/spf13/cobra"
 )
@@ -37,6 +44,7 @@ func main() {
 	}
 	cmd.Flags().BoolVar(&command.astOnly, "ast", false, "Print the AST for the input expression and exit.")
 	cmd.Flags().StringVar(&command.inputFile, "input", "", "Filename containing JSON data to search. If not provided, data is read from stdin.")
+	cmd.Flags().BoolVar(&command.profile, "profile", false, "Print the calls, time and elements produced of each node of the expression to stderr.")
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -46,9 +54,10 @@ func main() {
 type command struct {
 	astOnly   bool
 	inputFile string
+	profile   bool
 }
 
-func (c command) run(cmd *cobra.Command, args []string) error {
+func (c *command) run(cmd *cobra.Command, args []string) error {
 	if len(args) != 1 {
 		return errors.New("error: expected a single argument (the JMESPath expression)")
 	}
@@ -66,26 +75,35 @@ func (c command) run(cmd *cobra.Command, args []string) error {
 		fmt.Printf("%s\n", parsed)
 		return nil
 	}
-	var inputData []byte
+	// the input is streamed, only the parts of the document the
+	// expression needs are decoded
+	input := io.Reader(os.Stdin)
 	if c.inputFile != "" {
-		inputData, err = os.ReadFile(c.inputFile)
+		file, err := os.Open(c.inputFile)
 		if err != nil {
 			return fmt.Errorf("error loading file %s: %w", c.inputFile, err)
 		}
-	} else {
-		// If an input data file is not provided then we read the
-		// data from stdin.
-		inputData, err = io.ReadAll(os.Stdin)
-		if err != nil {
-			return fmt.Errorf("error reading from stdin: %w", err)
-		}
+		defer file.Close()
+		input = file
 	}
-	var data any
-	if err := json.Unmarshal(inputData, &data); err != nil {
-		return fmt.Errorf("invalid input JSON: %w", err)
+	// results do not depend on the iteration order of maps, so that
+	// running the same search twice prints the same output
+	opts := []interpreter.Option{interpreter.WithDeterministicOrder()}
+	var result any
+	if c.profile {
+		result, err = c.searchProfile(expression, input, opts)
+	} else {
+		result, err = api.SearchReader(expression, input, opts...)
 	}
-	result, err := api.Search(expression, data)
 	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, api.ErrTrailingData) {
+			return fmt.Errorf("invalid input JSON: %w", err)
+		}
+		var jpErr *jperror.Error
+		if errors.As(err, &jpErr) && jpErr.HasLocation() {
+			return fmt.Errorf("error executing expression: %w\n%s", err, jpErr.HighlightLocation())
+		}
 		return fmt.Errorf("error executing expression: %w", err)
 	}
 	toJSON, err := json.MarshalIndent(result, "", "  ")
@@ -95,3 +113,24 @@ func (c command) run(cmd *cobra.Command, args []string) error {
 	fmt.Println(string(toJSON))
 	return nil
 }
+
+// searchProfile decodes the whole input, so that every node of the expression is
+// evaluated by the interpreter, and prints the profile of the search.
+func (c *command) searchProfile(expression string, input io.Reader, opts []interpreter.Option) (any, error) {
+	// the input is checked like the searches without a profile check it
+	data, err := api.SearchReader("@", input)
+	if err != nil {
+		return nil, err
+	}
+	profile := interpreter.NewProfile()
+	result, err := api.Search(expression, data, append(opts, interpreter.WithProfile(profile))...)
+	if err != nil {
+		return nil, err
+	}
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "CALLS\tTIME\tELEMENTS\t\tNODE")
+	for _, node := range profile.Report() {
+		fmt.Fprintf(w, "%d\t%s\t%d\t\t%s %s\n", node.Calls, node.Time, node.Elements, node.NodeType, expression[node.Start:node.End])
+	}
+	return result, w.Flush()
+}
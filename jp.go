@@ -1,33 +1,154 @@
 package jmespath
 
 import (
+	"context"
+
 	"github.com/jmespath-community/go-jmespath/pkg/api"
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
 	"github.com/jmespath-community/go-jmespath/pkg/functions"
 	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/locate"
+	"github.com/jmespath-community/go-jmespath/pkg/mutate"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
 	"github.com/jmespath-community/go-jmespath/pkg/parsing"
 )
 
 // api types
 
-type JMESPath = api.JMESPath
+const DefaultCacheSize = api.DefaultCacheSize
+
+type (
+	JMESPath        = api.JMESPath
+	CompileOption   = api.CompileOption
+	CacheStats      = api.CacheStats
+	ConversionError = api.ConversionError
+)
 
 var (
-	Compile     = api.Compile
-	MustCompile = api.MustCompile
-	Search      = api.Search
+	Compile              = api.Compile
+	CompileWithFunctions = api.CompileWithFunctions
+	MustCompile          = api.MustCompile
+	Search               = api.Search
+	SearchContext        = api.SearchContext
+	SearchReader         = api.SearchReader
+	SearchReaderContext  = api.SearchReaderContext
+	SearchReaderCompiled = api.SearchReaderCompiled
+	Locate               = api.Locate
+	LocateContext        = api.LocateContext
+	LocateCompiled       = api.LocateCompiled
+	Set                  = api.Set
+	SetCompiled          = api.SetCompiled
+	Update               = api.Update
+	UpdateCompiled       = api.UpdateCompiled
+	Delete               = api.Delete
+	DeleteCompiled       = api.DeleteCompiled
+	WithOptimization     = api.WithOptimization
+	WithVirtualMachine   = api.WithVirtualMachine
+	WithUseNumber        = api.WithUseNumber
+	WithPrecision        = api.WithPrecision
+	WithFunctions        = api.WithFunctions
+	SetCacheSize         = api.SetCacheSize
+	GetCacheStats        = api.GetCacheStats
+	ErrTrailingData      = api.ErrTrailingData
 )
 
+// generic api functions, generic functions cannot be assigned to variables
+// and generic types cannot be aliased, CompiledAs is api.CompiledAs.
+
+// SearchAs evaluates a JMESPath expression and converts the result to T, see api.SearchAs.
+func SearchAs[T any](expression string, data any, opts ...Option) (T, error) {
+	return api.SearchAs[T](expression, data, opts...)
+}
+
+// SearchContextAs is like SearchAs but aborts the evaluation when the context is done.
+func SearchContextAs[T any](ctx context.Context, expression string, data any, opts ...Option) (T, error) {
+	return api.SearchContextAs[T](ctx, expression, data, opts...)
+}
+
+// CompileAs is like Compile but the results of the returned expression are converted to T.
+func CompileAs[T any](expression string, opts ...CompileOption) (api.CompiledAs[T], error) {
+	return api.CompileAs[T](expression, opts...)
+}
+
+// MustCompileAs is like CompileAs but panics if the expression cannot be parsed.
+func MustCompileAs[T any](expression string, opts ...CompileOption) api.CompiledAs[T] {
+	return api.MustCompileAs[T](expression, opts...)
+}
+
+// NewCompiledAs wraps a compiled JMESPath so that its results are converted to T.
+func NewCompiledAs[T any](jmespath JMESPath) api.CompiledAs[T] {
+	return api.NewCompiledAs[T](jmespath)
+}
+
 // interpreter types
 
-type Option = interpreter.Option
+type (
+	Option              = interpreter.Option
+	BudgetExceededError = interpreter.BudgetExceededError
+	Tracer              = interpreter.Tracer
+	Forker              = interpreter.Forker
+	Profile             = interpreter.Profile
+	NodeProfile         = interpreter.NodeProfile
+)
+
+var (
+	WithFunctionCaller     = interpreter.WithFunctionCaller
+	WithContext            = interpreter.WithContext
+	WithBindings           = interpreter.WithBindings
+	WithVariables          = interpreter.WithVariables
+	WithMaxSteps           = interpreter.WithMaxSteps
+	WithMaxDepth           = interpreter.WithMaxDepth
+	WithMaxResultElements  = interpreter.WithMaxResultElements
+	WithArbitraryPrecision = interpreter.WithArbitraryPrecision
+	WithParallelism        = interpreter.WithParallelism
+	WithDeterministicOrder = interpreter.WithDeterministicOrder
+	WithOrderedObjects     = interpreter.WithOrderedObjects
+	WithTracer             = interpreter.WithTracer
+	WithProfile            = interpreter.WithProfile
+	NewProfile             = interpreter.NewProfile
+)
+
+// locate types
+
+type (
+	Match = locate.Match
+	Path  = locate.Path
+)
 
-var WithFunctionCaller = interpreter.WithFunctionCaller
+// mutate types
+
+type MutateOption = mutate.Option
+
+var (
+	WithCopyOnWrite        = mutate.WithCopyOnWrite
+	WithInterpreterOptions = mutate.WithInterpreterOptions
+)
+
+// ordered types
+
+type OrderedObject = ordered.Object
+
+var (
+	NewOrderedObject = ordered.NewObject
+	DecodeOrdered    = ordered.Decode
+	UnmarshalOrdered = ordered.Unmarshal
+)
+
+// error types
+
+type (
+	Error     = jperror.Error
+	ErrorKind = jperror.Kind
+)
 
 // parsing types
 
 type SyntaxError = parsing.SyntaxError
 
-var NewParser = parsing.NewParser
+var (
+	NewParser = parsing.NewParser
+	Format    = parsing.Format
+)
 
 // function types
 
@@ -37,6 +158,13 @@ type (
 	FunctionEntry = functions.FunctionEntry
 	ArgSpec       = functions.ArgSpec
 	ExpRef        = functions.ExpRef
+	Registry      = functions.Registry
+)
+
+var (
+	NewRegistry                   = functions.NewRegistry
+	DefaultRegistry               = functions.DefaultRegistry
+	NewFunctionCallerFromRegistry = interpreter.NewFunctionCallerFromRegistry
 )
 
 const (
@@ -2,12 +2,14 @@ package jmespath
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
 	"github.com/jmespath-community/go-jmespath/pkg/parsing"
 	"github.com/stretchr/testify/assert"
 )
@@ -85,7 +87,59 @@ func runSyntaxTestCase(assert *assert.Assertions, given any, testcase TestCase,
 	// an error when we try to evaluate the expression.
 	// fmt.Println(fmt.Sprintf("%s: %s", filename, testcase.Expression))
 	_, err := Search(testcase.Expression, given)
-	assert.NotNil(err, fmt.Sprintf("Expression: %s", testcase.Expression))
+	if assert.NotNil(err, fmt.Sprintf("Expression: %s", testcase.Expression)) {
+		var jpErr *jperror.Error
+		if assert.True(errors.As(err, &jpErr), fmt.Sprintf("(%s) Expression: %s -- %s", filename, testcase.Expression, err)) {
+			assert.Equal(testcase.Error, string(jpErr.Kind), fmt.Sprintf("(%s) Expression: %s -- %s", filename, testcase.Expression, err))
+		}
+	}
+	runVirtualMachineErrorTestCase(assert, given, testcase.Expression, err)
+}
+
+// runVirtualMachineErrorTestCase checks that the virtual machine fails like the tree
+// interpreter did, with the same error kind at the same location.
+func runVirtualMachineErrorTestCase(assert *assert.Assertions, given any, expression string, want error) {
+	compiled, err := Compile(expression, WithVirtualMachine())
+	if err == nil {
+		_, err = compiled.Search(given)
+	}
+	msg := fmt.Sprintf("Expression: %s", expression)
+	if !assert.NotNil(err, msg) {
+		return
+	}
+	var wantErr, jpErr *jperror.Error
+	if errors.As(want, &wantErr) && assert.True(errors.As(err, &jpErr), msg) {
+		assert.Equal(wantErr.Kind, jpErr.Kind, msg)
+		assert.Equal(wantErr.Start, jpErr.Start, msg)
+		assert.Equal(wantErr.End, jpErr.End, msg)
+	}
+}
+
+func TestVirtualMachineErrors(t *testing.T) {
+	assert := assert.New(t)
+	given := map[string]any{"foo": "bar", "list": []any{1.0, "a"}}
+	expressions := []string{
+		"foo[",
+		"length(@, @)",
+		"unknown(@)",
+		"abs(foo)",
+		"foo | abs(@)",
+		"list[*].abs(@)",
+		"sort(list)",
+		"sort_by(list, &@)",
+		"map(&abs(@), list)",
+		"$missing",
+		"foo.[abs(@)]",
+		"{a: abs(foo)}",
+		"let $x = foo in abs($x)",
+		"list[?abs(@) > `0`]",
+	}
+	for _, expression := range expressions {
+		_, err := Search(expression, given)
+		if assert.NotNil(err, fmt.Sprintf("Expression: %s", expression)) {
+			runVirtualMachineErrorTestCase(assert, given, expression, err)
+		}
+	}
 }
 
 func runTestCase(assert *assert.Assertions, given any, testcase TestCase, filename string) {
@@ -108,4 +162,11 @@ func runTestCase(assert *assert.Assertions, given any, testcase TestCase, filena
 	if assert.Nil(err, fmt.Sprintf("Expression: %s", testcase.Expression)) {
 		assert.Equal(testcase.Result, actual, fmt.Sprintf("Expression: %s", testcase.Expression))
 	}
+	compiled, err := Compile(testcase.Expression, WithVirtualMachine())
+	if assert.Nil(err, fmt.Sprintf("Expression: %s", testcase.Expression)) {
+		actual, err := compiled.Search(given)
+		if assert.Nil(err, fmt.Sprintf("Expression: %s", testcase.Expression)) {
+			assert.Equal(testcase.Result, actual, fmt.Sprintf("Expression: %s", testcase.Expression))
+		}
+	}
 }
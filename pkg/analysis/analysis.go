@@ -0,0 +1,364 @@
+// Package analysis infers the types of the results of JMESPath expressions
+// without evaluating them, and reports the errors that can be proven ahead of time.
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/functions"
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+)
+
+// Result is the outcome of the analysis of an expression.
+type Result struct {
+	// Type is the type of the values the expression can produce.
+	Type Type
+	// Errors are the errors the expression produces whenever the part of the
+	// expression they are located on is evaluated. Elements of arrays are
+	// assumed to be of the type of the array items, empty arrays can hide errors.
+	Errors []error
+}
+
+// Analyze analyzes an expression evaluated against data described by a JSON Schema,
+// given as decoded JSON, with the default functions.
+func Analyze(node parsing.ASTNode, schema any) Result {
+	return AnalyzeType(node, FromSchema(schema))
+}
+
+// AnalyzeType analyzes an expression evaluated against data of the given type,
+// with the default functions.
+func AnalyzeType(node parsing.ASTNode, input Type) Result {
+	a := analyzer{
+		root:      input,
+		entries:   functions.GetDefaultFunctions(),
+		functions: map[string]functions.FunctionEntry{},
+	}
+	for _, entry := range a.entries {
+		a.functions[entry.Name] = entry
+	}
+	t := a.infer(node, input, map[string]Type{})
+	return Result{Type: t, Errors: a.errors}
+}
+
+type analyzer struct {
+	root      Type
+	entries   []functions.FunctionEntry
+	functions map[string]functions.FunctionEntry
+	errors    []error
+}
+
+func (a *analyzer) report(err error, node parsing.ASTNode) {
+	if jpErr, ok := err.(*jperror.Error); ok && node.End > node.Start {
+		err = jpErr.Locate(node.Start, node.End)
+	}
+	a.errors = append(a.errors, err)
+}
+
+// infer returns the type of the result of a node evaluated against a value of the
+// current type, with the given variables in scope.
+func (a *analyzer) infer(node parsing.ASTNode, current Type, scope map[string]Type) Type {
+	switch node.NodeType {
+	case parsing.ASTField:
+		return current.Field(node.Value.(string))
+	case parsing.ASTLiteral:
+		return typeOf(node.Value)
+	case parsing.ASTIdentity, parsing.ASTCurrentNode:
+		return current
+	case parsing.ASTRootNode:
+		return a.root
+	case parsing.ASTVariable:
+		name := node.Value.(string)
+		if t, ok := scope[name]; ok {
+			return t
+		}
+		// the variable may be bound when the evaluation starts
+		return Any()
+	case parsing.ASTLetExpression:
+		inner := make(map[string]Type, len(scope))
+		for name, t := range scope {
+			inner[name] = t
+		}
+		// bindings are evaluated in the outer scope
+		for _, binding := range node.Children[0].Children {
+			inner[binding.Children[0].Value.(string)] = a.infer(binding.Children[1], current, scope)
+		}
+		return a.infer(node.Children[1], current, inner)
+	case parsing.ASTIndex:
+		result := Type{}
+		if current.Has(Array) {
+			result = current.Elements().Union(Of(Null))
+		}
+		if current.Kind&^Array != 0 {
+			result = result.Union(Of(Null))
+		}
+		return result
+	case parsing.ASTSlice:
+		result := Type{}
+		if current.Has(Array) {
+			if parts := node.Value.([]*int); parts[2] != nil && *parts[2] == 0 {
+				a.report(jperror.New(jperror.InvalidValue, "invalid slice, step cannot be 0"), node)
+			} else {
+				result = current.Only(Array)
+			}
+		}
+		if current.Has(String) {
+			result = result.Union(Of(String))
+		}
+		if current.Kind&^(Array|String) != 0 {
+			result = result.Union(Of(Null))
+		}
+		return result
+	case parsing.ASTSubexpression, parsing.ASTIndexExpression:
+		left := a.infer(node.Children[0], current, scope)
+		result := Type{}
+		if left.Kind&^Null != 0 {
+			result = a.infer(node.Children[1], left.Without(Null), scope)
+		}
+		if left.Has(Null) {
+			result = result.Union(Of(Null))
+		}
+		return result
+	case parsing.ASTProjection:
+		// string slices are projections whose right hand side is applied to the string
+		allowString := false
+		if left := node.Children[0]; left.NodeType == parsing.ASTIndexExpression {
+			allowString = len(left.Children) > 1 && left.Children[1].NodeType == parsing.ASTSlice
+		}
+		left := a.infer(node.Children[0], current, scope)
+		result := Type{}
+		if left.Has(Array) {
+			result = ArrayOf(a.infer(node.Children[1], left.Elements(), scope).Without(Null))
+		}
+		projected := Array
+		if allowString {
+			projected |= String
+			if left.Has(String) {
+				result = result.Union(a.infer(node.Children[1], Of(String), scope))
+			}
+		}
+		if left.Kind&^projected != 0 {
+			result = result.Union(Of(Null))
+		}
+		return result
+	case parsing.ASTFilterProjection:
+		left := a.infer(node.Children[0], current, scope)
+		result := Type{}
+		if left.Has(Array) {
+			a.infer(node.Children[2], left.Elements(), scope)
+			result = ArrayOf(a.infer(node.Children[1], left.Elements(), scope).Without(Null))
+		}
+		if left.Kind&^Array != 0 {
+			result = result.Union(Of(Null))
+		}
+		return result
+	case parsing.ASTValueProjection:
+		left := a.infer(node.Children[0], current, scope)
+		result := Type{}
+		if left.Has(Object) {
+			result = ArrayOf(a.infer(node.Children[1], left.Values(), scope).Without(Null))
+		}
+		if left.Kind&^Object != 0 {
+			result = result.Union(Of(Null))
+		}
+		return result
+	case parsing.ASTFlatten:
+		left := a.infer(node.Children[0], current, scope)
+		result := Type{}
+		if left.Has(Array) {
+			elements := left.Elements()
+			flattened := elements.Without(Array)
+			if elements.Has(Array) {
+				flattened = flattened.Union(elements.Elements())
+			}
+			result = ArrayOf(flattened)
+		}
+		if left.Kind&^Array != 0 {
+			result = result.Union(Of(Null))
+		}
+		return result
+	case parsing.ASTPipe:
+		result := current
+		for _, child := range node.Children {
+			result = a.infer(child, result, scope)
+		}
+		return result
+	case parsing.ASTOrExpression, parsing.ASTAndExpression:
+		left := a.infer(node.Children[0], current, scope)
+		right := a.infer(node.Children[1], current, scope)
+		return left.Union(right)
+	case parsing.ASTNotExpression:
+		a.infer(node.Children[0], current, scope)
+		return Of(Boolean)
+	case parsing.ASTComparator:
+		left := a.infer(node.Children[0], current, scope)
+		right := a.infer(node.Children[1], current, scope)
+		switch node.Value {
+		case parsing.TOKEQ, parsing.TOKNE:
+			return Of(Boolean)
+		}
+		return numeric(Of(Boolean), left, right)
+	case parsing.ASTArithmeticExpression:
+		left := a.infer(node.Children[0], current, scope)
+		right := a.infer(node.Children[1], current, scope)
+		return numeric(Of(Number), left, right)
+	case parsing.ASTArithmeticUnaryExpression:
+		operand := a.infer(node.Children[0], current, scope)
+		return numeric(Of(Number), operand)
+	case parsing.ASTExpRef:
+		// the elements the expression is applied to are unknown
+		a.infer(node.Children[0], Any(), scope)
+		return Of(Expref)
+	case parsing.ASTFunctionExpression:
+		return a.inferFunction(node, current, scope)
+	case parsing.ASTMultiSelectList:
+		items := Type{}
+		for _, child := range node.Children {
+			items = items.Union(a.infer(child, current, scope))
+		}
+		return ArrayOf(items)
+	case parsing.ASTMultiSelectHash:
+		properties := make(map[string]Type, len(node.Children))
+		for _, child := range node.Children {
+			properties[child.Value.(string)] = a.infer(child, current, scope)
+		}
+		return Type{Kind: Object, Properties: properties, AdditionalProperties: &Type{}}
+	case parsing.ASTKeyValPair:
+		return a.infer(node.Children[0], current, scope)
+	}
+	return Any()
+}
+
+// numeric returns the type of an operation on numbers, which is null
+// when one of the operands is not a number.
+func numeric(result Type, operands ...Type) Type {
+	for _, operand := range operands {
+		if !operand.Has(Number) {
+			return Of(Null)
+		}
+	}
+	for _, operand := range operands {
+		if !operand.Is(Number) {
+			return result.Union(Of(Null))
+		}
+	}
+	return result
+}
+
+func (a *analyzer) inferFunction(node parsing.ASTNode, current Type, scope map[string]Type) Type {
+	name := node.Value.(string)
+	// validate the call alone, its arguments are analyzed below
+	call := node
+	call.Children = make([]parsing.ASTNode, len(node.Children))
+	if err := interpreter.ValidateFunctions(call, a.entries...); err != nil {
+		a.report(err, node)
+		for _, arg := range node.Children {
+			a.infer(arg, current, scope)
+		}
+		return Type{}
+	}
+	entry := a.functions[name]
+	// the index of the argument whose elements expression references are applied to
+	elements, ok := exprefElements[name]
+	appliesExprefs := ok && elements < len(node.Children)
+	args := make([]Type, len(node.Children))
+	for i, arg := range node.Children {
+		if appliesExprefs && arg.NodeType == parsing.ASTExpRef {
+			args[i] = Of(Expref)
+		} else {
+			args[i] = a.infer(arg, current, scope)
+		}
+	}
+	results := make([]Type, len(node.Children))
+	if appliesExprefs {
+		for i, arg := range node.Children {
+			if arg.NodeType == parsing.ASTExpRef {
+				results[i] = a.infer(arg.Children[0], args[elements].Elements(), scope)
+			}
+		}
+	}
+	failed := false
+	for i, arg := range node.Children {
+		spec, ok := argSpec(entry.Arguments, i)
+		if !ok || accepts(spec.Types, args[i]) {
+			continue
+		}
+		failed = true
+		a.report(jperror.InvalidTypeArgument(name, i, fmt.Sprintf("invalid type: %s, expected: %s", args[i], formatTypes(spec.Types))), arg)
+	}
+	if failed {
+		return Type{}
+	}
+	if returns, ok := returnTypes[name]; ok {
+		return returns(args, results)
+	}
+	return Any()
+}
+
+func argSpec(specs []functions.ArgSpec, index int) (functions.ArgSpec, bool) {
+	if index < len(specs) {
+		return specs[index], true
+	}
+	if len(specs) != 0 && specs[len(specs)-1].Variadic {
+		return specs[len(specs)-1], true
+	}
+	return functions.ArgSpec{}, false
+}
+
+// accepts reports whether some values of the type are accepted by one of the
+// function argument types.
+func accepts(types []functions.JpType, t Type) bool {
+	if t.Kind == 0 {
+		// never evaluated
+		return true
+	}
+	for _, jpType := range types {
+		switch jpType {
+		case functions.JpAny:
+			return true
+		case functions.JpNumber:
+			if t.Has(Number) {
+				return true
+			}
+		case functions.JpString:
+			if t.Has(String) {
+				return true
+			}
+		case functions.JpArray:
+			if t.Has(Array) {
+				return true
+			}
+		case functions.JpObject:
+			if t.Has(Object) {
+				return true
+			}
+		case functions.JpExpref:
+			if t.Has(Expref) {
+				return true
+			}
+		case functions.JpArrayNumber:
+			if t.Has(Array) && t.Elements().Has(Number) {
+				return true
+			}
+		case functions.JpArrayString:
+			if t.Has(Array) && t.Elements().Has(String) {
+				return true
+			}
+		case functions.JpArrayArray:
+			if t.Has(Array) && t.Elements().Has(Array) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func formatTypes(types []functions.JpType) string {
+	names := make([]string, 0, len(types))
+	for _, jpType := range types {
+		names = append(names, string(jpType))
+	}
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,146 @@
+package analysis
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+const peopleSchema = `{
+	"type": "object",
+	"required": ["names", "people", "count"],
+	"properties": {
+		"names": {"type": "array", "items": {"type": "string"}},
+		"count": {"type": "integer"},
+		"owner": {"type": "string"},
+		"people": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"required": ["name", "age"],
+				"properties": {
+					"name": {"type": "string"},
+					"age": {"type": "number"},
+					"tags": {"type": "array", "items": {"type": "string"}}
+				},
+				"additionalProperties": false
+			}
+		}
+	},
+	"additionalProperties": false
+}`
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		wantErrors []string
+	}{
+		{expression: "names", want: "array[string]"},
+		{expression: "names[0]", want: "string|null"},
+		{expression: "names[1:]", want: "array[string]"},
+		{expression: "owner", want: "string|null"},
+		{expression: "missing", want: "null"},
+		{expression: "count.foo", want: "null"},
+		{expression: "people[*].name", want: "array[string]"},
+		{expression: "people[?age > `18`].age", want: "array[number]"},
+		{expression: "people[].tags[]", want: "array[string]"},
+		{expression: "people[*].tags", want: "array[array[string]]"},
+		{expression: "people[0].{n: name, a: age}.n", want: "string|null"},
+		{expression: "[names, count]", want: "array[number|array[string]]"},
+		{expression: "count + `1`", want: "number"},
+		{expression: "owner + `1`", want: "null"},
+		{expression: "count > `1`", want: "boolean"},
+		{expression: "owner == 'x'", want: "boolean"},
+		{expression: "!names", want: "boolean"},
+		{expression: "owner || count", want: "number|string|null"},
+		{expression: "names | [0]", want: "string|null"},
+		{expression: "length(names)", want: "number"},
+		{expression: "sort_by(people, &age)[*].name", want: "array[string]"},
+		{expression: "map(&age, people)", want: "array[number]"},
+		{expression: "max_by(people, &age).name", want: "string|null"},
+		{expression: "keys(@)", want: "array[string]"},
+		{expression: "let $p = people in $p[0].age", want: "number|null"},
+		{expression: "$extra", want: "any"},
+		{expression: "unknown_field.foo", want: "null"},
+		{
+			expression: "sum(names)",
+			want:       "never",
+			wantErrors: []string{"sum(names)\n    ^^^^^"},
+		},
+		{
+			expression: "people[*].length(age)",
+			want:       "array[never]",
+			wantErrors: []string{"people[*].length(age)\n                 ^^^"},
+		},
+		{
+			expression: "lenght(@)",
+			want:       "never",
+			wantErrors: []string{"lenght(@)\n^^^^^^^^^"},
+		},
+		{
+			expression: "abs(count, count)",
+			want:       "never",
+			wantErrors: []string{"abs(count, count)\n^^^^^^^^^^^^^^^^^"},
+		},
+		{
+			expression: "sort_by(people, &abs(name))",
+			want:       "array[object]",
+			wantErrors: []string{"sort_by(people, &abs(name))\n                     ^^^^"},
+		},
+		{
+			expression: "names[::0]",
+			want:       "never",
+			wantErrors: []string{"names[::0]\n      ^^^"},
+		},
+	}
+	var schema any
+	assert.NoError(t, json.Unmarshal([]byte(peopleSchema), &schema))
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			node, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			result := Analyze(node, schema)
+			assert.Equal(tt.want, result.Type.String())
+			var locations []string
+			for _, err := range result.Errors {
+				var jpErr *jperror.Error
+				if assert.True(errors.As(err, &jpErr)) {
+					jpErr.Expression = tt.expression
+					locations = append(locations, jpErr.HighlightLocation())
+				}
+			}
+			assert.Equal(tt.wantErrors, locations)
+		})
+	}
+}
+
+func TestFromSchema(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{`true`, "any"},
+		{`{}`, "any"},
+		{`false`, "never"},
+		{`{"type": "integer"}`, "number"},
+		{`{"type": ["string", "null"]}`, "string|null"},
+		{`{"enum": ["a", 1]}`, "number|string"},
+		{`{"const": true}`, "boolean"},
+		{`{"type": "array", "items": {"type": "array", "items": {"type": "number"}}}`, "array[array[number]]"},
+		{`{"type": "array", "items": [{"type": "number"}, {"type": "string"}]}`, "array[number|string]"},
+		{`{"anyOf": [{"type": "string"}, {"type": "array", "items": {"type": "string"}}]}`, "string|array[string]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			var schema any
+			assert.NoError(t, json.Unmarshal([]byte(tt.schema), &schema))
+			assert.Equal(t, tt.want, FromSchema(schema).String())
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package analysis
+
+// exprefElements maps the functions that apply expression references to the
+// elements of an array to the index of that array argument.
+var exprefElements = map[string]int{
+	"group_by": 0,
+	"map":      1,
+	"max_by":   0,
+	"min_by":   0,
+	"sort_by":  0,
+}
+
+// returnTypes maps the default functions to the type of their result, given the types
+// of their arguments and the types of the results of their expression references.
+var returnTypes = map[string]func(args []Type, results []Type) Type{
+	"abs":         returns(Number),
+	"avg":         returns(Number | Null),
+	"ceil":        returns(Number),
+	"contains":    returns(Boolean),
+	"ends_with":   returns(Boolean),
+	"find_first":  returns(Number | Null),
+	"find_last":   returns(Number | Null),
+	"floor":       returns(Number),
+	"from_items":  returns(Object),
+	"join":        returns(String),
+	"keys":        returnsArrayOf(String),
+	"length":      returns(Number),
+	"lower":       returns(String),
+	"merge":       returns(Object),
+	"pad_left":    returns(String),
+	"pad_right":   returns(String),
+	"replace":     returns(String),
+	"split":       returnsArrayOf(String),
+	"starts_with": returns(Boolean),
+	"sum":         returns(Number),
+	"to_number":   returns(Number | Null),
+	"to_string":   returns(String),
+	"trim":        returns(String),
+	"trim_left":   returns(String),
+	"trim_right":  returns(String),
+	"type":        returns(String),
+	"upper":       returns(String),
+	"group_by": func(args []Type, _ []Type) Type {
+		groups := ArrayOf(args[0].Elements())
+		return Type{Kind: Object, AdditionalProperties: &groups}
+	},
+	"items": func(args []Type, _ []Type) Type {
+		return ArrayOf(ArrayOf(Of(String).Union(args[0].Values())))
+	},
+	"map": func(_ []Type, results []Type) Type {
+		return ArrayOf(results[0])
+	},
+	"max":    returnsElement(Number | String),
+	"max_by": returnsElement(AnyKind),
+	"min":    returnsElement(Number | String),
+	"min_by": returnsElement(AnyKind),
+	"not_null": func(args []Type, _ []Type) Type {
+		result := Of(Null)
+		for _, arg := range args {
+			result = result.Union(arg.Without(Null))
+		}
+		return result
+	},
+	"reverse": returnsArgument(Array | String),
+	"sort":    returnsArgument(Array),
+	"sort_by": returnsArgument(Array),
+	"to_array": func(args []Type, _ []Type) Type {
+		result := args[0].Only(Array)
+		if other := args[0].Without(Array); other.Kind != 0 {
+			result = result.Union(ArrayOf(other))
+		}
+		return result
+	},
+	"values": func(args []Type, _ []Type) Type {
+		return ArrayOf(args[0].Values())
+	},
+	"zip": func(args []Type, _ []Type) Type {
+		items := Type{}
+		for _, arg := range args {
+			items = items.Union(arg.Elements())
+		}
+		return ArrayOf(ArrayOf(items))
+	},
+}
+
+func returns(kind Kind) func([]Type, []Type) Type {
+	return func([]Type, []Type) Type {
+		return Of(kind)
+	}
+}
+
+func returnsArrayOf(kind Kind) func([]Type, []Type) Type {
+	return func([]Type, []Type) Type {
+		return ArrayOf(Of(kind))
+	}
+}
+
+// returnsElement returns the type of functions returning an element of their
+// first argument, or null if it is empty.
+func returnsElement(kind Kind) func([]Type, []Type) Type {
+	return func(args []Type, _ []Type) Type {
+		return args[0].Elements().Only(kind).Union(Of(Null))
+	}
+}
+
+// returnsArgument returns the type of functions returning a value of the same
+// type as their first argument.
+func returnsArgument(kind Kind) func([]Type, []Type) Type {
+	return func(args []Type, _ []Type) Type {
+		return args[0].Only(kind)
+	}
+}
@@ -0,0 +1,123 @@
+package analysis
+
+// FromSchema returns the type of the values described by a JSON Schema given as
+// decoded JSON. It understands the type, const, enum, properties, required,
+// additionalProperties, items, anyOf and oneOf keywords, other keywords are
+// ignored and don't restrict the type.
+func FromSchema(schema any) Type {
+	switch s := schema.(type) {
+	case bool:
+		if s {
+			return Any()
+		}
+		return Type{}
+	case map[string]any:
+		return fromSchemaObject(s)
+	}
+	return Any()
+}
+
+func fromSchemaObject(schema map[string]any) Type {
+	if alternatives, ok := alternativesOf(schema); ok {
+		union := Type{}
+		for _, alternative := range alternatives {
+			union = union.Union(FromSchema(alternative))
+		}
+		return union
+	}
+	t := Type{Kind: schemaKind(schema)}
+	if t.Has(Array) {
+		switch items := schema["items"].(type) {
+		case nil:
+		case []any:
+			// tuple validation, the elements may be of any of the given types
+			elements := Type{}
+			for _, item := range items {
+				elements = elements.Union(FromSchema(item))
+			}
+			t.Items = &elements
+		default:
+			elements := FromSchema(items)
+			t.Items = &elements
+		}
+	}
+	if t.Has(Object) {
+		required := map[string]bool{}
+		if names, ok := schema["required"].([]any); ok {
+			for _, name := range names {
+				if name, ok := name.(string); ok {
+					required[name] = true
+				}
+			}
+		}
+		if properties, ok := schema["properties"].(map[string]any); ok {
+			t.Properties = make(map[string]Type, len(properties))
+			for name, property := range properties {
+				propertyType := FromSchema(property)
+				if !required[name] {
+					propertyType = propertyType.Union(Of(Null))
+				}
+				t.Properties[name] = propertyType
+			}
+		}
+		if additional, ok := schema["additionalProperties"]; ok {
+			additionalType := FromSchema(additional)
+			t.AdditionalProperties = &additionalType
+		}
+	}
+	return t
+}
+
+func alternativesOf(schema map[string]any) ([]any, bool) {
+	if alternatives, ok := schema["anyOf"].([]any); ok {
+		return alternatives, true
+	}
+	if alternatives, ok := schema["oneOf"].([]any); ok {
+		return alternatives, true
+	}
+	return nil, false
+}
+
+func schemaKind(schema map[string]any) Kind {
+	switch types := schema["type"].(type) {
+	case string:
+		return schemaTypeKind(types)
+	case []any:
+		var kind Kind
+		for _, name := range types {
+			if name, ok := name.(string); ok {
+				kind |= schemaTypeKind(name)
+			}
+		}
+		return kind
+	}
+	if value, ok := schema["const"]; ok {
+		return kindOf(value)
+	}
+	if values, ok := schema["enum"].([]any); ok {
+		var kind Kind
+		for _, value := range values {
+			kind |= kindOf(value)
+		}
+		return kind
+	}
+	return AnyKind
+}
+
+func schemaTypeKind(name string) Kind {
+	switch name {
+	case "null":
+		return Null
+	case "boolean":
+		return Boolean
+	case "number", "integer":
+		return Number
+	case "string":
+		return String
+	case "array":
+		return Array
+	case "object":
+		return Object
+	}
+	return 0
+}
@@ -0,0 +1,241 @@
+package analysis
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Kind is a set of kinds of values.
+type Kind uint8
+
+const (
+	Null Kind = 1 << iota
+	Boolean
+	Number
+	String
+	Array
+	Object
+	Expref
+)
+
+// AnyKind is the set of the kinds of all JSON values.
+const AnyKind = Null | Boolean | Number | String | Array | Object
+
+// Type describes the values an expression can produce.
+// The zero Type has no values, it is the type of expressions that
+// never produce a result.
+type Type struct {
+	// Kind is the set of kinds of the values.
+	Kind Kind
+	// Items is the type of the elements of arrays, nil if unknown.
+	Items *Type
+	// Properties are the types of the known properties of objects, they
+	// include null when the property may be missing.
+	Properties map[string]Type
+	// AdditionalProperties is the type of the other properties of objects,
+	// nil if unknown.
+	AdditionalProperties *Type
+}
+
+// Any returns the type of all JSON values.
+func Any() Type {
+	return Type{Kind: AnyKind}
+}
+
+// Of returns the type of values of the given kinds.
+func Of(kind Kind) Type {
+	return Type{Kind: kind}
+}
+
+// ArrayOf returns the type of arrays with elements of the given type.
+func ArrayOf(items Type) Type {
+	return Type{Kind: Array, Items: &items}
+}
+
+// Is reports whether all the values of the type are of the given kinds.
+func (t Type) Is(kind Kind) bool {
+	return t.Kind != 0 && t.Kind&^kind == 0
+}
+
+// Has reports whether some values of the type are of the given kinds.
+func (t Type) Has(kind Kind) bool {
+	return t.Kind&kind != 0
+}
+
+// Union returns the type of the values of both types.
+func (t Type) Union(other Type) Type {
+	union := Type{Kind: t.Kind | other.Kind}
+	switch {
+	case !other.Has(Array):
+		union.Items = t.Items
+	case !t.Has(Array):
+		union.Items = other.Items
+	case t.Items != nil && other.Items != nil:
+		items := t.Items.Union(*other.Items)
+		union.Items = &items
+	}
+	switch {
+	case !other.Has(Object):
+		union.Properties, union.AdditionalProperties = t.Properties, t.AdditionalProperties
+	case !t.Has(Object):
+		union.Properties, union.AdditionalProperties = other.Properties, other.AdditionalProperties
+	default:
+		if len(t.Properties) != 0 || len(other.Properties) != 0 {
+			union.Properties = map[string]Type{}
+			for name := range t.Properties {
+				union.Properties[name] = t.Field(name).Union(other.Field(name))
+			}
+			for name := range other.Properties {
+				union.Properties[name] = t.Field(name).Union(other.Field(name))
+			}
+		}
+		if t.AdditionalProperties != nil && other.AdditionalProperties != nil {
+			additional := t.AdditionalProperties.Union(*other.AdditionalProperties)
+			union.AdditionalProperties = &additional
+		}
+	}
+	return union
+}
+
+// Without returns the type of the values of the type that are not of the given kinds.
+func (t Type) Without(kind Kind) Type {
+	t.Kind &^= kind
+	if !t.Has(Array) {
+		t.Items = nil
+	}
+	if !t.Has(Object) {
+		t.Properties, t.AdditionalProperties = nil, nil
+	}
+	return t
+}
+
+// Only returns the type of the values of the type that are of the given kinds.
+func (t Type) Only(kind Kind) Type {
+	return t.Without(^kind)
+}
+
+// Elements returns the type of the elements of arrays of the type.
+func (t Type) Elements() Type {
+	if t.Items == nil {
+		return Any()
+	}
+	return *t.Items
+}
+
+// Field returns the type of the value of a field of the type, fields
+// of values that are not objects are null.
+func (t Type) Field(name string) Type {
+	field := Type{}
+	if t.Has(Object) {
+		if property, ok := t.Properties[name]; ok {
+			field = property
+		} else if t.AdditionalProperties != nil {
+			field = t.AdditionalProperties.Union(Of(Null))
+		} else {
+			field = Any()
+		}
+	}
+	if t.Kind&^Object != 0 {
+		field = field.Union(Of(Null))
+	}
+	return field
+}
+
+// Values returns the type of the values of the properties of objects of the type.
+func (t Type) Values() Type {
+	if t.AdditionalProperties == nil {
+		return Any()
+	}
+	values := *t.AdditionalProperties
+	for _, property := range t.Properties {
+		values = values.Union(property)
+	}
+	return values
+}
+
+// String returns the type in the notation used by the JMESPath specification,
+// like number, array[string] or string|null.
+func (t Type) String() string {
+	if t.Kind == 0 {
+		return "never"
+	}
+	if t.Kind == AnyKind && t.Items == nil && t.Properties == nil && t.AdditionalProperties == nil {
+		return "any"
+	}
+	var kinds []string
+	if t.Has(Boolean) {
+		kinds = append(kinds, "boolean")
+	}
+	if t.Has(Number) {
+		kinds = append(kinds, "number")
+	}
+	if t.Has(String) {
+		kinds = append(kinds, "string")
+	}
+	if t.Has(Array) {
+		if items := t.Elements().String(); items != "any" {
+			kinds = append(kinds, "array["+items+"]")
+		} else {
+			kinds = append(kinds, "array")
+		}
+	}
+	if t.Has(Object) {
+		kinds = append(kinds, "object")
+	}
+	if t.Has(Expref) {
+		kinds = append(kinds, "expref")
+	}
+	if t.Has(Null) {
+		kinds = append(kinds, "null")
+	}
+	return strings.Join(kinds, "|")
+}
+
+// typeOf returns the type of a JSON value.
+func typeOf(value any) Type {
+	switch v := value.(type) {
+	case nil:
+		return Of(Null)
+	case bool:
+		return Of(Boolean)
+	case string:
+		return Of(String)
+	case []any:
+		if len(v) == 0 {
+			return Of(Array)
+		}
+		items := Type{}
+		for _, item := range v {
+			items = items.Union(typeOf(item))
+		}
+		return ArrayOf(items)
+	case map[string]any:
+		properties := make(map[string]Type, len(v))
+		for key, value := range v {
+			properties[key] = typeOf(value)
+		}
+		return Type{Kind: Object, Properties: properties, AdditionalProperties: &Type{}}
+	}
+	if kind := kindOf(value); kind != 0 {
+		return Of(kind)
+	}
+	return Any()
+}
+
+func kindOf(value any) Kind {
+	switch value.(type) {
+	case nil:
+		return Null
+	case bool:
+		return Boolean
+	case string:
+		return String
+	case []any:
+		return Array
+	case map[string]any:
+		return Object
+	case json.Number, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return Number
+	}
+	return 0
+}
@@ -1,44 +1,102 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"strconv"
 
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/functions"
 	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/locate"
+	"github.com/jmespath-community/go-jmespath/pkg/mutate"
+	"github.com/jmespath-community/go-jmespath/pkg/optimizer"
 	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/jmespath-community/go-jmespath/pkg/stream"
 )
 
+// ErrTrailingData is returned when a reader contains data after the JSON document.
+var ErrTrailingData = errors.New("invalid character after top-level value")
+
 // JMESPath is the representation of a compiled JMES path query. A JMESPath is
 // safe for concurrent use by multiple goroutines.
 type JMESPath interface {
 	Search(any, ...interpreter.Option) (any, error)
+	SearchContext(context.Context, any, ...interpreter.Option) (any, error)
 }
 
 type jmesPath struct {
-	node parsing.ASTNode
+	expression     string
+	node           parsing.ASTNode
+	functionCaller interpreter.FunctionCaller
+	program        *interpreter.Program
+	useNumber      bool
 }
 
-func newJMESPath(node parsing.ASTNode) JMESPath {
+func newJMESPath(expression string, node parsing.ASTNode) jmesPath {
 	return jmesPath{
-		node: node,
+		expression: expression,
+		node:       node,
 	}
 }
 
 // Compile parses a JMESPath expression and returns, if successful, a JMESPath
 // object that can be used to match against data.
-func Compile(expression string) (JMESPath, error) {
-	parser := parsing.NewParser()
+func Compile(expression string, opts ...CompileOption) (JMESPath, error) {
+	var o CompileOptions
+	for _, opt := range opts {
+		if opt != nil {
+			o = opt(o)
+		}
+	}
+	var parserOptions []parsing.Option
+	if o.UseNumber {
+		parserOptions = append(parserOptions, parsing.WithUseNumber())
+	}
+	parser := parsing.NewParser(parserOptions...)
 	ast, err := parser.Parse(expression)
 	if err != nil {
 		return nil, err
 	}
-	return newJMESPath(ast), nil
+	if o.Functions != nil {
+		if err := interpreter.ValidateFunctions(ast, o.Functions...); err != nil {
+			return nil, newJMESPath(expression, ast).withExpression(err)
+		}
+	}
+	if o.Optimize {
+		var optimizerOptions []optimizer.Option
+		if o.Precision {
+			optimizerOptions = append(optimizerOptions, optimizer.WithPrecision(o.ArbitraryPrecision))
+		}
+		ast = optimizer.Optimize(ast, optimizerOptions...)
+	}
+	jp := newJMESPath(expression, ast)
+	jp.useNumber = o.UseNumber
+	if o.Functions != nil {
+		jp.functionCaller = interpreter.NewFunctionCaller(o.Functions...)
+	}
+	if o.VirtualMachine {
+		jp.program = interpreter.CompileProgram(ast)
+	}
+	return jp, nil
+}
+
+// CompileWithFunctions is like Compile but also checks, before any data is seen,
+// that the expression only calls the given functions and calls them with a valid
+// number of arguments. The returned JMESPath evaluates function calls with the
+// given functions unless a function caller is passed as an option. It is a shorthand
+// for Compile with WithFunctions.
+func CompileWithFunctions(expression string, funcs ...functions.FunctionEntry) (JMESPath, error) {
+	return Compile(expression, WithFunctions(funcs...))
 }
 
 // MustCompile is like Compile but panics if the expression cannot be parsed.
 // It simplifies safe initialization of global variables holding compiled
 // JMESPaths.
-func MustCompile(expression string) JMESPath {
-	jmespath, err := Compile(expression)
+func MustCompile(expression string, opts ...CompileOption) JMESPath {
+	jmespath, err := Compile(expression, opts...)
 	if err != nil {
 		panic(`jmespath: Compile(` + strconv.Quote(expression) + `): ` + err.Error())
 	}
@@ -47,15 +105,252 @@ func MustCompile(expression string) JMESPath {
 
 // Search evaluates a JMESPath expression against input data and returns the result.
 func (jp jmesPath) Search(data any, opts ...interpreter.Option) (any, error) {
-	intr := interpreter.NewInterpreter(data, nil)
-	return intr.Execute(jp.node, data, opts...)
+	opts = jp.withFunctionCaller(opts)
+	var result any
+	var err error
+	if jp.program != nil {
+		vm := interpreter.NewVirtualMachine(data, nil)
+		result, err = vm.Run(jp.program, data, opts...)
+	} else {
+		intr := interpreter.NewInterpreter(data, nil)
+		result, err = intr.Execute(jp.node, data, opts...)
+	}
+	if err != nil {
+		return nil, jp.withExpression(err)
+	}
+	return result, nil
+}
+
+// SearchContext is like Search but aborts the evaluation when the context is done,
+// see interpreter.WithContext.
+func (jp jmesPath) SearchContext(ctx context.Context, data any, opts ...interpreter.Option) (any, error) {
+	return jp.Search(data, append(opts[:len(opts):len(opts)], interpreter.WithContext(ctx))...)
+}
+
+// withFunctionCaller returns the options with the function caller of the expression, if any.
+func (jp jmesPath) withFunctionCaller(opts []interpreter.Option) []interpreter.Option {
+	if jp.functionCaller != nil {
+		return append([]interpreter.Option{interpreter.WithFunctionCaller(jp.functionCaller)}, opts...)
+	}
+	return opts
+}
+
+// withMutationFunctionCaller returns the options of a change with the function caller
+// of the expression, if any.
+func (jp jmesPath) withMutationFunctionCaller(opts []mutate.Option) []mutate.Option {
+	if jp.functionCaller != nil {
+		return append([]mutate.Option{mutate.WithInterpreterOptions(interpreter.WithFunctionCaller(jp.functionCaller))}, opts...)
+	}
+	return opts
+}
+
+// withExpression attaches the expression text to located errors
+// so that they can highlight where the error occurred.
+func (jp jmesPath) withExpression(err error) error {
+	if jpErr, ok := err.(*jperror.Error); ok && jpErr.Expression == "" {
+		withExpression := *jpErr
+		withExpression.Expression = jp.expression
+		return &withExpression
+	}
+	return err
+}
+
+// compiled returns the implementation of a JMESPath returned by Compile.
+func compiled(jmespath JMESPath) (jmesPath, error) {
+	if jp, ok := jmespath.(jmesPath); ok {
+		return jp, nil
+	}
+	return jmesPath{}, jperror.New(jperror.Unsupported, "the JMESPath was not returned by Compile")
+}
+
+// newOptions returns the options of an evaluation.
+func newOptions(opts []interpreter.Option) interpreter.Options {
+	var o interpreter.Options
+	for _, opt := range opts {
+		if opt != nil {
+			o = opt(o)
+		}
+	}
+	return o
+}
+
+// interpreterOptions returns the interpreter options of a change, see mutate.WithInterpreterOptions.
+func interpreterOptions(opts []mutate.Option) []interpreter.Option {
+	var o mutate.Options
+	for _, opt := range opts {
+		if opt != nil {
+			o = opt(o)
+		}
+	}
+	return o.Interpreter
+}
+
+// evaluationContext returns the context of an evaluation, see interpreter.WithContext.
+func evaluationContext(opts []interpreter.Option) context.Context {
+	if ctx := newOptions(opts).Context; ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// compileCached returns the compiled expression from the cache. Literal numbers
+// keep their exact value when the options enable interpreter.WithArbitraryPrecision.
+func compileCached(expression string, opts []interpreter.Option) (JMESPath, error) {
+	return searchCache.compile(expression, newOptions(opts).ArbitraryPrecision)
 }
 
 // Search evaluates a JMESPath expression against input data and returns the result.
+// Compiled expressions are cached, see SetCacheSize.
 func Search(expression string, data any, opts ...interpreter.Option) (any, error) {
-	compiled, err := Compile(expression)
+	compiled, err := compileCached(expression, opts)
 	if err != nil {
 		return nil, err
 	}
 	return compiled.Search(data, opts...)
 }
+
+// SearchContext is like Search but aborts the evaluation when the context is done.
+func SearchContext(ctx context.Context, expression string, data any, opts ...interpreter.Option) (any, error) {
+	compiled, err := compileCached(expression, opts)
+	if err != nil {
+		return nil, err
+	}
+	return compiled.SearchContext(ctx, data, opts...)
+}
+
+// SearchReader evaluates a JMESPath expression against the JSON document read from r,
+// see SearchReaderCompiled.
+func SearchReader(expression string, r io.Reader, opts ...interpreter.Option) (any, error) {
+	compiled, err := compileCached(expression, opts)
+	if err != nil {
+		return nil, err
+	}
+	return SearchReaderCompiled(compiled, r, opts...)
+}
+
+// SearchReaderContext is like SearchReader but aborts the evaluation when the context is done,
+// see interpreter.WithContext.
+func SearchReaderContext(ctx context.Context, expression string, r io.Reader, opts ...interpreter.Option) (any, error) {
+	return SearchReader(expression, r, append(opts[:len(opts):len(opts)], interpreter.WithContext(ctx))...)
+}
+
+// SearchReaderCompiled evaluates a compiled JMESPath expression against the JSON document
+// read from r, without decoding the whole document first when possible, see stream.Search.
+// The reader must contain a single JSON document.
+func SearchReaderCompiled(jmespath JMESPath, r io.Reader, opts ...interpreter.Option) (any, error) {
+	jp, err := compiled(jmespath)
+	if err != nil {
+		return nil, err
+	}
+	opts = jp.withFunctionCaller(opts)
+	decoder := json.NewDecoder(r)
+	if jp.useNumber {
+		decoder.UseNumber()
+	}
+	result, err := stream.Search(evaluationContext(opts), jp.node, decoder, opts...)
+	if err != nil {
+		return nil, jp.withExpression(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		if err == nil {
+			err = ErrTrailingData
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// Locate evaluates a JMESPath expression against input data and returns the values it
+// selects with their paths, see LocateCompiled.
+func Locate(expression string, data any, opts ...interpreter.Option) ([]locate.Match, error) {
+	compiled, err := compileCached(expression, opts)
+	if err != nil {
+		return nil, err
+	}
+	return LocateCompiled(compiled, data, opts...)
+}
+
+// LocateContext is like Locate but aborts the evaluation when the context is done,
+// see interpreter.WithContext.
+func LocateContext(ctx context.Context, expression string, data any, opts ...interpreter.Option) ([]locate.Match, error) {
+	return Locate(expression, data, append(opts[:len(opts):len(opts)], interpreter.WithContext(ctx))...)
+}
+
+// LocateCompiled evaluates a compiled JMESPath expression against input data and returns
+// the values it selects with their paths in the data, see locate.Search. The expression
+// must be made of paths like `spec.containers[?privileged].name`.
+func LocateCompiled(jmespath JMESPath, data any, opts ...interpreter.Option) ([]locate.Match, error) {
+	jp, err := compiled(jmespath)
+	if err != nil {
+		return nil, err
+	}
+	opts = jp.withFunctionCaller(opts)
+	matches, err := locate.Search(evaluationContext(opts), jp.node, data, opts...)
+	if err != nil {
+		return nil, jp.withExpression(err)
+	}
+	return matches, nil
+}
+
+// Set sets the values addressed by a JMESPath expression in data, see SetCompiled.
+func Set(data any, expression string, value any, opts ...mutate.Option) (any, error) {
+	compiled, err := compileCached(expression, interpreterOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return SetCompiled(data, compiled, value, opts...)
+}
+
+// SetCompiled sets the values addressed by a compiled JMESPath expression in data to value
+// and returns the document, which is modified in place unless mutate.WithCopyOnWrite is
+// given, see mutate.Set.
+func SetCompiled(data any, jmespath JMESPath, value any, opts ...mutate.Option) (any, error) {
+	jp, err := compiled(jmespath)
+	if err != nil {
+		return nil, err
+	}
+	result, err := mutate.Set(data, jp.node, value, jp.withMutationFunctionCaller(opts)...)
+	return result, jp.withExpression(err)
+}
+
+// Update updates the values addressed by a JMESPath expression in data, see UpdateCompiled.
+func Update(data any, expression string, update func(any) any, opts ...mutate.Option) (any, error) {
+	compiled, err := compileCached(expression, interpreterOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return UpdateCompiled(data, compiled, update, opts...)
+}
+
+// UpdateCompiled replaces the values addressed by a compiled JMESPath expression in data
+// with the result of a function and returns the document, which is modified in place
+// unless mutate.WithCopyOnWrite is given, see mutate.Update.
+func UpdateCompiled(data any, jmespath JMESPath, update func(any) any, opts ...mutate.Option) (any, error) {
+	jp, err := compiled(jmespath)
+	if err != nil {
+		return nil, err
+	}
+	result, err := mutate.Update(data, jp.node, update, jp.withMutationFunctionCaller(opts)...)
+	return result, jp.withExpression(err)
+}
+
+// Delete removes the values addressed by a JMESPath expression from data, see DeleteCompiled.
+func Delete(data any, expression string, opts ...mutate.Option) (any, error) {
+	compiled, err := compileCached(expression, interpreterOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return DeleteCompiled(data, compiled, opts...)
+}
+
+// DeleteCompiled removes the values addressed by a compiled JMESPath expression from data
+// and returns the document, which is modified in place unless mutate.WithCopyOnWrite is
+// given, see mutate.Delete.
+func DeleteCompiled(data any, jmespath JMESPath, opts ...mutate.Option) (any, error) {
+	jp, err := compiled(jmespath)
+	if err != nil {
+		return nil, err
+	}
+	result, err := mutate.Delete(data, jp.node, jp.withMutationFunctionCaller(opts)...)
+	return result, jp.withExpression(err)
+}
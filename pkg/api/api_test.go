@@ -1,11 +1,20 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/jmespath-community/go-jmespath/pkg/binding"
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
 	"github.com/jmespath-community/go-jmespath/pkg/functions"
 	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/locate"
+	"github.com/jmespath-community/go-jmespath/pkg/mutate"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,6 +40,302 @@ func TestValidPrecompiledExpressionSearches(t *testing.T) {
 	assert.Equal("bar", result)
 }
 
+func TestSearchNumbers(t *testing.T) {
+	type item struct {
+		Count int     `json:"count"`
+		Size  uint8   `json:"size"`
+		Price float32 `json:"price"`
+	}
+	structs := map[string]any{
+		"items":  []item{{Count: -1, Size: 2, Price: 1.5}, {Count: 3, Size: 1, Price: 2.5}},
+		"counts": []int{3, 1, 2},
+	}
+	decoder := json.NewDecoder(strings.NewReader(`{
+		"items": [{"count": -1, "size": 2, "price": 1.5}, {"count": 3, "size": 1, "price": 2.5}],
+		"counts": [3, 1, 2]
+	}`))
+	decoder.UseNumber()
+	var numbers any
+	assert.NoError(t, decoder.Decode(&numbers))
+	tests := []struct {
+		expression string
+		want       any
+	}{
+		{"abs(items[0].count)", 1.0},
+		{"sum(counts)", 6.0},
+		{"avg(counts)", 2.0},
+		{"max(counts)", 3.0},
+		{"sort(counts)", []any{1.0, 2.0, 3.0}},
+		{"sort_by(items, &size)[0].count", 3.0},
+		{"max_by(items, &price).count", 3.0},
+		{"min_by(items, &count).size", 2.0},
+		{"items[?count > `0`] | length(@)", 1.0},
+		{"items[0].count == `-1`", true},
+		{"contains(counts, `2`)", true},
+		{"items[1].count * items[1].price", 7.5},
+		{"type(items[0].size)", "number"},
+		{"to_number(items[0].price)", 1.5},
+		{"find_first('abc', 'c', items[1].size)", 2.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			for name, data := range map[string]any{"structs": structs, "json numbers": numbers} {
+				result, err := Search(tt.expression, data)
+				assert.NoError(t, err, name)
+				if num, ok := result.(json.Number); ok {
+					result, _ = num.Float64()
+				}
+				if num, ok := result.(int); ok {
+					result = float64(num)
+				}
+				if num, ok := result.(uint8); ok {
+					result = float64(num)
+				}
+				assert.Equal(t, tt.want, result, name)
+			}
+		})
+	}
+}
+
+func TestSearchTypedCollections(t *testing.T) {
+	data := map[string]any{
+		"ints":    []int{1, 2},
+		"strings": []string{"a"},
+		"counts":  map[string]int{"b": 2, "a": 1},
+		"flags":   map[string]bool{},
+	}
+	tests := []struct {
+		expression string
+		want       any
+	}{
+		{"type(ints)", "array"},
+		{"type(strings)", "array"},
+		{"type(counts)", "object"},
+		{"type(flags)", "object"},
+		{"to_number(ints)", nil},
+		{"to_number(counts)", nil},
+		{"length(ints)", 2.0},
+		{"reverse(strings)", []any{"a"}},
+		{"keys(counts)", []any{"a", "b"}},
+		{"values(counts)", []any{1, 2}},
+		{"length(counts)", 2.0},
+		{"items(counts)", []any{[]any{"a", 1}, []any{"b", 2}}},
+		{"merge(counts, flags)", map[string]any{"a": 1, "b": 2}},
+		{"sort(counts.*)", []any{1.0, 2.0}},
+		{"counts == `{\"a\": 1, \"b\": 2}`", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			result, err := Search(tt.expression, data)
+			assert.NoError(err)
+			assert.Equal(tt.want, result)
+			compiled, err := Compile(tt.expression, WithVirtualMachine())
+			assert.NoError(err)
+			result, err = compiled.Search(data)
+			assert.NoError(err)
+			assert.Equal(tt.want, result)
+		})
+	}
+}
+
+func TestSearchTypedMapKeys(t *testing.T) {
+	assert := assert.New(t)
+	result, err := Search("keys(@)", map[string]int{"b": 2, "a": 1})
+	assert.NoError(err)
+	assert.Equal([]any{"a", "b"}, result)
+}
+
+func TestSearchContext(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]any{"foo": []any{1.0, 2.0, 3.0}}
+	result, err := SearchContext(context.Background(), "foo[*]", data)
+	assert.Nil(err)
+	assert.Equal([]any{1.0, 2.0, 3.0}, result)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = SearchContext(ctx, "foo[*]", data)
+	assert.ErrorIs(err, context.Canceled)
+	assert.Nil(result)
+	precompiled := MustCompile("sort_by(foo, &@)")
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	result, err = precompiled.SearchContext(ctx, data)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Nil(result)
+	result, err = precompiled.Search(data, interpreter.WithContext(ctx))
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Nil(result)
+	result, err = MustCompile("sort_by(foo, &@)", WithVirtualMachine()).Search(data, interpreter.WithContext(ctx))
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Nil(result)
+}
+
+func TestSearchWithVariables(t *testing.T) {
+	data := map[string]any{
+		"items": []any{
+			map[string]any{"name": "a", "owner": "alice"},
+			map[string]any{"name": "b", "owner": "bob"},
+			map[string]any{"name": "c", "owner": "alice"},
+		},
+	}
+	tests := []struct {
+		name       string
+		expression string
+		opts       []interpreter.Option
+		want       any
+		wantErr    bool
+	}{{
+		name:       "variables",
+		expression: "items[?owner == $user].name",
+		opts:       []interpreter.Option{interpreter.WithVariables(map[string]any{"user": "alice"})},
+		want:       []any{"a", "c"},
+	}, {
+		name:       "variables with prefix",
+		expression: "items[?owner == $user].name",
+		opts:       []interpreter.Option{interpreter.WithVariables(map[string]any{"$user": "bob"})},
+		want:       []any{"b"},
+	}, {
+		name:       "variables with and without prefix",
+		expression: "items[?owner == $user].name",
+		opts:       []interpreter.Option{interpreter.WithVariables(map[string]any{"user": "alice", "$user": "bob"})},
+		want:       []any{"b"},
+	}, {
+		name:       "bindings",
+		expression: "items[?owner == $user].name",
+		opts:       []interpreter.Option{interpreter.WithBindings(binding.NewBindings().Register("$user", binding.NewBinding("bob")))},
+		want:       []any{"b"},
+	}, {
+		name:       "shadowed by let",
+		expression: "let $user = 'bob' in items[?owner == $user].name",
+		opts:       []interpreter.Option{interpreter.WithVariables(map[string]any{"user": "alice"})},
+		want:       []any{"b"},
+	}, {
+		name:       "undefined",
+		expression: "items[?owner == $user].name",
+		wantErr:    true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			got, err := Search(tt.expression, data, tt.opts...)
+			assert.Equal(tt.wantErr, err != nil)
+			assert.Equal(tt.want, got)
+		})
+	}
+}
+
+func TestSearchErrorKinds(t *testing.T) {
+	tests := []struct {
+		expression   string
+		data         any
+		opts         []interpreter.Option
+		wantKind     jperror.Kind
+		wantFunction string
+		wantArgument int
+	}{{
+		expression:   "foo[",
+		wantKind:     jperror.Syntax,
+		wantArgument: -1,
+	}, {
+		expression:   "`{foo: bar}`",
+		wantKind:     jperror.Syntax,
+		wantArgument: -1,
+	}, {
+		expression:   "lenght(@)",
+		wantKind:     jperror.UnknownFunction,
+		wantFunction: "lenght",
+		wantArgument: -1,
+	}, {
+		expression:   "abs(@, @)",
+		data:         1.0,
+		wantKind:     jperror.InvalidArity,
+		wantFunction: "abs",
+		wantArgument: -1,
+	}, {
+		expression:   "starts_with('foo', @)",
+		data:         1.0,
+		wantKind:     jperror.InvalidType,
+		wantFunction: "starts_with",
+		wantArgument: 1,
+	}, {
+		expression:   "sort_by(@, &foo)",
+		data:         []any{map[string]any{"foo": 1.0}, map[string]any{"foo": "bar"}},
+		wantKind:     jperror.InvalidType,
+		wantFunction: "sort_by",
+		wantArgument: 1,
+	}, {
+		expression:   "pad_left('foo', `1.5`)",
+		wantKind:     jperror.InvalidValue,
+		wantFunction: "pad_left",
+		wantArgument: 1,
+	}, {
+		expression:   "@[::0]",
+		data:         []any{1.0},
+		wantKind:     jperror.InvalidValue,
+		wantArgument: -1,
+	}, {
+		expression:   "$foo",
+		wantKind:     jperror.UndefinedVariable,
+		wantArgument: -1,
+	}, {
+		expression:   "[*][*]",
+		data:         []any{[]any{1.0, 2.0}},
+		opts:         []interpreter.Option{interpreter.WithMaxResultElements(2)},
+		wantKind:     jperror.BudgetExceeded,
+		wantArgument: -1,
+	}, {
+		expression:   "[[[@]]]",
+		opts:         []interpreter.Option{interpreter.WithMaxDepth(2)},
+		wantKind:     jperror.BudgetExceeded,
+		wantArgument: -1,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			_, err := Search(tt.expression, tt.data, tt.opts...)
+			var jpErr *jperror.Error
+			if assert.True(errors.As(err, &jpErr)) {
+				assert.Equal(tt.wantKind, jpErr.Kind)
+				assert.Equal(tt.wantFunction, jpErr.Function)
+				assert.Equal(tt.wantArgument, jpErr.Argument)
+			}
+		})
+	}
+}
+
+func TestSearchErrorLocation(t *testing.T) {
+	tests := []struct {
+		expression string
+		data       any
+		want       string
+	}{{
+		expression: "items[?abs(name) > `1`]",
+		data:       map[string]any{"items": []any{map[string]any{"name": "x"}}},
+		want:       "items[?abs(name) > `1`]\n           ^^^^",
+	}, {
+		expression: "foo | lenght(@)",
+		want:       "foo | lenght(@)\n      ^^^^^^^^^",
+	}, {
+		expression: "let $a = b in [$a, $c]",
+		want:       "let $a = b in [$a, $c]\n                   ^^",
+	}, {
+		expression: "foo[",
+		want:       "foo[\n    ^",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			_, err := Search(tt.expression, tt.data)
+			var jpErr *jperror.Error
+			if assert.True(errors.As(err, &jpErr)) {
+				assert.True(jpErr.HasLocation())
+				assert.Equal(tt.want, jpErr.HighlightLocation())
+			}
+		})
+	}
+}
+
 func TestInvalidPrecompileErrors(t *testing.T) {
 	assert := assert.New(t)
 	_, err := Compile("not a valid expression")
@@ -45,6 +350,378 @@ func TestInvalidMustCompilePanics(t *testing.T) {
 	MustCompile("not a valid expression")
 }
 
+func TestCompileWithOptimization(t *testing.T) {
+	assert := assert.New(t)
+	compiled, err := Compile("@.items[?size > `1` + `1`] | length(@)", WithOptimization(), WithPrecision(false))
+	assert.NoError(err)
+	assert.Equal("items[?size > `2`] | length(@)", parsing.Format(compiled.(jmesPath).node))
+	data := map[string]any{"items": []any{map[string]any{"size": 1.0}, map[string]any{"size": 3.0}}}
+	result, err := compiled.Search(data)
+	assert.NoError(err)
+	assert.Equal(1.0, result)
+}
+
+func TestCompileWithOptimizationKeepsPrecision(t *testing.T) {
+	assert := assert.New(t)
+	compiled, err := Compile("`0.1` + `0.2`", WithOptimization())
+	assert.NoError(err)
+	assert.Equal("`0.1` + `0.2`", parsing.Format(compiled.(jmesPath).node))
+	result, err := compiled.Search(nil, interpreter.WithArbitraryPrecision())
+	assert.NoError(err)
+	assert.Equal(json.Number("0.3"), result)
+	result, err = compiled.Search(nil)
+	assert.NoError(err)
+	assert.Equal(0.30000000000000004, result)
+	compiled, err = Compile("`0.1` + `0.2`", WithOptimization(), WithUseNumber(), WithPrecision(true))
+	assert.NoError(err)
+	assert.Equal("`0.3`", parsing.Format(compiled.(jmesPath).node))
+	result, err = compiled.Search(nil, interpreter.WithArbitraryPrecision())
+	assert.NoError(err)
+	assert.Equal(json.Number("0.3"), result)
+}
+
+func TestCompileWithUseNumber(t *testing.T) {
+	assert := assert.New(t)
+	decoder := json.NewDecoder(strings.NewReader(`{"id": 9007199254740993, "total": 0.1}`))
+	decoder.UseNumber()
+	var data any
+	assert.NoError(decoder.Decode(&data))
+	for _, opts := range [][]CompileOption{{WithUseNumber()}, {WithUseNumber(), WithOptimization()}, {WithUseNumber(), WithVirtualMachine()}} {
+		compiled, err := Compile("[id + `1`, total + `0.2`, `0.1` + `0.2`]", opts...)
+		assert.NoError(err)
+		result, err := compiled.Search(data, interpreter.WithArbitraryPrecision())
+		assert.NoError(err)
+		assert.Equal([]any{json.Number("9007199254740994"), json.Number("0.3"), json.Number("0.3")}, result)
+	}
+	compiled, err := Compile("`9007199254740993`", WithUseNumber())
+	assert.NoError(err)
+	result, err := compiled.Search(nil)
+	assert.NoError(err)
+	assert.Equal(json.Number("9007199254740993"), result)
+}
+
+func TestSearchReader(t *testing.T) {
+	tests := []struct {
+		expression string
+		document   string
+		want       any
+		wantErr    string
+	}{{
+		expression: "records[?status=='error'].id",
+		document:   `{"records": [{"id": 1, "status": "ok"}, {"id": 2, "status": "error"}]}`,
+		want:       []any{2.0},
+	}, {
+		expression: "sort_by(records, &id)[-1].id",
+		document:   `{"records": [{"id": 2}, {"id": 1}]}`,
+		want:       2.0,
+	}, {
+		expression: "records[*].abs(id)",
+		document:   `{"records": [{"id": "a"}]}`,
+		wantErr:    "invalid type",
+	}, {
+		expression: "records",
+		document:   `{"records": [}`,
+		wantErr:    "invalid character",
+	}, {
+		expression: "records",
+		document:   `{"records": []} {}`,
+		wantErr:    "after top-level value",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			result, err := SearchReader(tt.expression, strings.NewReader(tt.document))
+			if tt.wantErr != "" {
+				assert.ErrorContains(err, tt.wantErr)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.want, result)
+		})
+	}
+}
+
+func TestSearchReaderTrailingData(t *testing.T) {
+	assert := assert.New(t)
+	_, err := SearchReader("@", strings.NewReader(`{} []`))
+	assert.ErrorIs(err, ErrTrailingData)
+	_, err = SearchReader("@", strings.NewReader(`{} ]`))
+	var syntaxErr *json.SyntaxError
+	assert.ErrorAs(err, &syntaxErr)
+}
+
+func TestSearchReaderErrorExpression(t *testing.T) {
+	assert := assert.New(t)
+	_, err := SearchReader("records[*].abs(id)", strings.NewReader(`{"records": [{"id": "a"}]}`))
+	var jpErr *jperror.Error
+	assert.True(errors.As(err, &jpErr))
+	assert.Equal("records[*].abs(id)", jpErr.Expression)
+}
+
+func TestCompiledSearchReaderUseNumber(t *testing.T) {
+	assert := assert.New(t)
+	compiled, err := Compile("records[*].id", WithUseNumber())
+	assert.NoError(err)
+	result, err := SearchReaderCompiled(compiled, strings.NewReader(`{"records": [{"id": 9007199254740993}]}`))
+	assert.NoError(err)
+	assert.Equal([]any{json.Number("9007199254740993")}, result)
+}
+
+func TestSearchReaderWithContext(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := SearchReaderCompiled(MustCompile("records[*].id"), strings.NewReader(`{"records": [{"id": 1}]}`), interpreter.WithContext(ctx))
+	assert.ErrorIs(err, context.Canceled)
+	_, err = SearchReaderContext(ctx, "records[*].id", strings.NewReader(`{"records": [{"id": 1}]}`))
+	assert.ErrorIs(err, context.Canceled)
+}
+
+// searcher is a JMESPath that was not returned by Compile.
+type searcher struct {
+	JMESPath
+}
+
+func TestCompiledFunctionsRequireCompile(t *testing.T) {
+	assert := assert.New(t)
+	var jpErr *jperror.Error
+	_, err := SearchReaderCompiled(searcher{}, strings.NewReader("{}"))
+	assert.True(errors.As(err, &jpErr))
+	assert.Equal(jperror.Unsupported, jpErr.Kind)
+	_, err = LocateCompiled(searcher{}, nil)
+	assert.True(errors.As(err, &jpErr))
+	assert.Equal(jperror.Unsupported, jpErr.Kind)
+	_, err = SetCompiled(nil, searcher{}, 1.0)
+	assert.True(errors.As(err, &jpErr))
+	_, err = UpdateCompiled(nil, searcher{}, func(v any) any { return v })
+	assert.True(errors.As(err, &jpErr))
+	_, err = DeleteCompiled(nil, searcher{})
+	assert.True(errors.As(err, &jpErr))
+}
+
+func TestLocateWithContext(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	data := map[string]any{"records": []any{}}
+	_, err := LocateCompiled(MustCompile("records[*].id"), data, interpreter.WithContext(ctx))
+	assert.ErrorIs(err, context.Canceled)
+	_, err = LocateContext(ctx, "records[*].id", data)
+	assert.ErrorIs(err, context.Canceled)
+}
+
+func TestLocate(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]any{"records": []any{
+		map[string]any{"id": 1.0, "status": "ok"},
+		map[string]any{"id": 2.0, "status": "error"},
+	}}
+	matches, err := Locate("records[?status=='error'].id", data)
+	assert.NoError(err)
+	assert.Equal([]locate.Match{{Path: locate.Path{"records", 1, "id"}, Value: 2.0}}, matches)
+	assert.Equal("/records/1/id", matches[0].Path.Pointer())
+	compiled, err := Compile("records[*].to_string(id)", WithOptimization())
+	assert.NoError(err)
+	_, err = LocateCompiled(compiled, data)
+	var jpErr *jperror.Error
+	assert.True(errors.As(err, &jpErr))
+	assert.Equal(jperror.Unsupported, jpErr.Kind)
+	assert.Equal("records[*].to_string(id)", jpErr.Expression)
+}
+
+func TestMutate(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]any{"records": []any{
+		map[string]any{"id": 1.0, "status": "ok"},
+		map[string]any{"id": 2.0, "status": "error"},
+	}}
+	result, err := Set(data, "records[?status=='error'].status", "retry")
+	assert.NoError(err)
+	assert.Equal("retry", data["records"].([]any)[1].(map[string]any)["status"])
+	result, err = Update(result, "records[*].id", func(v any) any { return v.(float64) + 10 })
+	assert.NoError(err)
+	result, err = Delete(result, "records[0]")
+	assert.NoError(err)
+	assert.Equal(map[string]any{"records": []any{map[string]any{"id": 12.0, "status": "retry"}}}, result)
+	_, err = Delete(result, "records[*].to_string(id)")
+	var jpErr *jperror.Error
+	assert.True(errors.As(err, &jpErr))
+	assert.Equal(jperror.Unsupported, jpErr.Kind)
+	assert.Equal("records[*].to_string(id)", jpErr.Expression)
+	variables := mutate.WithInterpreterOptions(interpreter.WithVariables(map[string]any{"id": 2.0}))
+	result, err = Set(map[string]any{"records": []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}}}, "records[?id == $id].status", "ok", variables)
+	assert.NoError(err)
+	assert.Equal(map[string]any{"records": []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0, "status": "ok"}}}, result)
+}
+
+func TestMutateCompiled(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]any{"records": []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}}}
+	ids := MustCompile("records[*].id")
+	result, err := SetCompiled(data, ids, 0.0, mutate.WithCopyOnWrite())
+	assert.NoError(err)
+	assert.Equal(map[string]any{"records": []any{map[string]any{"id": 0.0}, map[string]any{"id": 0.0}}}, result)
+	assert.Equal(1.0, data["records"].([]any)[0].(map[string]any)["id"])
+	result, err = UpdateCompiled(data, ids, func(v any) any { return v.(float64) * 2 })
+	assert.NoError(err)
+	assert.Equal(map[string]any{"records": []any{map[string]any{"id": 2.0}, map[string]any{"id": 4.0}}}, result)
+	result, err = DeleteCompiled(result, MustCompile("records[0]", WithVirtualMachine()))
+	assert.NoError(err)
+	assert.Equal(map[string]any{"records": []any{map[string]any{"id": 4.0}}}, result)
+	positive := func([]any) (any, error) { return true, nil }
+	compiled, err := Compile("records[?positive(id)].id", WithFunctions(functions.FunctionEntry{
+		Name:      "positive",
+		Arguments: []functions.ArgSpec{{Types: []functions.JpType{functions.JpNumber}}},
+		Handler:   positive,
+	}))
+	assert.NoError(err)
+	result, err = DeleteCompiled(result, compiled)
+	assert.NoError(err)
+	assert.Equal(map[string]any{"records": []any{map[string]any{}}}, result)
+}
+
+func TestCompileWithVirtualMachine(t *testing.T) {
+	tests := []struct {
+		expression string
+		data       any
+		want       any
+		wantErr    string
+	}{{
+		expression: "items[?size > `1`].name | [0]",
+		data:       map[string]any{"items": []any{map[string]any{"size": 1.0, "name": "a"}, map[string]any{"size": 3.0, "name": "b"}}},
+		want:       "b",
+	}, {
+		expression: "let $a = `1` in map(&[@, $a], @)",
+		data:       []any{1.0},
+		want:       []any{[]any{1.0, 1.0}},
+	}, {
+		expression: "items[?abs(name) > `1`]",
+		data:       map[string]any{"items": []any{map[string]any{"name": "x"}}},
+		wantErr:    "items[?abs(name) > `1`]\n           ^^^^",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			compiled, err := Compile(tt.expression, WithVirtualMachine())
+			assert.NoError(err)
+			assert.NotNil(compiled.(jmesPath).program)
+			result, err := compiled.Search(tt.data)
+			if tt.wantErr != "" {
+				var jpErr *jperror.Error
+				if assert.True(errors.As(err, &jpErr)) {
+					assert.Equal(tt.wantErr, jpErr.HighlightLocation())
+				}
+			} else {
+				assert.NoError(err)
+				assert.Equal(tt.want, result)
+			}
+		})
+	}
+}
+
+func TestCompileWithFunctions(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantKind   jperror.Kind
+		want       string
+	}{{
+		expression: "length(@)",
+	}, {
+		expression: "merge(a, b, c) | not_null(a, b)",
+	}, {
+		expression: "lenght(@)",
+		wantKind:   jperror.UnknownFunction,
+		want:       "lenght(@)\n^^^^^^^^^",
+	}, {
+		expression: "foo | length()",
+		wantKind:   jperror.InvalidArity,
+		want:       "foo | length()\n      ^^^^^^^^",
+	}, {
+		expression: "abs(a, b)",
+		wantKind:   jperror.InvalidArity,
+		want:       "abs(a, b)\n^^^^^^^^^",
+	}, {
+		expression: "merge()",
+		wantKind:   jperror.InvalidArity,
+		want:       "merge()\n^^^^^^^",
+	}, {
+		expression: "sort_by(@, &to_strin(@))",
+		wantKind:   jperror.UnknownFunction,
+		want:       "sort_by(@, &to_strin(@))\n            ^^^^^^^^^^^",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			compiled, err := CompileWithFunctions(tt.expression, functions.GetDefaultFunctions()...)
+			if tt.wantKind == "" {
+				assert.NoError(err)
+				assert.NotNil(compiled)
+				return
+			}
+			assert.Nil(compiled)
+			var jpErr *jperror.Error
+			if assert.True(errors.As(err, &jpErr)) {
+				assert.Equal(tt.wantKind, jpErr.Kind)
+				assert.Equal(tt.want, jpErr.HighlightLocation())
+			}
+		})
+	}
+}
+
+func TestCompileWithFunctionsUsesFunctions(t *testing.T) {
+	assert := assert.New(t)
+	echo := functions.FunctionEntry{
+		Name:      "echo",
+		Arguments: []functions.ArgSpec{{Types: []functions.JpType{functions.JpAny}}},
+		Handler:   jpfEcho,
+	}
+	compiled, err := CompileWithFunctions("echo(foo)", echo)
+	assert.NoError(err)
+	result, err := compiled.Search(map[string]any{"foo": "bar"})
+	assert.NoError(err)
+	assert.Equal("bar", result)
+	_, err = CompileWithFunctions("length(foo)", echo)
+	assert.EqualError(err, "unknown function: length")
+	_, err = CompileWithFunctions("foo[")
+	var syntaxError parsing.SyntaxError
+	assert.True(errors.As(err, &syntaxError))
+}
+
+func TestCompileWithFunctionsOption(t *testing.T) {
+	echo := functions.FunctionEntry{
+		Name:      "echo",
+		Arguments: []functions.ArgSpec{{Types: []functions.JpType{functions.JpAny}}},
+		Handler:   jpfEcho,
+	}
+	tests := []struct {
+		name string
+		opts []CompileOption
+	}{
+		{"default", nil},
+		{"optimization", []CompileOption{WithOptimization()}},
+		{"virtual machine", []CompileOption{WithVirtualMachine()}},
+		{"use number", []CompileOption{WithUseNumber()}},
+		{"all", []CompileOption{WithOptimization(), WithVirtualMachine(), WithUseNumber()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			opts := append([]CompileOption{WithFunctions(echo)}, tt.opts...)
+			compiled, err := Compile("echo(foo) || echo(`1`)", opts...)
+			assert.NoError(err)
+			result, err := compiled.Search(map[string]any{"foo": "bar"})
+			assert.NoError(err)
+			assert.Equal("bar", result)
+			_, err = Compile("foo | length(@)", opts...)
+			var jpErr *jperror.Error
+			if assert.True(errors.As(err, &jpErr)) {
+				assert.Equal(jperror.UnknownFunction, jpErr.Kind)
+				assert.Equal("foo | length(@)\n      ^^^^^^^^^", jpErr.HighlightLocation())
+			}
+		})
+	}
+}
+
 func jpfEcho(arguments []any) (any, error) {
 	return arguments[0], nil
 }
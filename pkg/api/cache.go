@@ -0,0 +1,139 @@
+package api
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheSize is the number of compiled expressions kept by Search and SearchContext.
+const DefaultCacheSize = 256
+
+// CacheStats describes the usage of the compiled expressions cache.
+type CacheStats struct {
+	// Hits is the number of searches that reused a compiled expression.
+	Hits uint64
+	// Misses is the number of searches that compiled their expression.
+	Misses uint64
+	// Evictions is the number of compiled expressions dropped to make room for others.
+	Evictions uint64
+	// Size is the number of compiled expressions in the cache.
+	Size int
+	// Capacity is the maximum number of compiled expressions in the cache.
+	Capacity int
+}
+
+var searchCache = newExpressionCache(DefaultCacheSize)
+
+// SetCacheSize sets the number of compiled expressions kept by Search and SearchContext,
+// the least recently used ones are evicted first. A size of zero or less turns the cache off.
+func SetCacheSize(size int) {
+	searchCache.resize(size)
+}
+
+// GetCacheStats returns the statistics of the compiled expressions cache used by
+// Search and SearchContext.
+func GetCacheStats() CacheStats {
+	return searchCache.stats()
+}
+
+// expressionCache is a concurrency safe LRU cache of compiled expressions.
+type expressionCache struct {
+	mutex    sync.Mutex
+	capacity int
+	// entries holds the cache entries, most recently used first.
+	entries   *list.List
+	elements  map[cacheKey]*list.Element
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// cacheKey identifies a compiled expression, expressions compiled for
+// arbitrary precision keep the exact numbers of their literals.
+type cacheKey struct {
+	expression string
+	useNumber  bool
+}
+
+type cacheEntry struct {
+	key      cacheKey
+	compiled JMESPath
+}
+
+func newExpressionCache(capacity int) *expressionCache {
+	return &expressionCache{
+		capacity: capacity,
+		entries:  list.New(),
+		elements: map[cacheKey]*list.Element{},
+	}
+}
+
+// compile returns the compiled expression from the cache, compiling and adding it
+// if it is missing. Literal numbers are decoded as json.Number when useNumber is
+// true, see WithUseNumber. Expressions that fail to compile are not cached.
+func (c *expressionCache) compile(expression string, useNumber bool) (JMESPath, error) {
+	key := cacheKey{expression: expression, useNumber: useNumber}
+	c.mutex.Lock()
+	if element, ok := c.elements[key]; ok {
+		c.hits++
+		c.entries.MoveToFront(element)
+		c.mutex.Unlock()
+		return element.Value.(cacheEntry).compiled, nil
+	}
+	c.misses++
+	c.mutex.Unlock()
+	var opts []CompileOption
+	if useNumber {
+		opts = append(opts, WithUseNumber())
+	}
+	compiled, err := Compile(expression, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.capacity <= 0 {
+		return compiled, nil
+	}
+	// another goroutine may have compiled the same expression meanwhile
+	if element, ok := c.elements[key]; ok {
+		c.entries.MoveToFront(element)
+		return compiled, nil
+	}
+	c.elements[key] = c.entries.PushFront(cacheEntry{key: key, compiled: compiled})
+	c.evict()
+	return compiled, nil
+}
+
+func (c *expressionCache) resize(capacity int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.capacity = capacity
+	c.evict()
+}
+
+// evict drops the least recently used entries until the cache fits its capacity.
+func (c *expressionCache) evict() {
+	for c.entries.Len() > 0 && c.entries.Len() > c.capacity {
+		oldest := c.entries.Back()
+		c.entries.Remove(oldest)
+		delete(c.elements, oldest.Value.(cacheEntry).key)
+		c.evictions++
+	}
+}
+
+func (c *expressionCache) stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	capacity := c.capacity
+	if capacity < 0 {
+		capacity = 0
+	}
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Size:      c.entries.Len(),
+		Capacity:  capacity,
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpressionCache(t *testing.T) {
+	assert := assert.New(t)
+	cache := newExpressionCache(2)
+	first, err := cache.compile("foo", false)
+	assert.NoError(err)
+	again, err := cache.compile("foo", false)
+	assert.NoError(err)
+	assert.Equal(first, again)
+	_, err = cache.compile("bar", false)
+	assert.NoError(err)
+	// foo is the most recently used, bar is evicted
+	_, err = cache.compile("foo", false)
+	assert.NoError(err)
+	_, err = cache.compile("baz", false)
+	assert.NoError(err)
+	assert.Contains(cache.elements, cacheKey{expression: "foo"})
+	assert.NotContains(cache.elements, cacheKey{expression: "bar"})
+	assert.Equal(CacheStats{Hits: 2, Misses: 3, Evictions: 1, Size: 2, Capacity: 2}, cache.stats())
+}
+
+func TestExpressionCacheDoesNotCacheErrors(t *testing.T) {
+	assert := assert.New(t)
+	cache := newExpressionCache(2)
+	for i := 0; i < 2; i++ {
+		_, err := cache.compile("foo[", false)
+		assert.Error(err)
+	}
+	assert.Equal(CacheStats{Misses: 2, Capacity: 2}, cache.stats())
+}
+
+func TestExpressionCacheResize(t *testing.T) {
+	assert := assert.New(t)
+	cache := newExpressionCache(3)
+	for _, expression := range []string{"a", "b", "c"} {
+		_, err := cache.compile(expression, false)
+		assert.NoError(err)
+	}
+	cache.resize(1)
+	assert.Contains(cache.elements, cacheKey{expression: "c"})
+	assert.Equal(CacheStats{Misses: 3, Evictions: 2, Size: 1, Capacity: 1}, cache.stats())
+	cache.resize(0)
+	_, err := cache.compile("a", false)
+	assert.NoError(err)
+	_, err = cache.compile("a", false)
+	assert.NoError(err)
+	assert.Equal(CacheStats{Misses: 5, Evictions: 3, Capacity: 0}, cache.stats())
+}
+
+func TestExpressionCacheConcurrency(t *testing.T) {
+	cache := newExpressionCache(8)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				compiled, err := cache.compile(fmt.Sprintf("foo[%d]", (i+j)%16), false)
+				assert.NoError(t, err)
+				assert.NotNil(t, compiled)
+			}
+		}(i)
+	}
+	wg.Wait()
+	stats := cache.stats()
+	assert.Equal(t, uint64(800), stats.Hits+stats.Misses)
+	assert.Equal(t, 8, stats.Size)
+}
+
+func TestSetCacheSize(t *testing.T) {
+	assert := assert.New(t)
+	defer SetCacheSize(DefaultCacheSize)
+	SetCacheSize(0)
+	before := GetCacheStats()
+	for i := 0; i < 2; i++ {
+		result, err := Search("foo", map[string]any{"foo": "bar"})
+		assert.NoError(err)
+		assert.Equal("bar", result)
+	}
+	after := GetCacheStats()
+	assert.Equal(before.Hits, after.Hits)
+	assert.Equal(before.Misses+2, after.Misses)
+	assert.Equal(0, after.Size)
+	SetCacheSize(DefaultCacheSize)
+	for i := 0; i < 2; i++ {
+		_, err := Search("foo", map[string]any{"foo": "bar"})
+		assert.NoError(err)
+	}
+	assert.Equal(after.Hits+1, GetCacheStats().Hits)
+}
+
+func TestSearchDoesNotModifyCachedLiterals(t *testing.T) {
+	assert := assert.New(t)
+	// the AST of the expression is cached, literals are shared by searches
+	for i := 0; i < 2; i++ {
+		result, err := Search("`[3, 1, 2]` | [@, sort_by(@, &@)]", nil)
+		assert.NoError(err)
+		assert.Equal([]any{[]any{3.0, 1.0, 2.0}, []any{1.0, 2.0, 3.0}}, result)
+	}
+	data := []any{3.0, 1.0, 2.0}
+	_, err := Search("sort_by(@, &@)", data)
+	assert.NoError(err)
+	assert.Equal([]any{3.0, 1.0, 2.0}, data)
+}
+
+func TestSearchArbitraryPrecisionLiterals(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]any{"n": json.Number("9007199254740993")}
+	// the same expression is cached once for each precision
+	for i := 0; i < 2; i++ {
+		result, err := Search("n == `9007199254740993`", data, interpreter.WithArbitraryPrecision())
+		assert.NoError(err)
+		assert.Equal(true, result)
+		result, err = Search("n == `9007199254740992`", data, interpreter.WithArbitraryPrecision())
+		assert.NoError(err)
+		assert.Equal(false, result)
+	}
+	result, err := Search("`9007199254740993` + `0`", nil, interpreter.WithArbitraryPrecision())
+	assert.NoError(err)
+	assert.Equal(json.Number("9007199254740993"), result)
+}
@@ -0,0 +1,68 @@
+package api
+
+import "github.com/jmespath-community/go-jmespath/pkg/functions"
+
+type CompileOption func(CompileOptions) CompileOptions
+
+type CompileOptions struct {
+	Optimize       bool
+	VirtualMachine bool
+	UseNumber      bool
+	Functions      []functions.FunctionEntry
+	// Precision is set when the precision of the searches is declared, see WithPrecision.
+	Precision bool
+	// ArbitraryPrecision is set when searches use interpreter.WithArbitraryPrecision.
+	ArbitraryPrecision bool
+}
+
+// WithOptimization rewrites the expression into an equivalent one that is cheaper
+// to evaluate, see optimizer.Optimize. Function calls are assumed to use the
+// default functions. Operations producing numbers are kept unless WithPrecision is given.
+func WithOptimization() CompileOption {
+	return func(o CompileOptions) CompileOptions {
+		o.Optimize = true
+		return o
+	}
+}
+
+// WithPrecision declares whether the compiled expression is searched with
+// interpreter.WithArbitraryPrecision. Combined with WithOptimization, operations
+// on literals producing numbers are only computed once when the precision is
+// declared, searches with another precision then get numbers of the declared one.
+func WithPrecision(arbitrary bool) CompileOption {
+	return func(o CompileOptions) CompileOptions {
+		o.Precision = true
+		o.ArbitraryPrecision = arbitrary
+		return o
+	}
+}
+
+// WithVirtualMachine compiles the expression into a program evaluated by the
+// virtual machine instead of walking the AST, see interpreter.CompileProgram.
+func WithVirtualMachine() CompileOption {
+	return func(o CompileOptions) CompileOptions {
+		o.VirtualMachine = true
+		return o
+	}
+}
+
+// WithUseNumber decodes numbers in JSON literals as json.Number instead of float64,
+// so that they keep their exact value when searching with interpreter.WithArbitraryPrecision.
+func WithUseNumber() CompileOption {
+	return func(o CompileOptions) CompileOptions {
+		o.UseNumber = true
+		return o
+	}
+}
+
+// WithFunctions checks, before any data is seen, that the expression only calls the
+// given functions and calls them with a valid number of arguments. The compiled
+// expression evaluates function calls with the given functions unless a function
+// caller is passed as an option. Combined with WithOptimization, calls to functions
+// named like default functions are still assumed to use the default implementations.
+func WithFunctions(funcs ...functions.FunctionEntry) CompileOption {
+	return func(o CompileOptions) CompileOptions {
+		o.Functions = append([]functions.FunctionEntry{}, funcs...)
+		return o
+	}
+}
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
+	"github.com/jmespath-community/go-jmespath/pkg/util"
+)
+
+// ConversionError is returned when the result of a search cannot be converted
+// to the requested Go type.
+type ConversionError struct {
+	// Path locates the value that could not be converted in the result,
+	// like `[0].name`. It is empty when the result itself could not be converted.
+	Path string
+	// Value describes the value that could not be converted, like "string" or "number 1.5".
+	Value string
+	// Type is the Go type the value could not be converted to.
+	Type reflect.Type
+}
+
+func (e *ConversionError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("cannot convert %s to %s", e.Value, e.Type)
+	}
+	return fmt.Sprintf("cannot convert %s to %s at %s", e.Value, e.Type, e.Path)
+}
+
+// CompiledAs is a compiled JMESPath whose results are converted to T, see SearchAs.
+// A CompiledAs is safe for concurrent use by multiple goroutines.
+type CompiledAs[T any] struct {
+	jmespath JMESPath
+}
+
+// NewCompiledAs wraps a compiled JMESPath so that its results are converted to T.
+func NewCompiledAs[T any](jmespath JMESPath) CompiledAs[T] {
+	return CompiledAs[T]{jmespath: jmespath}
+}
+
+// CompileAs is like Compile but the results of the returned expression are converted to T.
+func CompileAs[T any](expression string, opts ...CompileOption) (CompiledAs[T], error) {
+	jmespath, err := Compile(expression, opts...)
+	if err != nil {
+		return CompiledAs[T]{}, err
+	}
+	return NewCompiledAs[T](jmespath), nil
+}
+
+// MustCompileAs is like CompileAs but panics if the expression cannot be parsed.
+func MustCompileAs[T any](expression string, opts ...CompileOption) CompiledAs[T] {
+	return NewCompiledAs[T](MustCompile(expression, opts...))
+}
+
+// Search evaluates the expression against input data and returns the result converted to T.
+func (c CompiledAs[T]) Search(data any, opts ...interpreter.Option) (T, error) {
+	return c.SearchContext(context.Background(), data, opts...)
+}
+
+// SearchContext is like Search but aborts the evaluation when the context is done.
+func (c CompiledAs[T]) SearchContext(ctx context.Context, data any, opts ...interpreter.Option) (T, error) {
+	var result T
+	value, err := c.jmespath.SearchContext(ctx, data, opts...)
+	if err != nil {
+		return result, err
+	}
+	err = convert(value, &result)
+	return result, err
+}
+
+// SearchAs evaluates a JMESPath expression against input data and converts the result to T.
+// Conversions follow the rules of encoding/json: null converts to the zero value, numbers
+// convert to any Go number type that can represent them exactly, arrays to slices and
+// arrays, and objects to maps with string keys or to structs, whose fields are matched
+// by their json tags or names. A *ConversionError is returned when the result does not
+// match T.
+func SearchAs[T any](expression string, data any, opts ...interpreter.Option) (T, error) {
+	return SearchContextAs[T](context.Background(), expression, data, opts...)
+}
+
+// SearchContextAs is like SearchAs but aborts the evaluation when the context is done.
+func SearchContextAs[T any](ctx context.Context, expression string, data any, opts ...interpreter.Option) (T, error) {
+	var result T
+	value, err := SearchContext(ctx, expression, data, opts...)
+	if err != nil {
+		return result, err
+	}
+	err = convert(value, &result)
+	return result, err
+}
+
+var (
+	jsonNumberType      = reflect.TypeOf(json.Number(""))
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// convert converts a search result to the value pointed to by target.
+func convert(value any, target any) error {
+	return convertValue(value, reflect.ValueOf(target).Elem(), "")
+}
+
+func convertValue(value any, target reflect.Value, path string) error {
+	if value == nil {
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+	t := target.Type()
+	if reflect.TypeOf(value).AssignableTo(t) {
+		target.Set(reflect.ValueOf(value))
+		return nil
+	}
+	if t.Kind() != reflect.Ptr && (reflect.PtrTo(t).Implements(jsonUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType)) {
+		return convertJSON(value, target, path)
+	}
+	if object, ok := value.(*ordered.Object); ok {
+		// nested objects are converted when converting the values
+		value = object.Map()
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		element := reflect.New(t.Elem())
+		if err := convertValue(value, element.Elem(), path); err != nil {
+			return err
+		}
+		target.Set(element)
+		return nil
+	case reflect.Interface:
+		return mismatch(value, t, path)
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch(value, t, path)
+		}
+		target.SetBool(b)
+		return nil
+	case reflect.String:
+		if t == jsonNumberType {
+			n, ok := util.ToJSONNumber(value)
+			if !ok {
+				return mismatch(value, t, path)
+			}
+			target.SetString(string(n))
+			return nil
+		}
+		s, ok := value.(string)
+		if !ok {
+			return mismatch(value, t, path)
+		}
+		target.SetString(s)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f, ok := value.(float64); ok && f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			if target.OverflowInt(int64(f)) {
+				return &ConversionError{Path: path, Value: describeNumber(value), Type: t}
+			}
+			target.SetInt(int64(f))
+			return nil
+		}
+		r, ok := util.ToRat(value)
+		if !ok {
+			return mismatch(value, t, path)
+		}
+		if !r.IsInt() || !r.Num().IsInt64() || target.OverflowInt(r.Num().Int64()) {
+			return &ConversionError{Path: path, Value: describeNumber(value), Type: t}
+		}
+		target.SetInt(r.Num().Int64())
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		r, ok := util.ToRat(value)
+		if !ok {
+			return mismatch(value, t, path)
+		}
+		if !r.IsInt() || !r.Num().IsUint64() || target.OverflowUint(r.Num().Uint64()) {
+			return &ConversionError{Path: path, Value: describeNumber(value), Type: t}
+		}
+		target.SetUint(r.Num().Uint64())
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, ok := util.ToNumber(value)
+		if !ok {
+			return mismatch(value, t, path)
+		}
+		if target.OverflowFloat(f) && !math.IsInf(f, 0) {
+			return &ConversionError{Path: path, Value: describeNumber(value), Type: t}
+		}
+		target.SetFloat(f)
+		return nil
+	case reflect.Slice:
+		items, ok := util.ToArray(value)
+		if !ok {
+			return mismatch(value, t, path)
+		}
+		slice := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if err := convertValue(item, slice.Index(i), path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+	case reflect.Array:
+		items, ok := util.ToArray(value)
+		if !ok {
+			return mismatch(value, t, path)
+		}
+		// like encoding/json, extra items are ignored and missing items are zero
+		array := reflect.New(t).Elem()
+		for i := 0; i < len(items) && i < array.Len(); i++ {
+			if err := convertValue(items[i], array.Index(i), path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+		target.Set(array)
+		return nil
+	case reflect.Map:
+		source := reflect.ValueOf(value)
+		switch {
+		case source.Kind() == reflect.Struct:
+			return convertJSON(value, target, path)
+		case source.Kind() != reflect.Map || source.Type().Key().Kind() != reflect.String:
+			return mismatch(value, t, path)
+		case t.Key().Kind() != reflect.String:
+			// encoding/json also decodes integer and text keys
+			return convertJSON(value, target, path)
+		}
+		m := reflect.MakeMapWithSize(t, source.Len())
+		iter := source.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			element := reflect.New(t.Elem()).Elem()
+			if err := convertValue(iter.Value().Interface(), element, path+"."+quoteKey(key)); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), element)
+		}
+		target.Set(m)
+		return nil
+	case reflect.Struct:
+		switch reflect.TypeOf(value).Kind() {
+		case reflect.Map, reflect.Struct:
+			return convertJSON(value, target, path)
+		}
+	}
+	return mismatch(value, t, path)
+}
+
+func mismatch(value any, t reflect.Type, path string) error {
+	return &ConversionError{Path: path, Value: describe(value), Type: t}
+}
+
+// convertJSON converts a value by encoding it to JSON and decoding it into the target,
+// it is used for structs and types implementing json.Unmarshaler.
+func convertJSON(value any, target reflect.Value, path string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	element := reflect.New(target.Type())
+	if err := json.Unmarshal(data, element.Interface()); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			if typeErr.Field != "" {
+				path += "." + typeErr.Field
+			}
+			return &ConversionError{Path: path, Value: typeErr.Value, Type: typeErr.Type}
+		}
+		return err
+	}
+	target.Set(element.Elem())
+	return nil
+}
+
+// describe returns the JSON type of a value, like encoding/json describes values in errors.
+func describe(value any) string {
+	switch {
+	case value == nil:
+		return "null"
+	case util.IsNumber(value):
+		return "number"
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func describeNumber(value any) string {
+	if n, ok := util.ToJSONNumber(value); ok {
+		return "number " + string(n)
+	}
+	return fmt.Sprint("number ", value)
+}
+
+// quoteKey returns an object key as it appears in a path, quoted unless it is a valid identifier.
+func quoteKey(key string) string {
+	for i, c := range key {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			return strconv.Quote(key)
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
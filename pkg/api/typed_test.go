@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
+	"github.com/stretchr/testify/assert"
+)
+
+type typedPerson struct {
+	Name    string    `json:"name"`
+	Age     uint8     `json:"age"`
+	Tags    []string  `json:"tags,omitempty"`
+	Born    time.Time `json:"born"`
+	Manager *typedPerson
+}
+
+func TestSearchAs(t *testing.T) {
+	data := map[string]any{
+		"people": []any{
+			map[string]any{"name": "alice", "age": 30.0, "tags": []any{"a", "b"}, "born": "1990-01-02T00:00:00Z"},
+			map[string]any{"name": "bob", "age": json.Number("300"), "manager": map[string]any{"name": "alice"}},
+		},
+		"counts": map[string]any{"a": 1.0, "b": json.Number("9007199254740993")},
+		"ratio":  0.5,
+	}
+	born := time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		search  func() (any, error)
+		want    any
+		wantErr string
+	}{{
+		name:   "strings",
+		search: func() (any, error) { return SearchAs[[]string]("people[*].name", data) },
+		want:   []string{"alice", "bob"},
+	}, {
+		name:   "string",
+		search: func() (any, error) { return SearchAs[string]("people[0].name", data) },
+		want:   "alice",
+	}, {
+		name:   "null",
+		search: func() (any, error) { return SearchAs[*string]("missing", data) },
+		want:   (*string)(nil),
+	}, {
+		name:   "pointer",
+		search: func() (any, error) { return SearchAs[*float64]("ratio", data) },
+		want:   func() *float64 { f := 0.5; return &f }(),
+	}, {
+		name:   "integers",
+		search: func() (any, error) { return SearchAs[map[string]int64]("counts", data) },
+		want:   map[string]int64{"a": 1, "b": 9007199254740993},
+	}, {
+		name:   "array",
+		search: func() (any, error) { return SearchAs[[1]string]("people[*].name", data) },
+		want:   [1]string{"alice"},
+	}, {
+		name:   "any",
+		search: func() (any, error) { return SearchAs[any]("people[0].tags", data) },
+		want:   []any{"a", "b"},
+	}, {
+		name:   "struct",
+		search: func() (any, error) { return SearchAs[typedPerson]("people[0]", data) },
+		want:   typedPerson{Name: "alice", Age: 30, Tags: []string{"a", "b"}, Born: born},
+	}, {
+		name:   "struct from struct",
+		search: func() (any, error) { return SearchAs[map[string]any]("@", typedPerson{Name: "carol", Born: born}) },
+		want:   map[string]any{"name": "carol", "age": 0.0, "born": "1990-01-02T00:00:00Z", "Manager": nil},
+	}, {
+		name:   "time",
+		search: func() (any, error) { return SearchAs[time.Time]("people[0].born", data) },
+		want:   born,
+	}, {
+		name:    "not an integer",
+		search:  func() (any, error) { return SearchAs[int]("ratio", data) },
+		wantErr: "cannot convert number 0.5 to int",
+	}, {
+		name:    "overflow",
+		search:  func() (any, error) { return SearchAs[[]uint8]("people[*].age", data) },
+		wantErr: "cannot convert number 300 to uint8 at [1]",
+	}, {
+		name:    "struct field",
+		search:  func() (any, error) { return SearchAs[[]typedPerson]("people", data) },
+		wantErr: "cannot convert number 300 to uint8 at [1].age",
+	}, {
+		name:    "object",
+		search:  func() (any, error) { return SearchAs[[]string]("counts", data) },
+		wantErr: "cannot convert object to []string",
+	}, {
+		name:    "key",
+		search:  func() (any, error) { return SearchAs[map[string]string]("{\"a b\": people[0].age}", data) },
+		wantErr: "cannot convert number to string at .\"a b\"",
+	}, {
+		name:    "search error",
+		search:  func() (any, error) { return SearchAs[string]("foo[", data) },
+		wantErr: "SyntaxError",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			result, err := tt.search()
+			if tt.wantErr != "" {
+				assert.ErrorContains(err, tt.wantErr)
+				return
+			}
+			assert.NoError(err)
+			assert.Equal(tt.want, result)
+		})
+	}
+}
+
+func TestSearchAsConversionError(t *testing.T) {
+	assert := assert.New(t)
+	_, err := SearchAs[[]int]("[`1`, 'a']", nil)
+	var conversionErr *ConversionError
+	assert.True(errors.As(err, &conversionErr))
+	assert.Equal(&ConversionError{Path: "[1]", Value: "string", Type: reflect.TypeOf(0)}, conversionErr)
+}
+
+func TestCompileAs(t *testing.T) {
+	assert := assert.New(t)
+	compiled, err := CompileAs[[]string]("items[?size > `1`].name", WithVirtualMachine())
+	assert.NoError(err)
+	result, err := compiled.Search(map[string]any{"items": []any{
+		map[string]any{"size": 1.0, "name": "a"},
+		map[string]any{"size": 3.0, "name": "b"},
+	}})
+	assert.NoError(err)
+	assert.Equal([]string{"b"}, result)
+	_, err = CompileAs[string]("foo[")
+	assert.Error(err)
+	assert.Panics(func() { MustCompileAs[string]("foo[") })
+	count, err := NewCompiledAs[int](MustCompile("length(@)")).Search([]int{1, 2})
+	assert.NoError(err)
+	assert.Equal(2, count)
+}
+
+func TestSearchAsOrderedObject(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ordered.Unmarshal([]byte(`{"service": {"name": "web", "ports": [{"port": 80}]}}`))
+	assert.NoError(err)
+	type port struct {
+		Port int `json:"port"`
+	}
+	type service struct {
+		Name  string `json:"name"`
+		Ports []port `json:"ports"`
+	}
+	s, err := SearchAs[service]("service", data)
+	assert.NoError(err)
+	assert.Equal(service{Name: "web", Ports: []port{{Port: 80}}}, s)
+	m, err := SearchAs[map[string][]map[string]int]("{ports: service.ports}", data, interpreter.WithOrderedObjects())
+	assert.NoError(err)
+	assert.Equal(map[string][]map[string]int{"ports": {{"port": 80}}}, m)
+	o, err := SearchAs[*ordered.Object]("service", data)
+	assert.NoError(err)
+	assert.Equal([]string{"name", "ports"}, o.Keys())
+	_, err = SearchAs[map[string]string]("service", data)
+	var conversionErr *ConversionError
+	assert.True(errors.As(err, &conversionErr))
+	assert.Equal(".ports", conversionErr.Path)
+}
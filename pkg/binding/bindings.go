@@ -1,7 +1,7 @@
 package binding
 
 import (
-	"fmt"
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
 )
 
 // Bindings stores let expression bindings by name.
@@ -24,7 +24,7 @@ func (b bindings) Get(name string) (Binding, error) {
 	if value, ok := b.bindings[name]; ok {
 		return value, nil
 	}
-	return nil, fmt.Errorf("variable not defined: %s", name)
+	return nil, jperror.VariableNotDefined(name)
 }
 
 func (b bindings) Register(name string, binding Binding) Bindings {
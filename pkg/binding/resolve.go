@@ -1,12 +1,13 @@
 package binding
 
 import (
-	"errors"
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
 )
 
 func Resolve(name string, bindings Bindings) (any, error) {
+	// no variable is defined without bindings
 	if bindings == nil {
-		return nil, errors.New("bindings must not be nil")
+		return nil, jperror.VariableNotDefined(name)
 	}
 	binding, err := bindings.Get(name)
 	if err != nil {
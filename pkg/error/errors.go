@@ -1,24 +1,155 @@
 package error
 
 import (
-	"errors"
 	"fmt"
+	"strings"
 )
 
+// Kind identifies the category of an error, using the error names
+// defined by the JMESPath specification.
+type Kind string
+
+const (
+	InvalidType       Kind = "invalid-type"
+	InvalidArity      Kind = "invalid-arity"
+	UnknownFunction   Kind = "unknown-function"
+	InvalidValue      Kind = "invalid-value"
+	UndefinedVariable Kind = "undefined-variable"
+	Syntax            Kind = "syntax"
+	// Unsupported is not defined by the specification, it is returned when an
+	// expression cannot be used by an evaluation mode, like locating values, or
+	// contains nodes the interpreter does not know.
+	Unsupported Kind = "unsupported"
+	// BudgetExceeded is not defined by the specification, it is returned when an
+	// evaluation exceeds one of its configured limits.
+	BudgetExceeded Kind = "budget-exceeded"
+)
+
+// Error is the error returned when an expression fails to parse or evaluate.
+// Use errors.As to retrieve it and inspect its Kind.
+type Error struct {
+	// Kind is the category of the error.
+	Kind Kind
+	// Function is the name of the function that failed, if any.
+	Function string
+	// Argument is the zero-based index of the offending function argument,
+	// or -1 when the error is not related to a specific argument.
+	Argument int
+	// Message is the human readable description of the error.
+	Message string
+	// Expression is the expression that generated the error, when known.
+	Expression string
+	// Start and End are the byte offsets of the part of the expression
+	// where the error occurred. End is zero when the location is unknown.
+	Start int
+	End   int
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// HasLocation reports whether the part of the expression where the error occurred is known.
+func (e *Error) HasLocation() bool {
+	return e.End > 0
+}
+
+// Locate returns a copy of the error located at the given offsets,
+// the error is returned unchanged if its location is already known.
+func (e *Error) Locate(start int, end int) *Error {
+	if e.HasLocation() {
+		return e
+	}
+	located := *e
+	located.Start = start
+	located.End = end
+	return &located
+}
+
+// HighlightLocation will show where the error occurred.
+// It will underline the part of the expression that failed
+// with "^" characters on a line below the expression.
+func (e *Error) HighlightLocation() string {
+	if !e.HasLocation() {
+		return e.Expression
+	}
+	return e.Expression + "\n" + strings.Repeat(" ", e.Start) + strings.Repeat("^", e.End-e.Start)
+}
+
+// New creates an Error of the given kind that is not related to a function call.
+func New(kind Kind, message string) *Error {
+	return &Error{
+		Kind:     kind,
+		Argument: -1,
+		Message:  message,
+	}
+}
+
 func NotAnInteger(name string, arg string) error {
-	return errors.New(formatNotAnInteger(name, arg))
+	return InvalidValueArgument(name, -1, formatNotAnInteger(name, arg))
+}
+
+// NotAnIntegerArgument is like NotAnInteger but also records the zero-based index of the argument.
+func NotAnIntegerArgument(name string, index int, arg string) error {
+	return InvalidValueArgument(name, index, formatNotAnInteger(name, arg))
 }
 
 func NotAPositiveInteger(name string, arg string) error {
-	return errors.New(formatNotAPositiveInteger(name, arg))
+	return InvalidValueArgument(name, -1, formatNotAPositiveInteger(name, arg))
+}
+
+// NotAPositiveIntegerArgument is like NotAPositiveInteger but also records the zero-based index of the argument.
+func NotAPositiveIntegerArgument(name string, index int, arg string) error {
+	return InvalidValueArgument(name, index, formatNotAPositiveInteger(name, arg))
 }
 
 func NotEnoughArgumentsSupplied(name string, count int, minExpected int, variadic bool) error {
-	return errors.New(formatNotEnoughArguments(name, count, minExpected, variadic))
+	return &Error{
+		Kind:     InvalidArity,
+		Function: name,
+		Argument: -1,
+		Message:  formatNotEnoughArguments(name, count, minExpected, variadic),
+	}
 }
 
 func TooManyArgumentsSupplied(name string, count int, maxExpected int) error {
-	return errors.New(formatTooManyArguments(name, count, maxExpected))
+	return &Error{
+		Kind:     InvalidArity,
+		Function: name,
+		Argument: -1,
+		Message:  formatTooManyArguments(name, count, maxExpected),
+	}
+}
+
+func UnknownFunctionCalled(name string) error {
+	return &Error{
+		Kind:     UnknownFunction,
+		Function: name,
+		Argument: -1,
+		Message:  "unknown function: " + name,
+	}
+}
+
+func InvalidTypeArgument(name string, index int, message string) error {
+	return &Error{
+		Kind:     InvalidType,
+		Function: name,
+		Argument: index,
+		Message:  message,
+	}
+}
+
+func InvalidValueArgument(name string, index int, message string) error {
+	return &Error{
+		Kind:     InvalidValue,
+		Function: name,
+		Argument: index,
+		Message:  message,
+	}
+}
+
+func VariableNotDefined(name string) error {
+	return New(UndefinedVariable, fmt.Sprintf("variable not defined: %s", name))
 }
 
 func formatNotAnInteger(name string, arg string) string {
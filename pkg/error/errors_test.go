@@ -0,0 +1,29 @@
+package error
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidValueErrors(t *testing.T) {
+	tests := []struct {
+		err          error
+		wantArgument int
+	}{
+		{err: NotAnInteger("slice", "start"), wantArgument: -1},
+		{err: NotAnIntegerArgument("slice", 1, "start"), wantArgument: 1},
+		{err: NotAPositiveInteger("pad_left", "width"), wantArgument: -1},
+		{err: NotAPositiveIntegerArgument("pad_left", 1, "width"), wantArgument: 1},
+	}
+	for _, tt := range tests {
+		assert := assert.New(t)
+		var jpErr *Error
+		if assert.True(errors.As(tt.err, &jpErr)) {
+			assert.Equal(InvalidValue, jpErr.Kind)
+			assert.Equal(tt.wantArgument, jpErr.Argument)
+			assert.NotEmpty(jpErr.Function)
+		}
+	}
+}
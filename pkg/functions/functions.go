@@ -2,7 +2,6 @@ package functions
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"math"
 	"reflect"
@@ -13,6 +12,7 @@ import (
 	"unicode/utf8"
 
 	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
 	"github.com/jmespath-community/go-jmespath/pkg/util"
 )
 
@@ -92,17 +92,12 @@ func (a *byExprFloat) Swap(i, j int) {
 }
 
 func (a *byExprFloat) Less(i, j int) bool {
-	ith, ok := a.keys[i].(float64)
-	if !ok {
-		a.hasError = true
-		return true
-	}
-	jth, ok := a.keys[j].(float64)
-	if !ok {
+	if !util.IsNumber(a.keys[i]) || !util.IsNumber(a.keys[j]) {
 		a.hasError = true
 		return true
 	}
-	return ith < jth
+	c, _ := util.CompareNumbers(a.keys[i], a.keys[j])
+	return c < 0
 }
 
 func jpfAbs(arguments []any) (any, error) {
@@ -142,7 +137,7 @@ func jpfContains(arguments []any) (any, error) {
 	// Otherwise this is a generic contains for []any
 	general := search.([]any)
 	for _, item := range general {
-		if reflect.DeepEqual(el, item) {
+		if util.ObjsEqual(el, item) {
 			return true, nil
 		}
 	}
@@ -168,7 +163,7 @@ func jpfFindImpl(name string, arguments []any, find func(s string, substr string
 	if startSpecified {
 		num, ok := util.ToInteger(arguments[2])
 		if !ok {
-			return nil, jperror.NotAnInteger(name, "start")
+			return nil, jperror.NotAnIntegerArgument(name, 2, "start")
 		}
 		start = util.Max(0, num)
 	}
@@ -177,7 +172,7 @@ func jpfFindImpl(name string, arguments []any, find func(s string, substr string
 	if endSpecified {
 		num, ok := util.ToInteger(arguments[3])
 		if !ok {
-			return nil, jperror.NotAnInteger(name, "end")
+			return nil, jperror.NotAnIntegerArgument(name, 3, "end")
 		}
 		end = util.Min(num, len(subject))
 	}
@@ -205,31 +200,43 @@ func jpfFloor(arguments []any) (any, error) {
 }
 
 func jpfFromItems(arguments []any) (any, error) {
+	return fromItems(arguments, false)
+}
+
+// fromItems builds an object from key value pairs, an ordered object when keepOrder is set.
+func fromItems(arguments []any, keepOrder bool) (any, error) {
 	if arr, ok := util.ToArrayArray(arguments[0]); ok {
-		result := make(map[string]any)
+		result := newObject(keepOrder)
 		for _, item := range arr {
 			if len(item) != 2 {
-				return nil, errors.New("invalid value, each array must contain two elements, a pair of string and value")
+				return nil, jperror.InvalidValueArgument("from_items", 0, "invalid value, each array must contain two elements, a pair of string and value")
 			}
 			first, ok := item[0].(string)
 			if !ok {
-				return nil, errors.New("invalid value, each array must contain two elements, a pair of string and value")
+				return nil, jperror.InvalidValueArgument("from_items", 0, "invalid value, each array must contain two elements, a pair of string and value")
 			}
 			second := item[1]
-			result[first] = second
+			result.set(first, second)
 		}
-		return result, nil
+		return result.value(), nil
 	}
-	return nil, errors.New("invalid type, first argument must be an array of arrays")
+	return nil, jperror.InvalidTypeArgument("from_items", 0, "invalid type, first argument must be an array of arrays")
 }
 
 func jpfGroupBy(arguments []any) (any, error) {
+	return groupBy(arguments, false)
+}
+
+// groupBy groups elements by key, in an ordered object following the order
+// in which keys are found when keepOrder is set.
+func groupBy(arguments []any, keepOrder bool) (any, error) {
 	arr := arguments[0].([]any)
 	exp := arguments[1].(ExpRef)
 	if len(arr) == 0 {
 		return nil, nil
 	}
-	groups := map[string]any{}
+	groups := map[string][]any{}
+	keys := []string{}
 	for _, element := range arr {
 		spec, err := exp(element)
 		if err != nil {
@@ -237,21 +244,26 @@ func jpfGroupBy(arguments []any) (any, error) {
 		}
 		key, ok := spec.(string)
 		if !ok {
-			return nil, errors.New("invalid type, the expression must evaluate to a string")
+			return nil, jperror.InvalidTypeArgument("group_by", 1, "invalid type, the expression must evaluate to a string")
 		}
 		if _, ok := groups[key]; !ok {
-			groups[key] = []any{}
+			keys = append(keys, key)
 		}
-		groups[key] = append(groups[key].([]any), element)
+		groups[key] = append(groups[key], element)
+	}
+	result := newObject(keepOrder)
+	for _, key := range keys {
+		result.set(key, groups[key])
 	}
-	return groups, nil
+	return result.value(), nil
 }
 
 func jpfItems(arguments []any) (any, error) {
-	value := arguments[0].(map[string]any)
+	value, _ := util.ToObject(arguments[0])
+	keys, _ := util.ObjectKeys(arguments[0])
 	arrays := []any{}
-	for key, item := range value {
-		var element any = []any{key, item}
+	for _, key := range keys {
+		var element any = []any{key, value[key]}
 		arrays = append(arrays, element)
 	}
 
@@ -270,9 +282,9 @@ func jpfJoin(arguments []any) (any, error) {
 }
 
 func jpfKeys(arguments []any) (any, error) {
-	arg := arguments[0].(map[string]any)
-	collected := make([]any, 0, len(arg))
-	for key := range arg {
+	keys, _ := util.ObjectKeys(arguments[0])
+	collected := make([]any, 0, len(keys))
+	for _, key := range keys {
 		collected = append(collected, key)
 	}
 	return collected, nil
@@ -287,8 +299,10 @@ func jpfLength(arguments []any) (any, error) {
 		return float64(v.Len()), nil
 	} else if c, ok := arg.(map[string]any); ok {
 		return float64(len(c)), nil
+	} else if c, ok := arg.(*ordered.Object); ok {
+		return float64(c.Len()), nil
 	}
-	return nil, errors.New("could not compute length()")
+	return nil, jperror.InvalidTypeArgument("length", 0, "could not compute length()")
 }
 
 func jpfLower(arguments []any) (any, error) {
@@ -354,25 +368,25 @@ func jpfMaxBy(arguments []any) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	switch t := start.(type) {
-	case float64:
-		bestVal := t
+	if util.IsNumber(start) {
+		bestVal := start
 		bestItem := arr[0]
 		for _, item := range arr[1:] {
-			result, err := exp(item)
+			current, err := exp(item)
 			if err != nil {
 				return nil, err
 			}
-			current, ok := result.(float64)
-			if !ok {
-				return nil, errors.New("invalid type, must be number")
+			if !util.IsNumber(current) {
+				return nil, jperror.InvalidTypeArgument("max_by", 1, "invalid type, must be number")
 			}
-			if current > bestVal {
+			if c, _ := util.CompareNumbers(current, bestVal); c > 0 {
 				bestVal = current
 				bestItem = item
 			}
 		}
 		return bestItem, nil
+	}
+	switch t := start.(type) {
 	case string:
 		bestVal := t
 		bestItem := arr[0]
@@ -383,7 +397,7 @@ func jpfMaxBy(arguments []any) (any, error) {
 			}
 			current, ok := result.(string)
 			if !ok {
-				return nil, errors.New("invalid type, must be string")
+				return nil, jperror.InvalidTypeArgument("max_by", 1, "invalid type, must be string")
 			}
 			if current > bestVal {
 				bestVal = current
@@ -392,16 +406,38 @@ func jpfMaxBy(arguments []any) (any, error) {
 		}
 		return bestItem, nil
 	default:
-		return nil, errors.New("invalid type, must be number of string")
+		return nil, jperror.InvalidTypeArgument("max_by", 1, "invalid type, must be number of string")
 	}
 }
 
 func jpfMerge(arguments []any) (any, error) {
-	final := make(map[string]any)
+	return merge(arguments, false)
+}
+
+// merge merges objects, the result is an ordered object when keepOrder is set or one
+// of the objects is ordered. Keys keep the position where they are first found.
+func merge(arguments []any, keepOrder bool) (any, error) {
+	for _, m := range arguments {
+		if _, ok := m.(*ordered.Object); ok {
+			keepOrder = true
+		}
+	}
+	if !keepOrder {
+		final := make(map[string]any)
+		for _, m := range arguments {
+			mapped := m.(map[string]any)
+			for key, value := range mapped {
+				final[key] = value
+			}
+		}
+		return final, nil
+	}
+	final := ordered.NewObject(0)
 	for _, m := range arguments {
-		mapped := m.(map[string]any)
-		for key, value := range mapped {
-			final[key] = value
+		mapped, _ := util.ToObject(m)
+		keys, _ := util.ObjectKeys(m)
+		for _, key := range keys {
+			final.Set(key, mapped[key])
 		}
 	}
 	return final, nil
@@ -451,19 +487,18 @@ func jpfMinBy(arguments []any) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	if t, ok := start.(float64); ok {
-		bestVal := t
+	if util.IsNumber(start) {
+		bestVal := start
 		bestItem := arr[0]
 		for _, item := range arr[1:] {
-			result, err := exp(item)
+			current, err := exp(item)
 			if err != nil {
 				return nil, err
 			}
-			current, ok := result.(float64)
-			if !ok {
-				return nil, errors.New("invalid type, must be number")
+			if !util.IsNumber(current) {
+				return nil, jperror.InvalidTypeArgument("min_by", 1, "invalid type, must be number")
 			}
-			if current < bestVal {
+			if c, _ := util.CompareNumbers(current, bestVal); c < 0 {
 				bestVal = current
 				bestItem = item
 			}
@@ -479,7 +514,7 @@ func jpfMinBy(arguments []any) (any, error) {
 			}
 			current, ok := result.(string)
 			if !ok {
-				return nil, errors.New("invalid type, must be string")
+				return nil, jperror.InvalidTypeArgument("min_by", 1, "invalid type, must be string")
 			}
 			if current < bestVal {
 				bestVal = current
@@ -488,7 +523,7 @@ func jpfMinBy(arguments []any) (any, error) {
 		}
 		return bestItem, nil
 	} else {
-		return nil, errors.New("invalid type, must be number of string")
+		return nil, jperror.InvalidTypeArgument("min_by", 1, "invalid type, must be number of string")
 	}
 }
 
@@ -509,13 +544,13 @@ func jpfPadImpl(
 	s := arguments[0].(string)
 	width, ok := util.ToPositiveInteger(arguments[1])
 	if !ok {
-		return nil, jperror.NotAPositiveInteger(name, "width")
+		return nil, jperror.NotAPositiveIntegerArgument(name, 1, "width")
 	}
 	chars := " "
 	if len(arguments) > 2 {
 		chars = arguments[2].(string)
 		if len(chars) > 1 {
-			return nil, fmt.Errorf("invalid value, the function '%s' expects its 'pad' argument to be a string of length 1", name)
+			return nil, jperror.InvalidValueArgument(name, 2, fmt.Sprintf("invalid value, the function '%s' expects its 'pad' argument to be a string of length 1", name))
 		}
 	}
 
@@ -552,7 +587,7 @@ func jpfReplace(arguments []any) (any, error) {
 	if len(arguments) > 3 {
 		num, ok := util.ToPositiveInteger(arguments[3])
 		if !ok {
-			return nil, jperror.NotAPositiveInteger("replace", "count")
+			return nil, jperror.NotAPositiveIntegerArgument("replace", 3, "count")
 		}
 		count = num
 	}
@@ -599,7 +634,9 @@ func jpfSort(arguments []any) (any, error) {
 }
 
 func jpfSortBy(arguments []any) (any, error) {
-	arr := arguments[0].([]any)
+	// sort a copy, the input array must not be modified
+	arr := make([]any, len(arguments[0].([]any)))
+	copy(arr, arguments[0].([]any))
 	exp := arguments[1].(ExpRef)
 	if len(arr) == 0 {
 		return arr, nil
@@ -614,22 +651,22 @@ func jpfSortBy(arguments []any) (any, error) {
 			sortKeys = append(sortKeys, value)
 		}
 	}
-	if _, ok := sortKeys[0].(float64); ok {
+	if util.IsNumber(sortKeys[0]) {
 		sortable := &byExprFloat{arr, sortKeys, false}
 		sort.Stable(sortable)
 		if sortable.hasError {
-			return nil, errors.New("error in sort_by comparison")
+			return nil, jperror.InvalidTypeArgument("sort_by", 1, "error in sort_by comparison")
 		}
 		return arr, nil
 	} else if _, ok := sortKeys[0].(string); ok {
 		sortable := &byExprString{arr, sortKeys, false}
 		sort.Stable(sortable)
 		if sortable.hasError {
-			return nil, errors.New("error in sort_by comparison")
+			return nil, jperror.InvalidTypeArgument("sort_by", 1, "error in sort_by comparison")
 		}
 		return arr, nil
 	} else {
-		return nil, errors.New("invalid type, must be number of string")
+		return nil, jperror.InvalidTypeArgument("sort_by", 1, "invalid type, must be number of string")
 	}
 }
 
@@ -645,7 +682,7 @@ func jpfSplit(arguments []any) (any, error) {
 	if nSpecified {
 		num, ok := util.ToPositiveInteger(arguments[2])
 		if !ok {
-			return nil, jperror.NotAPositiveInteger("split", "count")
+			return nil, jperror.NotAPositiveIntegerArgument("split", 2, "count")
 		}
 		n = num
 	}
@@ -686,8 +723,8 @@ func jpfSum(arguments []any) (any, error) {
 }
 
 func jpfToArray(arguments []any) (any, error) {
-	if _, ok := arguments[0].([]any); ok {
-		return arguments[0], nil
+	if array, ok := util.ToArray(arguments[0]); ok {
+		return array, nil
 	}
 	return arguments[:1:1], nil
 }
@@ -724,10 +761,13 @@ func jpfToNumber(arguments []any) (any, error) {
 	if _, ok := arg.([]any); ok {
 		return nil, nil
 	}
-	if _, ok := arg.(map[string]any); ok {
+	if util.IsObject(arg) {
+		return nil, nil
+	}
+	if util.IsSliceType(arg) || util.IsMapType(arg) {
 		return nil, nil
 	}
-	return nil, errors.New("unknown type")
+	return nil, jperror.InvalidTypeArgument("to_number", 0, "unknown type")
 }
 
 func jpfTrimImpl(
@@ -761,7 +801,7 @@ func jpfTrimRight(arguments []any) (any, error) {
 
 func jpfType(arguments []any) (any, error) {
 	arg := arguments[0]
-	if _, ok := arg.(float64); ok {
+	if util.IsNumber(arg) {
 		return "number", nil
 	}
 	if _, ok := arg.(string); ok {
@@ -770,7 +810,7 @@ func jpfType(arguments []any) (any, error) {
 	if _, ok := arg.([]any); ok {
 		return "array", nil
 	}
-	if _, ok := arg.(map[string]any); ok {
+	if util.IsObject(arg) {
 		return "object", nil
 	}
 	if arg == nil {
@@ -779,7 +819,13 @@ func jpfType(arguments []any) (any, error) {
 	if arg == true || arg == false {
 		return "boolean", nil
 	}
-	return nil, errors.New("unknown type")
+	if util.IsSliceType(arg) {
+		return "array", nil
+	}
+	if util.IsMapType(arg) {
+		return "object", nil
+	}
+	return nil, jperror.InvalidTypeArgument("type", 0, "unknown type")
 }
 
 func jpfUpper(arguments []any) (any, error) {
@@ -787,10 +833,11 @@ func jpfUpper(arguments []any) (any, error) {
 }
 
 func jpfValues(arguments []any) (any, error) {
-	arg := arguments[0].(map[string]any)
-	collected := make([]any, 0, len(arg))
-	for _, value := range arg {
-		collected = append(collected, value)
+	arg, _ := util.ToObject(arguments[0])
+	keys, _ := util.ObjectKeys(arguments[0])
+	collected := make([]any, 0, len(keys))
+	for _, key := range keys {
+		collected = append(collected, arg[key])
 	}
 	return collected, nil
 }
@@ -0,0 +1,52 @@
+package functions
+
+import (
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
+)
+
+// OrderedFunctions returns a copy of functions where merge, from_items and group_by
+// return an *ordered.Object, keeping keys in the order they are found, instead of
+// a map[string]any. They are used when evaluating with ordered objects.
+func OrderedFunctions(funcs ...FunctionEntry) []FunctionEntry {
+	handlers := map[string]JpFunction{
+		"from_items": func(arguments []any) (any, error) { return fromItems(arguments, true) },
+		"group_by":   func(arguments []any) (any, error) { return groupBy(arguments, true) },
+		"merge":      func(arguments []any) (any, error) { return merge(arguments, true) },
+	}
+	functions := make([]FunctionEntry, len(funcs))
+	copy(functions, funcs)
+	for i, function := range functions {
+		if handler, ok := handlers[function.Name]; ok {
+			functions[i].Handler = handler
+		}
+	}
+	return functions
+}
+
+// object builds a map[string]any, or an *ordered.Object when keeping the order of keys.
+type object struct {
+	unordered map[string]any
+	ordered   *ordered.Object
+}
+
+func newObject(keepOrder bool) object {
+	if keepOrder {
+		return object{ordered: ordered.NewObject(0)}
+	}
+	return object{unordered: map[string]any{}}
+}
+
+func (o object) set(key string, value any) {
+	if o.ordered != nil {
+		o.ordered.Set(key, value)
+		return
+	}
+	o.unordered[key] = value
+}
+
+func (o object) value() any {
+	if o.ordered != nil {
+		return o.ordered
+	}
+	return o.unordered
+}
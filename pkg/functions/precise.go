@@ -0,0 +1,164 @@
+package functions
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/jmespath-community/go-jmespath/pkg/util"
+)
+
+// GetPreciseFunctions returns the default functions, except that the numeric
+// functions compute exact results from json.Number arguments and return
+// json.Number values. They are used when evaluating with arbitrary precision.
+func GetPreciseFunctions() []FunctionEntry {
+	handlers := map[string]JpFunction{
+		"abs":       jpfPreciseAbs,
+		"avg":       jpfPreciseAvg,
+		"ceil":      jpfPreciseCeil,
+		"floor":     jpfPreciseFloor,
+		"max":       jpfPreciseMax,
+		"min":       jpfPreciseMin,
+		"sort":      jpfPreciseSort,
+		"sum":       jpfPreciseSum,
+		"to_number": jpfPreciseToNumber,
+	}
+	functions := GetDefaultFunctions()
+	for i, function := range functions {
+		if handler, ok := handlers[function.Name]; ok {
+			functions[i].Handler = handler
+		}
+	}
+	return functions
+}
+
+func jpfPreciseAbs(arguments []any) (any, error) {
+	num, ok := util.ToRat(arguments[0])
+	if !ok {
+		return jpfAbs(toFloats(arguments))
+	}
+	return util.RatToJSONNumber(num.Abs(num)), nil
+}
+
+func jpfPreciseAvg(arguments []any) (any, error) {
+	items := arguments[0].([]any)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	sum, ok := preciseSum(items)
+	if !ok {
+		return jpfAvg([]any{toFloats(items)})
+	}
+	return util.RatToJSONNumber(sum.Quo(sum, new(big.Rat).SetInt64(int64(len(items))))), nil
+}
+
+func jpfPreciseCeil(arguments []any) (any, error) {
+	num, ok := util.ToRat(arguments[0])
+	if !ok {
+		return jpfCeil(toFloats(arguments))
+	}
+	return util.RatToJSONNumber(new(big.Rat).SetInt(ceil(num))), nil
+}
+
+func jpfPreciseFloor(arguments []any) (any, error) {
+	num, ok := util.ToRat(arguments[0])
+	if !ok {
+		return jpfFloor(toFloats(arguments))
+	}
+	return util.RatToJSONNumber(new(big.Rat).SetInt(floor(num))), nil
+}
+
+func jpfPreciseMax(arguments []any) (any, error) {
+	return preciseBest(arguments, 1, jpfMax)
+}
+
+func jpfPreciseMin(arguments []any) (any, error) {
+	return preciseBest(arguments, -1, jpfMin)
+}
+
+// preciseBest returns the element of an array of numbers that compares to all other
+// elements as the given sign, strings are handled by the fallback function.
+func preciseBest(arguments []any, sign int, fallback JpFunction) (any, error) {
+	items := arguments[0].([]any)
+	if len(items) == 0 || !util.IsNumber(items[0]) {
+		return fallback(arguments)
+	}
+	best := items[0]
+	for _, item := range items[1:] {
+		if c, _ := util.CompareNumbers(item, best); c == sign {
+			best = item
+		}
+	}
+	return best, nil
+}
+
+func jpfPreciseSort(arguments []any) (any, error) {
+	items := arguments[0].([]any)
+	if len(items) == 0 || !util.IsNumber(items[0]) {
+		return jpfSort(arguments)
+	}
+	sorted := make([]any, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		c, _ := util.CompareNumbers(sorted[i], sorted[j])
+		return c < 0
+	})
+	return sorted, nil
+}
+
+func jpfPreciseSum(arguments []any) (any, error) {
+	sum, ok := preciseSum(arguments[0].([]any))
+	if !ok {
+		return jpfSum(arguments)
+	}
+	return util.RatToJSONNumber(sum), nil
+}
+
+func jpfPreciseToNumber(arguments []any) (any, error) {
+	if v, ok := arguments[0].(string); ok {
+		// decimal numbers are converted exactly, even beyond the range of float64,
+		// the others like infinities and hexadecimal floats are parsed as float64
+		if strings.IndexAny(v, "/_xXoObB") < 0 {
+			if num, ok := new(big.Rat).SetString(v); ok {
+				return util.RatToJSONNumber(num), nil
+			}
+		}
+		return jpfToNumber(arguments)
+	}
+	if num, ok := util.ToJSONNumber(arguments[0]); ok {
+		return num, nil
+	}
+	return jpfToNumber(arguments)
+}
+
+// preciseSum returns the exact sum of numbers, it fails if one of them is an infinity or NaN.
+func preciseSum(items []any) (*big.Rat, bool) {
+	sum := new(big.Rat)
+	for _, item := range items {
+		num, ok := util.ToRat(item)
+		if !ok {
+			return nil, false
+		}
+		sum.Add(sum, num)
+	}
+	return sum, true
+}
+
+// toFloats converts numbers to float64, for the handlers of the default functions.
+func toFloats(numbers []any) []any {
+	floats := make([]any, len(numbers))
+	for i, number := range numbers {
+		floats[i], _ = util.ToNumber(number)
+	}
+	return floats
+}
+
+func floor(num *big.Rat) *big.Int {
+	// the denominator is positive, euclidean division rounds down
+	return new(big.Int).Div(num.Num(), num.Denom())
+}
+
+func ceil(num *big.Rat) *big.Int {
+	result := floor(new(big.Rat).Neg(num))
+	return result.Neg(result)
+}
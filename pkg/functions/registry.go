@@ -0,0 +1,76 @@
+package functions
+
+import (
+	"sort"
+)
+
+// Registry is an immutable set of functions indexed by name. With and Without return
+// new registries, so that a registry can be shared and derived from safely. The
+// functions of a registry are called with interpreter.NewFunctionCallerFromRegistry.
+// A nil registry has no functions.
+type Registry struct {
+	entries map[string]FunctionEntry
+}
+
+// NewRegistry returns a registry of functions, a function replaces the previous
+// functions having the same name.
+func NewRegistry(funcs ...FunctionEntry) *Registry {
+	return (&Registry{}).With(funcs...)
+}
+
+// DefaultRegistry returns a registry of the default functions, see GetDefaultFunctions.
+func DefaultRegistry() *Registry {
+	return NewRegistry(GetDefaultFunctions()...)
+}
+
+// With returns a registry with the functions of the registry and the given functions,
+// which replace the functions of the registry having the same name.
+func (r *Registry) With(funcs ...FunctionEntry) *Registry {
+	entries := make(map[string]FunctionEntry, len(r.all())+len(funcs))
+	for name, entry := range r.all() {
+		entries[name] = entry
+	}
+	for _, entry := range funcs {
+		entries[entry.Name] = entry
+	}
+	return &Registry{entries: entries}
+}
+
+// Without returns a registry with the functions of the registry except the functions
+// having the given names, names not found in the registry are ignored.
+func (r *Registry) Without(names ...string) *Registry {
+	entries := make(map[string]FunctionEntry, len(r.all()))
+	for name, entry := range r.all() {
+		entries[name] = entry
+	}
+	for _, name := range names {
+		delete(entries, name)
+	}
+	return &Registry{entries: entries}
+}
+
+// Lookup returns the function having the given name and whether it is found.
+func (r *Registry) Lookup(name string) (FunctionEntry, bool) {
+	entry, ok := r.all()[name]
+	return entry, ok
+}
+
+// List returns the functions of the registry sorted by name.
+func (r *Registry) List() []FunctionEntry {
+	funcs := make([]FunctionEntry, 0, len(r.all()))
+	for _, entry := range r.all() {
+		funcs = append(funcs, entry)
+	}
+	sort.Slice(funcs, func(i, j int) bool {
+		return funcs[i].Name < funcs[j].Name
+	})
+	return funcs
+}
+
+// all returns the functions of the registry indexed by name, it must not be modified.
+func (r *Registry) all() map[string]FunctionEntry {
+	if r == nil {
+		return nil
+	}
+	return r.entries
+}
@@ -0,0 +1,61 @@
+package functions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	assert := assert.New(t)
+	defaults := DefaultRegistry()
+	tenant := defaults.With(FunctionEntry{
+		Name: "shout",
+		Arguments: []ArgSpec{
+			{Types: []JpType{JpString}},
+		},
+		Handler: func(arguments []any) (any, error) {
+			return strings.ToUpper(arguments[0].(string)), nil
+		},
+	}).Without("to_number", "missing")
+	_, ok := tenant.Lookup("to_number")
+	assert.False(ok)
+	_, ok = defaults.Lookup("to_number")
+	assert.True(ok)
+	_, ok = defaults.Lookup("shout")
+	assert.False(ok)
+	entry, ok := tenant.Lookup("shout")
+	assert.True(ok)
+	assert.Equal("shout", entry.Name)
+	list := tenant.List()
+	assert.Len(list, len(defaults.List()))
+	for i := 1; i < len(list); i++ {
+		assert.Less(list[i-1].Name, list[i].Name)
+	}
+}
+
+func TestRegistryReplacesFunctions(t *testing.T) {
+	assert := assert.New(t)
+	first := FunctionEntry{Name: "f", Arguments: []ArgSpec{{Types: []JpType{JpAny}}}}
+	second := FunctionEntry{Name: "f", Arguments: []ArgSpec{{Types: []JpType{JpString}}}}
+	registry := NewRegistry(first, second)
+	entry, ok := registry.Lookup("f")
+	assert.True(ok)
+	assert.Equal(second.Arguments, entry.Arguments)
+	entry, ok = registry.With(first).Lookup("f")
+	assert.True(ok)
+	assert.Equal(first.Arguments, entry.Arguments)
+	assert.Len(registry.List(), 1)
+}
+
+func TestNilRegistry(t *testing.T) {
+	assert := assert.New(t)
+	var registry *Registry
+	_, ok := registry.Lookup("abs")
+	assert.False(ok)
+	assert.Empty(registry.List())
+	assert.Empty(registry.Without("abs").List())
+	_, ok = registry.With(FunctionEntry{Name: "abs"}).Lookup("abs")
+	assert.True(ok)
+}
@@ -0,0 +1,125 @@
+package interpreter
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+)
+
+// BudgetExceededError is returned when an evaluation exceeds one of the limits
+// configured with WithMaxSteps, WithMaxDepth or WithMaxResultElements. It wraps a
+// *jperror.Error of kind jperror.BudgetExceeded.
+type BudgetExceededError struct {
+	// Budget is the name of the exhausted budget, one of "steps", "depth" or "result elements".
+	Budget string
+	// Limit is the configured limit that was exceeded.
+	Limit int
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("budget exceeded, the evaluation exceeded the limit of %d %s", e.Limit, e.Budget)
+}
+
+// Unwrap returns the error as a *jperror.Error, so that errors.As finds its kind.
+func (e *BudgetExceededError) Unwrap() error {
+	return jperror.New(jperror.BudgetExceeded, e.Error())
+}
+
+// budget tracks the resources consumed by a single evaluation.
+// A zero limit means the corresponding resource is not limited.
+type budget struct {
+	maxSteps          int
+	maxDepth          int
+	maxResultElements int
+	depth             int
+	// counters are shared with the forks of the budget.
+	counters *budgetCounters
+}
+
+// budgetCounters are updated atomically, forks of a budget are used concurrently.
+type budgetCounters struct {
+	steps          int64
+	resultElements int64
+}
+
+func newBudget(o Options) *budget {
+	if o.MaxSteps <= 0 && o.MaxDepth <= 0 && o.MaxResultElements <= 0 {
+		return nil
+	}
+	return &budget{
+		maxSteps:          o.MaxSteps,
+		maxDepth:          o.MaxDepth,
+		maxResultElements: o.MaxResultElements,
+		counters:          &budgetCounters{},
+	}
+}
+
+// fork returns a budget sharing the steps and result elements of the budget,
+// with its own depth, for use by another goroutine.
+func (b *budget) fork() *budget {
+	if b == nil {
+		return nil
+	}
+	forked := *b
+	return &forked
+}
+
+// enter is called every time the interpreter starts evaluating a node.
+func (b *budget) enter() error {
+	steps := atomic.AddInt64(&b.counters.steps, 1)
+	b.depth++
+	if b.maxSteps > 0 && steps > int64(b.maxSteps) {
+		return &BudgetExceededError{Budget: "steps", Limit: b.maxSteps}
+	}
+	if b.maxDepth > 0 && b.depth > b.maxDepth {
+		return &BudgetExceededError{Budget: "depth", Limit: b.maxDepth}
+	}
+	return nil
+}
+
+// exit is called every time the interpreter is done evaluating a node.
+func (b *budget) exit() {
+	b.depth--
+}
+
+// produced accounts for the elements of arrays and objects built by a node.
+func (b *budget) produced(node parsing.ASTNode, result any) error {
+	if b.maxResultElements <= 0 {
+		return nil
+	}
+	if produced, ok := producedElements(node, result); ok {
+		if atomic.AddInt64(&b.counters.resultElements, int64(produced)) > int64(b.maxResultElements) {
+			return &BudgetExceededError{Budget: "result elements", Limit: b.maxResultElements}
+		}
+	}
+	return nil
+}
+
+// producedElements returns the number of elements of the array or object built by
+// a node. Nodes that only select part of their input don't allocate new elements
+// and return false.
+func producedElements(node parsing.ASTNode, result any) (int, bool) {
+	switch node.NodeType {
+	case parsing.ASTProjection,
+		parsing.ASTFilterProjection,
+		parsing.ASTValueProjection,
+		parsing.ASTFlatten,
+		parsing.ASTSlice,
+		parsing.ASTMultiSelectList,
+		parsing.ASTMultiSelectHash,
+		parsing.ASTFunctionExpression:
+		switch r := result.(type) {
+		case []any:
+			return len(r), true
+		case map[string]any:
+			return len(r), true
+		case *ordered.Object:
+			return r.Len(), true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,129 @@
+package interpreter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	wide := []any{}
+	for i := 0; i < 10; i++ {
+		wide = append(wide, []any{[]any{1.0, 2.0}, []any{3.0, 4.0}})
+	}
+	tests := []struct {
+		name       string
+		expression string
+		data       any
+		opts       []Option
+		want       any
+		wantBudget string
+	}{{
+		name:       "no limits",
+		expression: "[*][*][*]",
+		data:       wide,
+		want:       []any{[]any{[]any{1.0, 2.0}, []any{3.0, 4.0}}, []any{[]any{1.0, 2.0}, []any{3.0, 4.0}}, []any{[]any{1.0, 2.0}, []any{3.0, 4.0}}, []any{[]any{1.0, 2.0}, []any{3.0, 4.0}}, []any{[]any{1.0, 2.0}, []any{3.0, 4.0}}, []any{[]any{1.0, 2.0}, []any{3.0, 4.0}}, []any{[]any{1.0, 2.0}, []any{3.0, 4.0}}, []any{[]any{1.0, 2.0}, []any{3.0, 4.0}}, []any{[]any{1.0, 2.0}, []any{3.0, 4.0}}, []any{[]any{1.0, 2.0}, []any{3.0, 4.0}}},
+	}, {
+		name:       "within steps",
+		expression: "foo.bar",
+		data:       map[string]any{"foo": map[string]any{"bar": "baz"}},
+		opts:       []Option{WithMaxSteps(3)},
+		want:       "baz",
+	}, {
+		name:       "steps exceeded",
+		expression: "foo.bar",
+		data:       map[string]any{"foo": map[string]any{"bar": "baz"}},
+		opts:       []Option{WithMaxSteps(2)},
+		wantBudget: "steps",
+	}, {
+		name:       "steps exceeded in projection",
+		expression: "[*][*][*]",
+		data:       wide,
+		opts:       []Option{WithMaxSteps(50)},
+		wantBudget: "steps",
+	}, {
+		name:       "within depth",
+		expression: "a.b.c",
+		data:       map[string]any{"a": map[string]any{"b": map[string]any{"c": 1.0}}},
+		opts:       []Option{WithMaxDepth(3)},
+		want:       1.0,
+	}, {
+		name:       "depth exceeded",
+		expression: "a.b.c.d",
+		data:       map[string]any{"a": map[string]any{"b": map[string]any{"c": map[string]any{"d": 1.0}}}},
+		opts:       []Option{WithMaxDepth(3)},
+		wantBudget: "depth",
+	}, {
+		name:       "within depth in nested expression references",
+		expression: "map(&map(&map(&@, @), @), @)",
+		data:       []any{[]any{[]any{1.0}}},
+		opts:       []Option{WithMaxDepth(4)},
+		want:       []any{[]any{[]any{1.0}}},
+	}, {
+		name:       "depth exceeded in nested expression references",
+		expression: "map(&map(&map(&@, @), @), @)",
+		data:       []any{[]any{[]any{1.0}}},
+		opts:       []Option{WithMaxDepth(3)},
+		wantBudget: "depth",
+	}, {
+		name:       "within result elements",
+		expression: "[*][*]",
+		data:       []any{[]any{1.0, 2.0}},
+		opts:       []Option{WithMaxResultElements(3)},
+		want:       []any{[]any{1.0, 2.0}},
+	}, {
+		name:       "result elements exceeded",
+		expression: "[*][*][*]",
+		data:       wide,
+		opts:       []Option{WithMaxResultElements(60)},
+		wantBudget: "result elements",
+	}, {
+		name:       "result elements exceeded in functions",
+		expression: "map(&[@, @, @], @)",
+		data:       []any{1.0, 2.0},
+		opts:       []Option{WithMaxResultElements(5)},
+		wantBudget: "result elements",
+	}, {
+		name:       "result elements exceeded in discarded left hand side",
+		expression: "map(&[@, @, @], @)[].foo",
+		data:       []any{1.0, 2.0},
+		opts:       []Option{WithMaxResultElements(5)},
+		wantBudget: "result elements",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := assert.New(t)
+			parser := parsing.NewParser()
+			ast, err := parser.Parse(tt.expression)
+			assert.Nil(err)
+			intr := NewInterpreter(nil, nil)
+			got, err := intr.Execute(ast, tt.data, tt.opts...)
+			if tt.wantBudget != "" {
+				var budgetErr *BudgetExceededError
+				if assert.True(errors.As(err, &budgetErr)) {
+					assert.Equal(tt.wantBudget, budgetErr.Budget)
+				}
+				assert.Nil(got)
+			} else {
+				assert.Nil(err)
+				assert.Equal(tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBudgetIsResetBetweenEvaluations(t *testing.T) {
+	assert := assert.New(t)
+	parser := parsing.NewParser()
+	ast, err := parser.Parse("foo.bar")
+	assert.Nil(err)
+	data := map[string]any{"foo": map[string]any{"bar": "baz"}}
+	intr := NewInterpreter(nil, nil)
+	for i := 0; i < 3; i++ {
+		got, err := intr.Execute(ast, data, WithMaxSteps(3))
+		assert.Nil(err)
+		assert.Equal("baz", got)
+	}
+}
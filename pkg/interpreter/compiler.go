@@ -0,0 +1,289 @@
+package interpreter
+
+import (
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+)
+
+type opcode int
+
+const (
+	opPop opcode = iota
+	opDup
+	opSwap
+	opLiteral
+	opRoot
+	opVariable
+	opField
+	opIndex
+	opSlice
+	opFlatten
+	opNot
+	opUnary
+	opArithmetic
+	opCompare
+	opJumpIfNull
+	opOr
+	opAnd
+	opList
+	opHash
+	opExpRef
+	opCall
+	opProjectArray
+	opProjectArrayOrString
+	opProjectValues
+	opNext
+	opCollect
+	opEndProjection
+	opJump
+	opJumpIfFalse
+	opTry
+	opEndTry
+	opBind
+	opUnbind
+	opEnter
+	opExit
+	opFail
+)
+
+// instruction is a single step of a program. The code of every node replaces the
+// value on top of the stack, the current node, with the result of the node.
+type instruction struct {
+	op opcode
+	// arg is the jump target, the number of operands or the index.
+	arg int
+	// value is the literal, the operator or the error of the instruction.
+	value any
+	// name is the field, variable or function name.
+	name string
+	// names are the keys of a multi-select hash or the names of let bindings.
+	names []string
+	// parts are the parts of a slice.
+	parts []*int
+	// code is the program of an expression reference.
+	code []instruction
+	// args are the arguments of a function call, errors are located on them.
+	args []parsing.ASTNode
+	// node is the node entered or exited, or else the node errors are located on.
+	node *parsing.ASTNode
+}
+
+// Program is an AST compiled into a flat sequence of instructions that a virtual
+// machine runs without walking the tree. A Program is safe for concurrent use.
+type Program struct {
+	code []instruction
+	// accounted also checks the context and accounts for the budget of every node,
+	// it is run when a budget or a tracer is configured.
+	accounted []instruction
+}
+
+// CompileProgram compiles an AST into a program for the virtual machine.
+func CompileProgram(node parsing.ASTNode) *Program {
+	return &Program{
+		code:      compile(node, false),
+		accounted: compile(node, true),
+	}
+}
+
+type compiler struct {
+	accounted bool
+	code      []instruction
+	// locator is the innermost node having a location, errors are located on it.
+	locator *parsing.ASTNode
+}
+
+func compile(node parsing.ASTNode, accounted bool) []instruction {
+	c := compiler{accounted: accounted}
+	c.compile(node)
+	return c.code
+}
+
+func (c *compiler) emit(ins instruction) int {
+	if ins.node == nil {
+		ins.node = c.locator
+	}
+	c.code = append(c.code, ins)
+	return len(c.code) - 1
+}
+
+// patch makes the jump of the instruction at the given position target the next instruction.
+func (c *compiler) patch(at int) {
+	c.code[at].arg = len(c.code)
+}
+
+func (c *compiler) compile(node parsing.ASTNode) {
+	locator := c.locator
+	defer func() {
+		c.locator = locator
+	}()
+	if node.End > node.Start {
+		c.locator = &node
+	}
+	if c.accounted {
+		c.emit(instruction{op: opEnter, node: &node})
+	}
+	c.compileNode(node)
+	if c.accounted {
+		c.emit(instruction{op: opExit, node: &node})
+	}
+}
+
+func (c *compiler) compileNode(node parsing.ASTNode) {
+	switch node.NodeType {
+	case parsing.ASTArithmeticUnaryExpression:
+		c.compile(node.Children[0])
+		if _, ok := unaryArithmetic(node.Value, 0.0, false); ok {
+			c.emit(instruction{op: opUnary, value: node.Value})
+		} else {
+			c.fail(node)
+		}
+	case parsing.ASTArithmeticExpression:
+		c.compileOperands(node.Children...)
+		if _, ok := arithmetic(node.Value, 0.0, 0.0, false); ok {
+			c.emit(instruction{op: opArithmetic, value: node.Value})
+		} else {
+			c.fail(node)
+		}
+	case parsing.ASTComparator:
+		c.compileOperands(node.Children...)
+		if _, ok := compare(node.Value, 0.0, 0.0, false); ok {
+			c.emit(instruction{op: opCompare, value: node.Value})
+		} else {
+			c.fail(node)
+		}
+	case parsing.ASTExpRef:
+		c.emit(instruction{op: opExpRef, code: compile(node.Children[0], c.accounted)})
+	case parsing.ASTFunctionExpression:
+		c.compileOperands(node.Children...)
+		c.emit(instruction{op: opCall, arg: len(node.Children), name: node.Value.(string), args: node.Children})
+	case parsing.ASTField:
+		c.emit(instruction{op: opField, name: node.Value.(string)})
+	case parsing.ASTFilterProjection:
+		try := c.emit(instruction{op: opTry})
+		c.compile(node.Children[0])
+		c.emit(instruction{op: opEndTry})
+		project := c.emit(instruction{op: opProjectArray})
+		loop := c.emit(instruction{op: opNext})
+		c.emit(instruction{op: opDup})
+		c.compile(node.Children[2])
+		skip := c.emit(instruction{op: opJumpIfFalse})
+		c.compile(node.Children[1])
+		c.emit(instruction{op: opCollect})
+		c.emit(instruction{op: opJump, arg: loop})
+		c.patch(skip)
+		c.emit(instruction{op: opPop})
+		c.emit(instruction{op: opJump, arg: loop})
+		c.patch(loop)
+		c.emit(instruction{op: opEndProjection})
+		c.patch(try)
+		c.patch(project)
+	case parsing.ASTFlatten:
+		try := c.emit(instruction{op: opTry})
+		c.compile(node.Children[0])
+		c.emit(instruction{op: opEndTry})
+		c.emit(instruction{op: opFlatten})
+		c.patch(try)
+	case parsing.ASTIdentity, parsing.ASTCurrentNode:
+		// the current node is already on top of the stack
+	case parsing.ASTRootNode:
+		c.emit(instruction{op: opRoot})
+	case parsing.ASTBindings:
+		names := make([]string, 0, len(node.Children))
+		for _, child := range node.Children {
+			c.compileOperands(child.Children[1])
+			names = append(names, child.Children[0].Value.(string))
+		}
+		c.emit(instruction{op: opBind, names: names})
+	case parsing.ASTLetExpression:
+		c.compile(node.Children[0])
+		c.compile(node.Children[1])
+		c.emit(instruction{op: opUnbind})
+	case parsing.ASTVariable:
+		c.emit(instruction{op: opVariable, name: node.Value.(string)})
+	case parsing.ASTIndex:
+		c.emit(instruction{op: opIndex, arg: node.Value.(int)})
+	case parsing.ASTKeyValPair:
+		c.compile(node.Children[0])
+	case parsing.ASTLiteral:
+		c.emit(instruction{op: opLiteral, value: node.Value})
+	case parsing.ASTMultiSelectHash:
+		c.compileOperands(node.Children...)
+		keys := make([]string, 0, len(node.Children))
+		for _, child := range node.Children {
+			keys = append(keys, child.Value.(string))
+		}
+		c.emit(instruction{op: opHash, arg: len(keys), names: keys})
+	case parsing.ASTMultiSelectList:
+		c.compileOperands(node.Children...)
+		c.emit(instruction{op: opList, arg: len(node.Children)})
+	case parsing.ASTOrExpression, parsing.ASTAndExpression:
+		op := opOr
+		if node.NodeType == parsing.ASTAndExpression {
+			op = opAnd
+		}
+		c.emit(instruction{op: opDup})
+		c.compile(node.Children[0])
+		end := c.emit(instruction{op: op})
+		c.compile(node.Children[1])
+		c.patch(end)
+	case parsing.ASTNotExpression:
+		c.compile(node.Children[0])
+		c.emit(instruction{op: opNot})
+	case parsing.ASTPipe:
+		for _, child := range node.Children {
+			c.compile(child)
+		}
+	case parsing.ASTProjection:
+		// see the tree interpreter for when strings are projected
+		project := opProjectArray
+		if first := node.Children[0]; first.NodeType == parsing.ASTIndexExpression {
+			if len(first.Children) > 1 && first.Children[1].NodeType == parsing.ASTSlice {
+				project = opProjectArrayOrString
+			}
+		}
+		c.compile(node.Children[0])
+		c.compileProjection(project, node.Children[1])
+	case parsing.ASTSubexpression, parsing.ASTIndexExpression:
+		c.compile(node.Children[0])
+		end := c.emit(instruction{op: opJumpIfNull})
+		c.compile(node.Children[1])
+		c.patch(end)
+	case parsing.ASTSlice:
+		c.emit(instruction{op: opSlice, parts: node.Value.([]*int)})
+	case parsing.ASTValueProjection:
+		try := c.emit(instruction{op: opTry})
+		c.compile(node.Children[0])
+		c.emit(instruction{op: opEndTry})
+		c.compileProjection(opProjectValues, node.Children[1])
+		c.patch(try)
+	default:
+		c.fail(node)
+	}
+}
+
+// compileOperands compiles nodes evaluated against the current node, their results
+// are left on the stack below the current node.
+func (c *compiler) compileOperands(nodes ...parsing.ASTNode) {
+	for _, node := range nodes {
+		c.emit(instruction{op: opDup})
+		c.compile(node)
+		c.emit(instruction{op: opSwap})
+	}
+}
+
+// compileProjection compiles a loop evaluating a node against the elements of
+// the value on top of the stack.
+func (c *compiler) compileProjection(op opcode, node parsing.ASTNode) {
+	project := c.emit(instruction{op: op})
+	loop := c.emit(instruction{op: opNext})
+	c.compile(node)
+	c.emit(instruction{op: opCollect})
+	c.emit(instruction{op: opJump, arg: loop})
+	c.patch(loop)
+	c.emit(instruction{op: opEndProjection})
+	c.patch(project)
+}
+
+func (c *compiler) fail(node parsing.ASTNode) {
+	c.emit(instruction{op: opFail, value: jperror.New(jperror.Unsupported, "Unknown AST node: "+node.NodeType.String())})
+}
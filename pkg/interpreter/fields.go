@@ -0,0 +1,235 @@
+package interpreter
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// structField describes how to reach a field of a struct, fields are resolved
+// the way encoding/json resolves them.
+type structField struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+}
+
+type structFields struct {
+	byName       map[string]*structField
+	byFoldedName map[string]*structField
+}
+
+// fieldCache maps struct types to their *structFields.
+var fieldCache sync.Map
+
+func cachedFields(t reflect.Type) *structFields {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.(*structFields)
+	}
+	fields, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return fields.(*structFields)
+}
+
+// extractStructField returns the value of the field with the given name, matching
+// the field names exactly first and then case-insensitively. Fields that
+// encoding/json would omit evaluate to nil.
+func extractStructField(value reflect.Value, name string) any {
+	fields := cachedFields(value.Type())
+	field, ok := fields.byName[name]
+	if !ok {
+		if field, ok = fields.byFoldedName[strings.ToLower(name)]; !ok {
+			return nil
+		}
+	}
+	for _, i := range field.index {
+		if value.Kind() == reflect.Ptr {
+			// the field is promoted through a nil embedded pointer
+			if value.IsNil() {
+				return nil
+			}
+			value = value.Elem()
+		}
+		value = value.Field(i)
+	}
+	if field.omitEmpty && isEmptyValue(value) {
+		return nil
+	}
+	return value.Interface()
+}
+
+// typeFields returns the fields of a struct type following the rules of encoding/json:
+// names come from the json tags, fields of embedded structs are promoted, fields
+// tagged "-" are ignored and conflicting fields at the same depth cancel each other.
+func typeFields(t reflect.Type) *structFields {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var fields []structField
+	var current []embedded
+	next := []embedded{{typ: t}}
+	// count and nextCount record the number of times a type is embedded at a depth
+	var count map[reflect.Type]int
+	nextCount := map[reflect.Type]int{}
+	visited := map[reflect.Type]bool{}
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				if sf.Anonymous {
+					t := sf.Type
+					if t.Kind() == reflect.Ptr {
+						t = t.Elem()
+					}
+					if !sf.IsExported() && t.Kind() != reflect.Struct {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, options, _ := strings.Cut(tag, ",")
+				if !isValidTag(name) {
+					name = ""
+				}
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					field := structField{
+						name:      name,
+						index:     index,
+						tagged:    name != "",
+						omitEmpty: hasOption(options, "omitempty"),
+					}
+					if field.name == "" {
+						field.name = sf.Name
+					}
+					fields = append(fields, field)
+					if count[e.typ] > 1 {
+						// the type is embedded several times at this depth,
+						// add a duplicate so that the field is canceled below
+						fields = append(fields, field)
+					}
+					continue
+				}
+				nextCount[ft]++
+				if nextCount[ft] == 1 {
+					next = append(next, embedded{typ: ft, index: index})
+				}
+			}
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		x, y := fields[i], fields[j]
+		if x.name != y.name {
+			return x.name < y.name
+		}
+		if len(x.index) != len(y.index) {
+			return len(x.index) < len(y.index)
+		}
+		if x.tagged != y.tagged {
+			return x.tagged
+		}
+		return lessIndex(x.index, y.index)
+	})
+	// keep the dominant field of each name, the shallowest one, preferring tagged fields
+	dominant := fields[:0]
+	for i, advance := 0, 0; i < len(fields); i += advance {
+		for advance = 1; i+advance < len(fields); advance++ {
+			if fields[i+advance].name != fields[i].name {
+				break
+			}
+		}
+		if advance > 1 && len(fields[i].index) == len(fields[i+1].index) && fields[i].tagged == fields[i+1].tagged {
+			continue
+		}
+		dominant = append(dominant, fields[i])
+	}
+	fields = dominant
+	sort.Slice(fields, func(i, j int) bool {
+		return lessIndex(fields[i].index, fields[j].index)
+	})
+	result := &structFields{
+		byName:       make(map[string]*structField, len(fields)),
+		byFoldedName: make(map[string]*structField, len(fields)),
+	}
+	for i := range fields {
+		field := &fields[i]
+		result.byName[field.name] = field
+		folded := strings.ToLower(field.name)
+		if _, ok := result.byFoldedName[folded]; !ok {
+			result.byFoldedName[folded] = field
+		}
+	}
+	return result
+}
+
+func lessIndex(x, y []int) bool {
+	for i := range x {
+		if i >= len(y) {
+			return false
+		}
+		if x[i] != y[i] {
+			return x[i] < y[i]
+		}
+	}
+	return len(x) < len(y)
+}
+
+func hasOption(options string, option string) bool {
+	for options != "" {
+		var current string
+		current, options, _ = strings.Cut(options, ",")
+		if current == option {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidTag reports whether a json tag name is used by encoding/json.
+func isValidTag(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
+
+// isEmptyValue reports whether encoding/json omits a value tagged omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Ptr:
+		return v.IsZero()
+	}
+	return false
+}
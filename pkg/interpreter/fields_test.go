@@ -0,0 +1,96 @@
+package interpreter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type taggedBase struct {
+	Kind string `json:"kind"`
+	Name string
+}
+
+type taggedAudit struct {
+	CreatedBy string `json:"created_by"`
+}
+
+type taggedUser struct {
+	ID      int    `json:"user_id"`
+	Name    string `json:",omitempty"`
+	Email   string `json:"email,omitempty"`
+	Secret  string `json:"-"`
+	Dash    string `json:"-,"`
+	private string
+	taggedBase
+	*taggedAudit
+}
+
+type conflictA struct {
+	X int
+	Y int
+}
+
+type conflictB struct {
+	X int
+	Y int `json:"Y"`
+}
+
+type conflicting struct {
+	conflictA
+	conflictB
+}
+
+func TestStructFieldResolution(t *testing.T) {
+	user := taggedUser{
+		ID:          42,
+		Name:        "alice",
+		Secret:      "secret",
+		Dash:        "dash",
+		private:     "private",
+		taggedBase:  taggedBase{Kind: "admin", Name: "base"},
+		taggedAudit: &taggedAudit{CreatedBy: "bob"},
+	}
+	tests := []struct {
+		expression string
+		data       any
+		want       any
+	}{
+		{"user_id", user, 42},
+		{"USER_ID", user, 42},
+		{"ID", user, nil},
+		{"Name", user, "alice"},
+		{"name", user, "alice"},
+		{"name", taggedUser{}, nil},
+		{"email", user, nil},
+		{"Secret", user, nil},
+		{`"-"`, user, "dash"},
+		{"private", user, nil},
+		{"kind", user, "admin"},
+		{"Kind", user, "admin"},
+		{"taggedBase", user, nil},
+		{"created_by", user, "bob"},
+		{"created_by", taggedUser{}, nil},
+		{"created_by", &user, "bob"},
+		{"X", conflicting{}, nil},
+		{"Y", conflicting{conflictA{Y: 1}, conflictB{Y: 2}}, 2},
+		{"[*].user_id", []taggedUser{user, {ID: 7}}, []any{42, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			result, err := search(t, tt.expression, tt.data)
+			assert.NoError(err)
+			assert.Equal(tt.want, result)
+		})
+	}
+}
+
+func TestStructFieldsAreCached(t *testing.T) {
+	assert := assert.New(t)
+	first := cachedFields(reflect.TypeOf(taggedUser{}))
+	assert.Same(first, cachedFields(reflect.TypeOf(taggedUser{})))
+	assert.Contains(first.byName, "user_id")
+	assert.Contains(first.byFoldedName, "created_by")
+}
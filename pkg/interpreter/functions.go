@@ -1,7 +1,7 @@
 package interpreter
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 
 	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
@@ -20,9 +20,27 @@ type functionEntry struct {
 
 type functionCaller struct {
 	functionTable map[string]functionEntry
+	precise       bool
 }
 
 func NewFunctionCaller(funcs ...functions.FunctionEntry) *functionCaller {
+	return newFunctionCaller(false, funcs...)
+}
+
+// NewFunctionCallerFromRegistry returns a function caller calling the functions of a
+// registry, see functions.Registry.
+func NewFunctionCallerFromRegistry(registry *functions.Registry) *functionCaller {
+	return NewFunctionCaller(registry.List()...)
+}
+
+// NewPreciseFunctionCaller is like NewFunctionCaller but numbers are passed to the
+// handlers as json.Number instead of float64, and numbers returned as float64 are
+// converted to json.Number, see functions.GetPreciseFunctions.
+func NewPreciseFunctionCaller(funcs ...functions.FunctionEntry) *functionCaller {
+	return newFunctionCaller(true, funcs...)
+}
+
+func newFunctionCaller(precise bool, funcs ...functions.FunctionEntry) *functionCaller {
 	fTable := map[string]functionEntry{}
 	for _, f := range funcs {
 		fTable[f.Name] = functionEntry{
@@ -32,32 +50,37 @@ func NewFunctionCaller(funcs ...functions.FunctionEntry) *functionCaller {
 	}
 	return &functionCaller{
 		functionTable: fTable,
+		precise:       precise,
 	}
 }
 
-func resolveArgs(name string, function functionEntry, arguments []any) ([]any, error) {
+func resolveArgs(name string, function functionEntry, arguments []any, precise bool) ([]any, error) {
 	if len(function.arguments) == 0 {
 		return arguments, nil
 	}
 
-	variadic := isVariadic(function.arguments)
-	minExpected := getMinExpected(function.arguments)
-	maxExpected, hasMax := getMaxExpected(function.arguments)
-	count := len(arguments)
-
-	if count < minExpected {
-		return nil, jperror.NotEnoughArgumentsSupplied(name, count, minExpected, variadic)
+	if err := checkArity(name, function.arguments, len(arguments)); err != nil {
+		return nil, err
 	}
 
-	if hasMax && count > maxExpected {
-		return nil, jperror.TooManyArgumentsSupplied(name, count, maxExpected)
+	// arguments are copied only when one of them is converted
+	resolved, copied := arguments, false
+	resolve := func(i int, spec functions.ArgSpec) error {
+		arg, converted, err := typeCheck(name, i, spec, arguments[i], precise)
+		if err != nil {
+			return err
+		}
+		if converted {
+			if !copied {
+				resolved, copied = append([]any(nil), arguments...), true
+			}
+			resolved[i] = arg
+		}
+		return nil
 	}
-
 	for i, spec := range function.arguments {
 		if !spec.Optional || i <= len(arguments)-1 {
-			userArg := arguments[i]
-			err := typeCheck(spec, userArg)
-			if err != nil {
+			if err := resolve(i, spec); err != nil {
 				return nil, err
 			}
 		}
@@ -66,14 +89,27 @@ func resolveArgs(name string, function functionEntry, arguments []any) ([]any, e
 	lastArg := function.arguments[lastIndex]
 	if lastArg.Variadic {
 		for i := len(function.arguments) - 1; i < len(arguments); i++ {
-			userArg := arguments[i]
-			err := typeCheck(lastArg, userArg)
-			if err != nil {
+			if err := resolve(i, lastArg); err != nil {
 				return nil, err
 			}
 		}
 	}
-	return arguments, nil
+	return resolved, nil
+}
+
+func checkArity(name string, arguments []functions.ArgSpec, count int) error {
+	if len(arguments) == 0 {
+		return nil
+	}
+	minExpected := getMinExpected(arguments)
+	if count < minExpected {
+		return jperror.NotEnoughArgumentsSupplied(name, count, minExpected, isVariadic(arguments))
+	}
+	maxExpected, hasMax := getMaxExpected(arguments)
+	if hasMax && count > maxExpected {
+		return jperror.TooManyArgumentsSupplied(name, count, maxExpected)
+	}
+	return nil
 }
 
 func isVariadic(arguments []functions.ArgSpec) bool {
@@ -102,58 +138,145 @@ func getMaxExpected(arguments []functions.ArgSpec) (int, bool) {
 	return len(arguments), true
 }
 
-func typeCheck(a functions.ArgSpec, arg any) error {
+// typeCheck checks an argument against its specification and returns it normalized
+// for the function handlers: numbers are converted to float64, or json.Number when
+// precise, arrays to []any and Go maps to map[string]any. It reports whether the argument was converted.
+func typeCheck(name string, index int, a functions.ArgSpec, arg any, precise bool) (any, bool, error) {
 	for _, t := range a.Types {
 		switch t {
 		case functions.JpNumber:
-			if _, ok := arg.(float64); ok {
-				return nil
+			if _, ok := arg.(float64); ok && !precise {
+				return arg, false, nil
+			}
+			if _, ok := arg.(json.Number); ok && precise {
+				return arg, false, nil
+			}
+			if num, ok := toNumber(arg, precise); ok {
+				return num, true, nil
 			}
 		case functions.JpString:
 			if _, ok := arg.(string); ok {
-				return nil
+				return arg, false, nil
 			}
 		case functions.JpArray:
-			if util.IsSliceType(arg) {
-				return nil
+			if _, ok := arg.([]any); ok {
+				return arg, false, nil
+			}
+			if array, ok := util.ToArray(arg); ok {
+				return array, true, nil
 			}
 		case functions.JpObject:
-			if _, ok := arg.(map[string]any); ok {
-				return nil
+			if util.IsObject(arg) {
+				return arg, false, nil
+			}
+			if object, ok := util.ToObject(arg); ok {
+				return object, true, nil
 			}
 		case functions.JpArrayArray:
 			if util.IsSliceType(arg) {
 				if _, ok := arg.([]any); ok {
-					return nil
+					return arg, false, nil
 				}
 			}
 		case functions.JpArrayNumber:
-			if _, ok := util.ToArrayNum(arg); ok {
-				return nil
+			if array, converted, ok := toArrayNum(arg, precise); ok {
+				return array, converted, nil
 			}
 		case functions.JpArrayString:
-			if _, ok := util.ToArrayStr(arg); ok {
-				return nil
+			if array, converted, ok := toArrayStr(arg); ok {
+				return array, converted, nil
 			}
 		case functions.JpAny:
-			return nil
+			return arg, false, nil
 		case functions.JpExpref:
 			if _, ok := arg.(functions.ExpRef); ok {
-				return nil
+				return arg, false, nil
 			}
 		}
 	}
-	return fmt.Errorf("invalid type for: %v, expected: %#v", arg, a.Types)
+	return nil, false, jperror.InvalidTypeArgument(name, index, fmt.Sprintf("invalid type for: %v, expected: %#v", arg, a.Types))
+}
+
+// toNumber converts a number to a float64, or to a json.Number when precise.
+// Infinities and NaN are always converted to float64.
+func toNumber(arg any, precise bool) (any, bool) {
+	if precise {
+		if num, ok := util.ToJSONNumber(arg); ok {
+			return num, true
+		}
+	}
+	return util.ToNumber(arg)
+}
+
+// toArrayNum converts an array of numbers to an array of float64, or json.Number
+// when precise, and reports whether it was converted. Arrays that only contain
+// such numbers are returned as is.
+func toArrayNum(arg any, precise bool) (any, bool, bool) {
+	array, ok := util.ToArray(arg)
+	if !ok {
+		return nil, false, false
+	}
+	_, converted := arg.([]any)
+	converted = !converted
+	copied := converted
+	for i, item := range array {
+		if _, ok := item.(float64); ok && !precise {
+			continue
+		}
+		if _, ok := item.(json.Number); ok && precise {
+			continue
+		}
+		num, ok := toNumber(item, precise)
+		if !ok {
+			return nil, false, false
+		}
+		if !copied {
+			array = append([]any(nil), array...)
+			copied = true
+		}
+		array[i] = num
+	}
+	if !copied {
+		return arg, false, true
+	}
+	return array, true, true
+}
+
+// toArrayStr converts an array of strings to an array of empty interfaces
+// and reports whether it was converted.
+func toArrayStr(arg any) (any, bool, bool) {
+	array, ok := util.ToArray(arg)
+	if !ok {
+		return nil, false, false
+	}
+	for _, item := range array {
+		if _, ok := item.(string); !ok {
+			return nil, false, false
+		}
+	}
+	if _, ok := arg.([]any); ok {
+		return arg, false, true
+	}
+	return array, true, true
 }
 
 func (f *functionCaller) CallFunction(name string, arguments []any) (any, error) {
 	entry, ok := f.functionTable[name]
 	if !ok {
-		return nil, errors.New("unknown function: " + name)
+		return nil, jperror.UnknownFunctionCalled(name)
 	}
-	resolvedArgs, err := resolveArgs(name, entry, arguments)
+	resolvedArgs, err := resolveArgs(name, entry, arguments, f.precise)
 	if err != nil {
 		return nil, err
 	}
-	return entry.handler(resolvedArgs)
+	result, err := entry.handler(resolvedArgs)
+	if err != nil || !f.precise {
+		return result, err
+	}
+	if num, ok := result.(float64); ok {
+		if exact, ok := util.ToJSONNumber(num); ok {
+			return exact, nil
+		}
+	}
+	return result, nil
 }
@@ -1,19 +1,27 @@
 package interpreter
 
 import (
+	"context"
 	"errors"
-	"math"
 	"reflect"
-	"unicode"
-	"unicode/utf8"
 
 	"github.com/jmespath-community/go-jmespath/pkg/binding"
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
 	"github.com/jmespath-community/go-jmespath/pkg/functions"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
 	"github.com/jmespath-community/go-jmespath/pkg/parsing"
 	"github.com/jmespath-community/go-jmespath/pkg/util"
 )
 
-var DefaultFunctionCaller FunctionCaller = NewFunctionCaller(functions.GetDefaultFunctions()...)
+var DefaultFunctionCaller FunctionCaller = NewFunctionCallerFromRegistry(functions.DefaultRegistry())
+
+// PreciseFunctionCaller is the function caller used with arbitrary precision, see WithArbitraryPrecision.
+var PreciseFunctionCaller FunctionCaller = NewPreciseFunctionCaller(functions.GetPreciseFunctions()...)
+
+// OrderedFunctionCaller is the function caller used with ordered objects, see WithOrderedObjects.
+var OrderedFunctionCaller FunctionCaller = NewFunctionCaller(functions.OrderedFunctions(functions.GetDefaultFunctions()...)...)
+
+var preciseOrderedFunctionCaller FunctionCaller = NewPreciseFunctionCaller(functions.OrderedFunctions(functions.GetPreciseFunctions()...)...)
 
 /*
 Interpreter is a tree based interpreter.
@@ -25,8 +33,17 @@ type Interpreter interface {
 }
 
 type treeInterpreter struct {
-	root     any
-	bindings binding.Bindings
+	root              any
+	bindings          binding.Bindings
+	budget            *budget
+	precise           bool
+	parallelism       int
+	parallelThreshold int
+	sorted            bool
+	ordered           bool
+	tracer            Tracer
+	// instrumented is set when nodes check a context, a budget or a tracer.
+	instrumented bool
 }
 
 func NewInterpreter(data any, bindings binding.Bindings) Interpreter {
@@ -43,139 +60,178 @@ func NewInterpreter(data any, bindings binding.Bindings) Interpreter {
 // It will produce the result of applying the JMESPath expression associated
 // with the ASTNode to the input data "value".
 func (intr *treeInterpreter) Execute(node parsing.ASTNode, value any, opts ...Option) (any, error) {
-	var o Options
-	for _, opt := range opts {
-		if opt != nil {
-			o = opt(o)
+	o := newOptions(opts...)
+	ctx := o.context()
+	functionCaller := o.functionCaller()
+	if o.Bindings != nil {
+		bindings := intr.bindings
+		intr.bindings = o.Bindings
+		defer func() {
+			intr.bindings = bindings
+		}()
+	}
+	intr.budget = newBudget(o)
+	intr.precise = o.ArbitraryPrecision
+	intr.parallelism = o.Parallelism
+	intr.parallelThreshold = o.ParallelThreshold
+	intr.sorted = o.DeterministicOrder
+	intr.ordered = o.OrderedObjects
+	intr.tracer = o.Tracer
+	// contexts that are never done, like the background context, have no Done channel
+	intr.instrumented = intr.budget != nil || intr.tracer != nil || ctx.Done() != nil
+	result, err := intr.execute(ctx, node, value, functionCaller)
+	if err != nil {
+		return nil, err
+	}
+	// some nodes discard errors from their left hand side,
+	// make sure cancellation is never silently ignored
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (intr *treeInterpreter) execute(ctx context.Context, node parsing.ASTNode, value any, functionCaller FunctionCaller) (any, error) {
+	if !intr.instrumented {
+		result, err := intr.evaluate(ctx, node, value, functionCaller)
+		if err != nil {
+			return nil, locate(err, node)
 		}
+		return result, nil
 	}
-	functionCaller := o.FunctionCaller
-	if functionCaller == nil {
-		functionCaller = DefaultFunctionCaller
+	if intr.tracer == nil {
+		return intr.executeNode(ctx, node, value, functionCaller)
 	}
-	return intr.execute(node, value, functionCaller)
+	intr.tracer.Enter(node, value)
+	result, err := intr.executeNode(ctx, node, value, functionCaller)
+	intr.tracer.Exit(node, value, result, err)
+	return result, err
 }
 
-func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCaller FunctionCaller) (any, error) {
+// executeNode checks the context and the budget, and evaluates a node.
+func (intr *treeInterpreter) executeNode(ctx context.Context, node parsing.ASTNode, value any, functionCaller FunctionCaller) (any, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	if intr.budget == nil {
+		result, err := intr.evaluate(ctx, node, value, functionCaller)
+		if err != nil {
+			return nil, locate(err, node)
+		}
+		return result, nil
+	}
+	if err := intr.budget.enter(); err != nil {
+		return nil, err
+	}
+	defer intr.budget.exit()
+	result, err := intr.evaluate(ctx, node, value, functionCaller)
+	if err != nil {
+		return nil, locate(err, node)
+	}
+	if err := intr.budget.produced(node, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (intr *treeInterpreter) evaluate(ctx context.Context, node parsing.ASTNode, value any, functionCaller FunctionCaller) (any, error) {
 	switch node.NodeType {
 	case parsing.ASTArithmeticUnaryExpression:
-		expr, err := intr.execute(node.Children[0], value, functionCaller)
+		expr, err := intr.execute(ctx, node.Children[0], value, functionCaller)
 		if err != nil {
 			return nil, err
 		}
-		num, ok := util.ToNumber(expr)
-		if !ok {
-			return nil, nil
-		}
-		switch node.Value {
-		case parsing.TOKPlus:
-			return num, nil
-		case parsing.TOKMinus:
-			return -num, nil
+		if result, ok := unaryArithmetic(node.Value, expr, intr.precise); ok {
+			return result, nil
 		}
 	case parsing.ASTArithmeticExpression:
-		left, err := intr.execute(node.Children[0], value, functionCaller)
+		left, err := intr.execute(ctx, node.Children[0], value, functionCaller)
 		if err != nil {
 			return nil, err
 		}
-		right, err := intr.execute(node.Children[1], value, functionCaller)
+		right, err := intr.execute(ctx, node.Children[1], value, functionCaller)
 		if err != nil {
 			return nil, err
 		}
-		leftNum, ok := util.ToNumber(left)
-		if !ok {
-			return nil, nil
-		}
-		rightNum, ok := util.ToNumber(right)
-		if !ok {
-			return nil, nil
-		}
-		switch node.Value {
-		case parsing.TOKPlus:
-			return leftNum + rightNum, nil
-		case parsing.TOKMinus:
-			return leftNum - rightNum, nil
-		case parsing.TOKStar:
-			return leftNum * rightNum, nil
-		case parsing.TOKMultiply:
-			return leftNum * rightNum, nil
-		case parsing.TOKDivide:
-			return leftNum / rightNum, nil
-		case parsing.TOKModulo:
-			return math.Mod(leftNum, rightNum), nil
-		case parsing.TOKDiv:
-			return math.Floor(leftNum / rightNum), nil
+		if result, ok := arithmetic(node.Value, left, right, intr.precise); ok {
+			return result, nil
 		}
 	case parsing.ASTComparator:
-		left, err := intr.execute(node.Children[0], value, functionCaller)
+		left, err := intr.execute(ctx, node.Children[0], value, functionCaller)
 		if err != nil {
 			return nil, err
 		}
-		right, err := intr.execute(node.Children[1], value, functionCaller)
+		right, err := intr.execute(ctx, node.Children[1], value, functionCaller)
 		if err != nil {
 			return nil, err
 		}
-		switch node.Value {
-		case parsing.TOKEQ:
-			return util.ObjsEqual(left, right), nil
-		case parsing.TOKNE:
-			return !util.ObjsEqual(left, right), nil
-		}
-		leftNum, ok := util.ToNumber(left)
-		if !ok {
-			return nil, nil
-		}
-		rightNum, ok := util.ToNumber(right)
-		if !ok {
-			return nil, nil
-		}
-		switch node.Value {
-		case parsing.TOKGT:
-			return leftNum > rightNum, nil
-		case parsing.TOKGTE:
-			return leftNum >= rightNum, nil
-		case parsing.TOKLT:
-			return leftNum < rightNum, nil
-		case parsing.TOKLTE:
-			return leftNum <= rightNum, nil
+		if result, ok := compare(node.Value, left, right, intr.precise); ok {
+			return result, nil
 		}
 	case parsing.ASTExpRef:
 		return func(data any) (any, error) {
-			return intr.execute(node.Children[0], data, functionCaller)
+			return intr.execute(ctx, node.Children[0], data, functionCaller)
 		}, nil
 	case parsing.ASTFunctionExpression:
 		resolvedArgs := []any{}
 		for _, arg := range node.Children {
-			current, err := intr.execute(arg, value, functionCaller)
+			current, err := intr.execute(ctx, arg, value, functionCaller)
 			if err != nil {
 				return nil, err
 			}
 			resolvedArgs = append(resolvedArgs, current)
 		}
-		return functionCaller.CallFunction(node.Value.(string), resolvedArgs)
+		if elements, ok := intr.parallelMap(node, resolvedArgs, functionCaller); ok {
+			expression := node.Children[0].Children[0]
+			if intr.tracer != nil {
+				intr.tracer.EnterFunction(node.Value.(string), resolvedArgs)
+			}
+			result, err := intr.evaluateParallel(elements, func(worker *treeInterpreter, element any) (any, error) {
+				return worker.execute(ctx, expression, element, functionCaller)
+			})
+			if intr.tracer != nil {
+				intr.tracer.ExitFunction(node.Value.(string), resolvedArgs, result, err)
+			}
+			return result, err
+		}
+		result, err := intr.callFunction(functionCaller, node.Value.(string), resolvedArgs)
+		if err != nil {
+			// point at the offending argument when there is one
+			if jpErr, ok := err.(*jperror.Error); ok && jpErr.Argument >= 0 && jpErr.Argument < len(node.Children) {
+				return nil, locate(err, node.Children[jpErr.Argument])
+			}
+			return nil, err
+		}
+		return result, nil
 	case parsing.ASTField:
 		return extractField(value, node.Value.(string))
 	case parsing.ASTFilterProjection:
-		left, err := intr.execute(node.Children[0], value, functionCaller)
+		left, err := intr.execute(ctx, node.Children[0], value, functionCaller)
 		if err != nil {
+			if mustPropagate(ctx, err) {
+				return nil, err
+			}
 			return nil, nil
 		}
 		sliceType, ok := left.([]any)
 		if !ok {
 			if util.IsSliceType(left) {
-				return intr.filterProjectionWithReflection(node, left, functionCaller)
+				return intr.filterProjectionWithReflection(ctx, node, left, functionCaller)
 			}
 			return nil, nil
 		}
 		compareNode := node.Children[2]
+		if intr.parallel(len(sliceType)) {
+			return intr.filterProjectionParallel(ctx, node, sliceType, functionCaller)
+		}
 		collected := []any{}
 		for _, element := range sliceType {
-			result, err := intr.execute(compareNode, element, functionCaller)
+			result, err := intr.execute(ctx, compareNode, element, functionCaller)
 			if err != nil {
 				return nil, err
 			}
 			if !util.IsFalse(result) {
-				current, err := intr.execute(node.Children[1], element, functionCaller)
+				current, err := intr.execute(ctx, node.Children[1], element, functionCaller)
 				if err != nil {
 					return nil, err
 				}
@@ -186,36 +242,14 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 		}
 		return collected, nil
 	case parsing.ASTFlatten:
-		left, err := intr.execute(node.Children[0], value, functionCaller)
+		left, err := intr.execute(ctx, node.Children[0], value, functionCaller)
 		if err != nil {
-			return nil, nil
-		}
-		sliceType, ok := left.([]any)
-		if !ok {
-			// If we can't type convert to []any, there's
-			// a chance this could still work via reflection if we're
-			// dealing with user provided types.
-			if util.IsSliceType(left) {
-				return intr.flattenWithReflection(left)
+			if mustPropagate(ctx, err) {
+				return nil, err
 			}
 			return nil, nil
 		}
-		flattened := []any{}
-		for _, element := range sliceType {
-			if elementSlice, ok := element.([]any); ok {
-				flattened = append(flattened, elementSlice...)
-			} else if util.IsSliceType(element) {
-				reflectFlat := []any{}
-				v := reflect.ValueOf(element)
-				for i := 0; i < v.Len(); i++ {
-					reflectFlat = append(reflectFlat, v.Index(i).Interface())
-				}
-				flattened = append(flattened, reflectFlat...)
-			} else {
-				flattened = append(flattened, element)
-			}
-		}
-		return flattened, nil
+		return flatten(left), nil
 	case parsing.ASTIdentity, parsing.ASTCurrentNode:
 		return value, nil
 	case parsing.ASTRootNode:
@@ -223,7 +257,7 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 	case parsing.ASTBindings:
 		bindings := intr.bindings
 		for _, child := range node.Children {
-			if value, err := intr.execute(child.Children[1], value, functionCaller); err != nil {
+			if value, err := intr.execute(ctx, child.Children[1], value, functionCaller); err != nil {
 				return nil, err
 			} else {
 				bindings = bindings.Register(child.Children[0].Value.(string), binding.NewBinding(value))
@@ -233,20 +267,7 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 		// doesn't mutate value
 		return value, nil
 	case parsing.ASTLetExpression:
-		// save bindings state
-		bindings := intr.bindings
-		// retore bindings state
-		defer func() {
-			intr.bindings = bindings
-		}()
-		// evalute bindings first, then evaluate expression
-		if _, err := intr.execute(node.Children[0], value, functionCaller); err != nil {
-			return nil, err
-		} else if value, err := intr.execute(node.Children[1], value, functionCaller); err != nil {
-			return nil, err
-		} else {
-			return value, nil
-		}
+		return intr.evaluateLet(ctx, node, value, functionCaller)
 	case parsing.ASTVariable:
 		if value, err := binding.Resolve(node.Value.(string), intr.bindings); err != nil {
 			return nil, err
@@ -254,37 +275,26 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 			return value, nil
 		}
 	case parsing.ASTIndex:
-		if sliceType, ok := value.([]any); ok {
-			index := node.Value.(int)
-			if index < 0 {
-				index += len(sliceType)
-			}
-			if index < len(sliceType) && index >= 0 {
-				return sliceType[index], nil
-			}
-			return nil, nil
-		}
-		// Otherwise try via reflection.
-		rv := reflect.ValueOf(value)
-		if rv.Kind() == reflect.Slice {
-			index := node.Value.(int)
-			if index < 0 {
-				index += rv.Len()
-			}
-			if index < rv.Len() && index >= 0 {
-				v := rv.Index(index)
-				return v.Interface(), nil
-			}
-		}
-		return nil, nil
+		return index(value, node.Value.(int)), nil
 	case parsing.ASTKeyValPair:
-		return intr.execute(node.Children[0], value, functionCaller)
+		return intr.execute(ctx, node.Children[0], value, functionCaller)
 	case parsing.ASTLiteral:
 		return node.Value, nil
 	case parsing.ASTMultiSelectHash:
+		if intr.ordered {
+			collected := ordered.NewObject(len(node.Children))
+			for _, child := range node.Children {
+				current, err := intr.execute(ctx, child, value, functionCaller)
+				if err != nil {
+					return nil, err
+				}
+				collected.Set(child.Value.(string), current)
+			}
+			return collected, nil
+		}
 		collected := make(map[string]any)
 		for _, child := range node.Children {
-			current, err := intr.execute(child, value, functionCaller)
+			current, err := intr.execute(ctx, child, value, functionCaller)
 			if err != nil {
 				return nil, err
 			}
@@ -295,7 +305,7 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 	case parsing.ASTMultiSelectList:
 		collected := []any{}
 		for _, child := range node.Children {
-			current, err := intr.execute(child, value, functionCaller)
+			current, err := intr.execute(ctx, child, value, functionCaller)
 			if err != nil {
 				return nil, err
 			}
@@ -303,28 +313,28 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 		}
 		return collected, nil
 	case parsing.ASTOrExpression:
-		matched, err := intr.execute(node.Children[0], value, functionCaller)
+		matched, err := intr.execute(ctx, node.Children[0], value, functionCaller)
 		if err != nil {
 			return nil, err
 		}
 		if util.IsFalse(matched) {
-			matched, err = intr.execute(node.Children[1], value, functionCaller)
+			matched, err = intr.execute(ctx, node.Children[1], value, functionCaller)
 			if err != nil {
 				return nil, err
 			}
 		}
 		return matched, nil
 	case parsing.ASTAndExpression:
-		matched, err := intr.execute(node.Children[0], value, functionCaller)
+		matched, err := intr.execute(ctx, node.Children[0], value, functionCaller)
 		if err != nil {
 			return nil, err
 		}
 		if util.IsFalse(matched) {
 			return matched, nil
 		}
-		return intr.execute(node.Children[1], value, functionCaller)
+		return intr.execute(ctx, node.Children[1], value, functionCaller)
 	case parsing.ASTNotExpression:
-		matched, err := intr.execute(node.Children[0], value, functionCaller)
+		matched, err := intr.execute(ctx, node.Children[0], value, functionCaller)
 		if err != nil {
 			return nil, err
 		}
@@ -336,7 +346,7 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 		result := value
 		var err error
 		for _, child := range node.Children {
-			result, err = intr.execute(child, result, functionCaller)
+			result, err = intr.execute(ctx, child, result, functionCaller)
 			if err != nil {
 				return nil, err
 			}
@@ -362,7 +372,7 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 			}
 		}
 
-		left, err := intr.execute(node.Children[0], value, functionCaller)
+		left, err := intr.execute(ctx, node.Children[0], value, functionCaller)
 		if err != nil {
 			return nil, err
 		}
@@ -370,13 +380,13 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 		sliceType, ok := left.([]any)
 		if !ok {
 			if util.IsSliceType(left) {
-				return intr.projectWithReflection(node, left, functionCaller)
+				return intr.projectWithReflection(ctx, node, left, functionCaller)
 			}
 			stringType, ok := left.(string)
 			if allowString && ok {
 				// a projection is really a sub-expression in disguise
 				// we must evaluate the right hand expression
-				result, err := intr.Execute(node.Children[1], stringType)
+				result, err := intr.execute(ctx, node.Children[1], stringType, functionCaller)
 				if err != nil {
 					return nil, err
 				}
@@ -384,10 +394,13 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 			}
 			return nil, nil
 		}
+		if intr.parallel(len(sliceType)) {
+			return intr.projectParallel(ctx, node, sliceType, functionCaller)
+		}
 		collected := []any{}
 		var current any
 		for _, element := range sliceType {
-			current, err = intr.execute(node.Children[1], element, functionCaller)
+			current, err = intr.execute(ctx, node.Children[1], element, functionCaller)
 			if err != nil {
 				return nil, err
 			}
@@ -397,53 +410,31 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 		}
 		return collected, nil
 	case parsing.ASTSubexpression, parsing.ASTIndexExpression:
-		left, err := intr.execute(node.Children[0], value, functionCaller)
+		left, err := intr.execute(ctx, node.Children[0], value, functionCaller)
 		if err != nil {
 			return nil, err
 		}
 		if left == nil {
 			return nil, nil
 		}
-		return intr.execute(node.Children[1], left, functionCaller)
+		return intr.execute(ctx, node.Children[1], left, functionCaller)
 	case parsing.ASTSlice:
-		parts := node.Value.([]*int)
-		sliceType, ok := value.([]any)
-		if !ok {
-			if util.IsSliceType(value) {
-				return intr.sliceWithReflection(node, value)
-			}
-			// string slices is implemented by slicing
-			// the corresponding array of runes and
-			// converting the result back to a string
-			if stringType, ok := value.(string); ok {
-				runeType := []rune(stringType)
-				sliceParams := util.MakeSliceParams(parts)
-				runes, err := util.Slice(runeType, sliceParams)
-				if err != nil {
-					return nil, nil
-				}
-				return string(runes), nil
-			}
-			return nil, nil
-		}
-		sliceParams := util.MakeSliceParams(parts)
-		return util.Slice(sliceType, sliceParams)
+		return slice(node.Value.([]*int), value)
 	case parsing.ASTValueProjection:
-		left, err := intr.execute(node.Children[0], value, functionCaller)
+		left, err := intr.execute(ctx, node.Children[0], value, functionCaller)
 		if err != nil {
+			if mustPropagate(ctx, err) {
+				return nil, err
+			}
 			return nil, nil
 		}
-		mapType, ok := left.(map[string]any)
+		values, ok := objectValues(left, intr.sorted)
 		if !ok {
 			return nil, nil
 		}
-		values := []any{}
-		for _, value := range mapType {
-			values = append(values, value)
-		}
 		collected := []any{}
 		for _, element := range values {
-			current, err := intr.execute(node.Children[1], element, functionCaller)
+			current, err := intr.execute(ctx, node.Children[1], element, functionCaller)
 			if err != nil {
 				return nil, err
 			}
@@ -453,7 +444,61 @@ func (intr *treeInterpreter) execute(node parsing.ASTNode, value any, functionCa
 		}
 		return collected, nil
 	}
-	return nil, errors.New("Unknown AST node: " + node.NodeType.String())
+	return nil, jperror.New(jperror.Unsupported, "Unknown AST node: "+node.NodeType.String())
+}
+
+// evaluateLet evaluates a let expression. It is kept out of evaluate, whose
+// many returns would all run the deferred restoration of the bindings.
+func (intr *treeInterpreter) evaluateLet(ctx context.Context, node parsing.ASTNode, value any, functionCaller FunctionCaller) (any, error) {
+	// save bindings state
+	bindings := intr.bindings
+	// retore bindings state
+	defer func() {
+		intr.bindings = bindings
+	}()
+	// evalute bindings first, then evaluate expression
+	if _, err := intr.execute(ctx, node.Children[0], value, functionCaller); err != nil {
+		return nil, err
+	} else if value, err := intr.execute(ctx, node.Children[1], value, functionCaller); err != nil {
+		return nil, err
+	} else {
+		return value, nil
+	}
+}
+
+// callFunction calls a function, between the function hooks of the tracer.
+func (intr *treeInterpreter) callFunction(functionCaller FunctionCaller, name string, arguments []any) (any, error) {
+	if intr.tracer == nil {
+		return functionCaller.CallFunction(name, arguments)
+	}
+	intr.tracer.EnterFunction(name, arguments)
+	result, err := functionCaller.CallFunction(name, arguments)
+	intr.tracer.ExitFunction(name, arguments, result, err)
+	return result, err
+}
+
+// locate attaches the span of the node to errors that don't have a location yet.
+func locate(err error, node parsing.ASTNode) error {
+	if jpErr, ok := err.(*jperror.Error); ok && node.End > node.Start {
+		return jpErr.Locate(node.Start, node.End)
+	}
+	return err
+}
+
+// mustPropagate reports whether an error must abort the evaluation
+// even where the interpreter would otherwise discard it.
+func mustPropagate(ctx context.Context, err error) bool {
+	var budgetErr *BudgetExceededError
+	return ctx.Err() != nil || errors.As(err, &budgetErr)
+}
+
+func checkContext(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return nil
+	}
 }
 
 func extractField(value any, field string) (any, error) {
@@ -463,6 +508,10 @@ func extractField(value any, field string) (any, error) {
 	if m, ok := value.(map[string]any); ok {
 		return m[field], nil
 	}
+	if o, ok := value.(*ordered.Object); ok {
+		v, _ := o.Get(field)
+		return v, nil
+	}
 	return extractFieldUsingReflection(reflect.ValueOf(value), field)
 }
 
@@ -473,12 +522,7 @@ func extractFieldUsingReflection(value reflect.Value, field string) (any, error)
 		}
 		return extractFieldUsingReflection(value.Elem(), field)
 	} else if value.Kind() == reflect.Struct {
-		first, n := utf8.DecodeRuneInString(field)
-		fieldName := string(unicode.ToUpper(first)) + field[n:]
-		value := value.FieldByName(fieldName)
-		if value.IsValid() {
-			return value.Interface(), nil
-		}
+		return extractStructField(value, field), nil
 	} else if value.Kind() == reflect.Map {
 		keyType := value.Type().Key()
 		if reflect.TypeOf(field).ConvertibleTo(keyType) {
@@ -492,57 +536,22 @@ func extractFieldUsingReflection(value reflect.Value, field string) (any, error)
 	return nil, nil
 }
 
-func (intr *treeInterpreter) flattenWithReflection(value any) (any, error) {
-	v := reflect.ValueOf(value)
-	flattened := []any{}
-	for i := 0; i < v.Len(); i++ {
-		element := v.Index(i).Interface()
-		if reflect.TypeOf(element).Kind() == reflect.Slice {
-			// Then insert the contents of the element
-			// slice into the flattened slice,
-			// i.e flattened = append(flattened, mySlice...)
-			elementV := reflect.ValueOf(element)
-			for j := 0; j < elementV.Len(); j++ {
-				flattened = append(
-					flattened, elementV.Index(j).Interface())
-			}
-		} else {
-			flattened = append(flattened, element)
-		}
-	}
-	return flattened, nil
-}
-
-func (intr *treeInterpreter) sliceWithReflection(node parsing.ASTNode, value any) (any, error) {
-	v := reflect.ValueOf(value)
-	parts := node.Value.([]*int)
-	sliceParams := make([]util.SliceParam, 3)
-	for i, part := range parts {
-		if part != nil {
-			sliceParams[i].Specified = true
-			sliceParams[i].N = *part
-		}
-	}
-	final := []any{}
-	for i := 0; i < v.Len(); i++ {
-		element := v.Index(i).Interface()
-		final = append(final, element)
-	}
-	return util.Slice(final, sliceParams)
-}
-
-func (intr *treeInterpreter) filterProjectionWithReflection(node parsing.ASTNode, value any, functionCaller FunctionCaller) (any, error) {
+func (intr *treeInterpreter) filterProjectionWithReflection(ctx context.Context, node parsing.ASTNode, value any, functionCaller FunctionCaller) (any, error) {
 	compareNode := node.Children[2]
 	collected := []any{}
 	v := reflect.ValueOf(value)
+	if intr.parallel(v.Len()) {
+		elements, _ := util.ToArray(value)
+		return intr.filterProjectionParallel(ctx, node, elements, functionCaller)
+	}
 	for i := 0; i < v.Len(); i++ {
 		element := v.Index(i).Interface()
-		result, err := intr.execute(compareNode, element, functionCaller)
+		result, err := intr.execute(ctx, compareNode, element, functionCaller)
 		if err != nil {
 			return nil, err
 		}
 		if !util.IsFalse(result) {
-			current, err := intr.execute(node.Children[1], element, functionCaller)
+			current, err := intr.execute(ctx, node.Children[1], element, functionCaller)
 			if err != nil {
 				return nil, err
 			}
@@ -554,12 +563,16 @@ func (intr *treeInterpreter) filterProjectionWithReflection(node parsing.ASTNode
 	return collected, nil
 }
 
-func (intr *treeInterpreter) projectWithReflection(node parsing.ASTNode, value any, functionCaller FunctionCaller) (any, error) {
+func (intr *treeInterpreter) projectWithReflection(ctx context.Context, node parsing.ASTNode, value any, functionCaller FunctionCaller) (any, error) {
 	collected := []any{}
 	v := reflect.ValueOf(value)
+	if intr.parallel(v.Len()) {
+		elements, _ := util.ToArray(value)
+		return intr.projectParallel(ctx, node, elements, functionCaller)
+	}
 	for i := 0; i < v.Len(); i++ {
 		element := v.Index(i).Interface()
-		result, err := intr.execute(node.Children[1], element, functionCaller)
+		result, err := intr.execute(ctx, node.Children[1], element, functionCaller)
 		if err != nil {
 			return nil, err
 		}
@@ -1,9 +1,14 @@
 package interpreter
 
 import (
+	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/jmespath-community/go-jmespath/pkg/binding"
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/functions"
 	"github.com/jmespath-community/go-jmespath/pkg/parsing"
 	"github.com/stretchr/testify/assert"
 )
@@ -209,6 +214,79 @@ func TestErrEvaluatingRightHandSideOfStringSlice(t *testing.T) {
 	assert.Equal("unknown function: unknown", err.Error())
 }
 
+func TestExecuteWithContextCanceled(t *testing.T) {
+	assert := assert.New(t)
+	parser := parsing.NewParser()
+	ast, err := parser.Parse("foo")
+	assert.Nil(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	intr := NewInterpreter(nil, nil)
+	result, err := intr.Execute(ast, map[string]any{"foo": "bar"}, WithContext(ctx))
+	assert.ErrorIs(err, context.Canceled)
+	assert.Nil(result)
+}
+
+func TestExecuteWithContextCanceledInExpRef(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	caller := NewFunctionCaller(append(functions.GetDefaultFunctions(), functions.FunctionEntry{
+		Name: "cancel",
+		Arguments: []functions.ArgSpec{
+			{Types: []functions.JpType{functions.JpAny}},
+		},
+		Handler: func(arguments []any) (any, error) {
+			calls++
+			cancel()
+			return arguments[0], nil
+		},
+	})...)
+	parser := parsing.NewParser()
+	ast, err := parser.Parse("sort_by(@, &cancel(@))")
+	assert.Nil(err)
+	intr := NewInterpreter(nil, nil)
+	result, err := intr.Execute(ast, []any{3.0, 2.0, 1.0}, WithFunctionCaller(caller), WithContext(ctx))
+	assert.ErrorIs(err, context.Canceled)
+	assert.Nil(result)
+	assert.Equal(1, calls)
+}
+
+func TestExecuteWithContextCanceledInFilterLeftHandSide(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	caller := NewFunctionCaller(append(functions.GetDefaultFunctions(), functions.FunctionEntry{
+		Name: "cancel",
+		Handler: func(arguments []any) (any, error) {
+			cancel()
+			return []any{1.0}, nil
+		},
+	})...)
+	parser := parsing.NewParser()
+	ast, err := parser.Parse("cancel()[].foo")
+	assert.Nil(err)
+	intr := NewInterpreter(nil, nil)
+	result, err := intr.Execute(ast, nil, WithFunctionCaller(caller), WithContext(ctx))
+	assert.ErrorIs(err, context.Canceled)
+	assert.Nil(result)
+}
+
+func TestExecuteWithBindingsRestoresBindings(t *testing.T) {
+	assert := assert.New(t)
+	parser := parsing.NewParser()
+	ast, err := parser.Parse("$foo")
+	assert.Nil(err)
+	intr := NewInterpreter(nil, binding.NewBindings().Register("$foo", binding.NewBinding("initial")))
+	result, err := intr.Execute(ast, nil, WithVariables(map[string]any{"foo": "option"}))
+	assert.Nil(err)
+	assert.Equal("option", result)
+	result, err = intr.Execute(ast, nil)
+	assert.Nil(err)
+	assert.Equal("initial", result)
+}
+
 func BenchmarkInterpretSingleFieldStruct(b *testing.B) {
 	assert := assert.New(b)
 	intr := NewInterpreter(nil, nil)
@@ -259,3 +337,97 @@ func BenchmarkInterpretNestedMaps(b *testing.B) {
 		}
 	}
 }
+
+func TestFunctionCallerFromRegistry(t *testing.T) {
+	assert := assert.New(t)
+	registry := functions.DefaultRegistry().With(functions.FunctionEntry{
+		Name: "shout",
+		Arguments: []functions.ArgSpec{
+			{Types: []functions.JpType{functions.JpString}},
+		},
+		Handler: func(arguments []any) (any, error) {
+			return strings.ToUpper(arguments[0].(string)), nil
+		},
+	}, functions.FunctionEntry{
+		Name: "length",
+		Arguments: []functions.ArgSpec{
+			{Types: []functions.JpType{functions.JpAny}},
+		},
+		Handler: func(arguments []any) (any, error) {
+			return -1.0, nil
+		},
+	}).Without("to_number")
+	caller := NewFunctionCallerFromRegistry(registry)
+	tests := []struct {
+		expression string
+		want       any
+		wantErr    bool
+	}{
+		{expression: "shout(name)", want: "WEB"},
+		{expression: "length(name)", want: -1.0},
+		{expression: "abs(`-2`)", want: 2.0},
+		{expression: "to_number('1')", wantErr: true},
+	}
+	data := map[string]any{"name": "web"}
+	for _, tt := range tests {
+		ast, err := parsing.NewParser().Parse(tt.expression)
+		assert.NoError(err)
+		result, err := NewInterpreter(data, nil).Execute(ast, data, WithFunctionCaller(caller))
+		if tt.wantErr {
+			var jpErr *jperror.Error
+			assert.ErrorAs(err, &jpErr)
+			assert.Equal(jperror.UnknownFunction, jpErr.Kind)
+			continue
+		}
+		assert.NoError(err)
+		assert.Equal(tt.want, result)
+	}
+}
+
+func TestCallFunctionKeepsArguments(t *testing.T) {
+	assert := assert.New(t)
+	arguments := []any{[]int{1, 2}, 3}
+	result, err := DefaultFunctionCaller.CallFunction("sum", arguments[:1])
+	assert.NoError(err)
+	assert.Equal(3.0, result)
+	result, err = DefaultFunctionCaller.CallFunction("abs", arguments[1:])
+	assert.NoError(err)
+	assert.Equal(3.0, result)
+	assert.Equal([]any{[]int{1, 2}, 3}, arguments)
+}
+
+var benchmarkData = map[string]any{
+	"people": []any{
+		map[string]any{"name": "a", "age": 30.0, "tags": []any{"x", "y"}},
+		map[string]any{"name": "b", "age": 20.0, "tags": []any{"z"}},
+		map[string]any{"name": "c", "age": 40.0, "tags": []any{}},
+	},
+}
+
+func BenchmarkExecute(b *testing.B) {
+	runExecuteBenchmark(b, "people[?contains(tags, 'x')].name | sort(@)")
+}
+
+func BenchmarkExecuteWithContext(b *testing.B) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runExecuteBenchmark(b, "people[?contains(tags, 'x')].name | sort(@)", WithContext(ctx))
+}
+
+func BenchmarkExecuteWithBudget(b *testing.B) {
+	runExecuteBenchmark(b, "people[?contains(tags, 'x')].name | sort(@)", WithMaxSteps(1000))
+}
+
+func runExecuteBenchmark(b *testing.B, expression string, opts ...Option) {
+	b.Helper()
+	ast, err := parsing.NewParser().Parse(expression)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewInterpreter(benchmarkData, nil).Execute(ast, benchmarkData, opts...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package interpreter
+
+import (
+	"math"
+	"reflect"
+
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/jmespath-community/go-jmespath/pkg/util"
+)
+
+// The operations below are shared by the tree interpreter and the virtual machine.
+// Those reporting a bool return false when the operator is not supported, the
+// arithmetic and comparisons are exact when precise is true.
+
+func unaryArithmetic(operator any, operand any, precise bool) (any, bool) {
+	if precise {
+		return preciseUnaryArithmetic(operator, operand)
+	}
+	num, ok := util.ToNumber(operand)
+	if !ok {
+		return nil, true
+	}
+	switch operator {
+	case parsing.TOKPlus:
+		return num, true
+	case parsing.TOKMinus:
+		return -num, true
+	}
+	return nil, false
+}
+
+func arithmetic(operator any, left any, right any, precise bool) (any, bool) {
+	if precise {
+		return preciseArithmetic(operator, left, right)
+	}
+	leftNum, ok := util.ToNumber(left)
+	if !ok {
+		return nil, true
+	}
+	rightNum, ok := util.ToNumber(right)
+	if !ok {
+		return nil, true
+	}
+	switch operator {
+	case parsing.TOKPlus:
+		return leftNum + rightNum, true
+	case parsing.TOKMinus:
+		return leftNum - rightNum, true
+	case parsing.TOKStar:
+		return leftNum * rightNum, true
+	case parsing.TOKMultiply:
+		return leftNum * rightNum, true
+	case parsing.TOKDivide:
+		return leftNum / rightNum, true
+	case parsing.TOKModulo:
+		return math.Mod(leftNum, rightNum), true
+	case parsing.TOKDiv:
+		return math.Floor(leftNum / rightNum), true
+	}
+	return nil, false
+}
+
+func compare(operator any, left any, right any, precise bool) (any, bool) {
+	if precise {
+		return preciseCompare(operator, left, right)
+	}
+	switch operator {
+	case parsing.TOKEQ:
+		return util.ObjsEqual(left, right), true
+	case parsing.TOKNE:
+		return !util.ObjsEqual(left, right), true
+	}
+	leftNum, ok := util.ToNumber(left)
+	if !ok {
+		return nil, true
+	}
+	rightNum, ok := util.ToNumber(right)
+	if !ok {
+		return nil, true
+	}
+	switch operator {
+	case parsing.TOKGT:
+		return leftNum > rightNum, true
+	case parsing.TOKGTE:
+		return leftNum >= rightNum, true
+	case parsing.TOKLT:
+		return leftNum < rightNum, true
+	case parsing.TOKLTE:
+		return leftNum <= rightNum, true
+	}
+	return nil, false
+}
+
+func index(value any, index int) any {
+	if sliceType, ok := value.([]any); ok {
+		if index < 0 {
+			index += len(sliceType)
+		}
+		if index < len(sliceType) && index >= 0 {
+			return sliceType[index]
+		}
+		return nil
+	}
+	// Otherwise try via reflection.
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice {
+		if index < 0 {
+			index += rv.Len()
+		}
+		if index < rv.Len() && index >= 0 {
+			v := rv.Index(index)
+			return v.Interface()
+		}
+	}
+	return nil
+}
+
+func slice(parts []*int, value any) (any, error) {
+	sliceType, ok := value.([]any)
+	if !ok {
+		if util.IsSliceType(value) {
+			return sliceWithReflection(parts, value)
+		}
+		// string slices is implemented by slicing
+		// the corresponding array of runes and
+		// converting the result back to a string
+		if stringType, ok := value.(string); ok {
+			runeType := []rune(stringType)
+			sliceParams := util.MakeSliceParams(parts)
+			runes, err := util.Slice(runeType, sliceParams)
+			if err != nil {
+				return nil, nil
+			}
+			return string(runes), nil
+		}
+		return nil, nil
+	}
+	sliceParams := util.MakeSliceParams(parts)
+	return util.Slice(sliceType, sliceParams)
+}
+
+func sliceWithReflection(parts []*int, value any) (any, error) {
+	v := reflect.ValueOf(value)
+	sliceParams := make([]util.SliceParam, 3)
+	for i, part := range parts {
+		if part != nil {
+			sliceParams[i].Specified = true
+			sliceParams[i].N = *part
+		}
+	}
+	final := []any{}
+	for i := 0; i < v.Len(); i++ {
+		element := v.Index(i).Interface()
+		final = append(final, element)
+	}
+	return util.Slice(final, sliceParams)
+}
+
+func flatten(value any) any {
+	sliceType, ok := value.([]any)
+	if !ok {
+		// If we can't type convert to []any, there's
+		// a chance this could still work via reflection if we're
+		// dealing with user provided types.
+		if util.IsSliceType(value) {
+			return flattenWithReflection(value)
+		}
+		return nil
+	}
+	flattened := []any{}
+	for _, element := range sliceType {
+		if elementSlice, ok := element.([]any); ok {
+			flattened = append(flattened, elementSlice...)
+		} else if util.IsSliceType(element) {
+			reflectFlat := []any{}
+			v := reflect.ValueOf(element)
+			for i := 0; i < v.Len(); i++ {
+				reflectFlat = append(reflectFlat, v.Index(i).Interface())
+			}
+			flattened = append(flattened, reflectFlat...)
+		} else {
+			flattened = append(flattened, element)
+		}
+	}
+	return flattened
+}
+
+func flattenWithReflection(value any) any {
+	v := reflect.ValueOf(value)
+	flattened := []any{}
+	for i := 0; i < v.Len(); i++ {
+		element := v.Index(i).Interface()
+		if reflect.TypeOf(element).Kind() == reflect.Slice {
+			// Then insert the contents of the element
+			// slice into the flattened slice,
+			// i.e flattened = append(flattened, mySlice...)
+			elementV := reflect.ValueOf(element)
+			for j := 0; j < elementV.Len(); j++ {
+				flattened = append(
+					flattened, elementV.Index(j).Interface())
+			}
+		} else {
+			flattened = append(flattened, element)
+		}
+	}
+	return flattened
+}
+
+// objectValues returns the values of an object, in lexicographic order of their
+// keys when sorted is set. The values of an ordered object are always in order.
+// Go maps are converted, see util.ToObject.
+func objectValues(value any, sorted bool) ([]any, bool) {
+	if util.IsMapType(value) {
+		if _, ok := value.(map[string]any); !ok {
+			value, _ = util.ToObject(value)
+		}
+	}
+	switch object := value.(type) {
+	case *ordered.Object:
+		return object.Values(), true
+	case map[string]any:
+		values := make([]any, 0, len(object))
+		if sorted {
+			for _, key := range util.SortedKeys(object) {
+				values = append(values, object[key])
+			}
+			return values, true
+		}
+		for _, value := range object {
+			values = append(values, value)
+		}
+		return values, true
+	}
+	return nil, false
+}
@@ -1,9 +1,77 @@
 package interpreter
 
+import (
+	"context"
+	"strings"
+
+	"github.com/jmespath-community/go-jmespath/pkg/binding"
+)
+
 type Option func(Options) Options
 
 type Options struct {
-	FunctionCaller FunctionCaller
+	FunctionCaller    FunctionCaller
+	Bindings          binding.Bindings
+	MaxSteps          int
+	MaxDepth          int
+	MaxResultElements int
+	// ArbitraryPrecision enables arbitrary precision arithmetic.
+	ArbitraryPrecision bool
+	// Parallelism is the number of goroutines evaluating large projections.
+	Parallelism int
+	// ParallelThreshold is the length from which arrays are projected in parallel.
+	ParallelThreshold int
+	// DeterministicOrder projects object values in a deterministic order.
+	DeterministicOrder bool
+	// OrderedObjects builds objects that keep the order of their keys.
+	OrderedObjects bool
+	// Tracer observes the evaluation of nodes and function calls.
+	Tracer Tracer
+	// Context aborts the evaluation when it is done.
+	Context context.Context
+}
+
+func newOptions(opts ...Option) Options {
+	var o Options
+	for _, opt := range opts {
+		if opt != nil {
+			o = opt(o)
+		}
+	}
+	return o
+}
+
+// context returns the context of the evaluation, defaulting to the background context.
+func (o Options) context() context.Context {
+	if o.Context == nil {
+		return context.Background()
+	}
+	return o.Context
+}
+
+// functionCaller returns the function caller of the evaluation, defaulting
+// to the function caller matching the precision.
+func (o Options) functionCaller() FunctionCaller {
+	switch {
+	case o.FunctionCaller != nil:
+		return o.FunctionCaller
+	case o.ArbitraryPrecision && o.OrderedObjects:
+		return preciseOrderedFunctionCaller
+	case o.OrderedObjects:
+		return OrderedFunctionCaller
+	case o.ArbitraryPrecision:
+		return PreciseFunctionCaller
+	}
+	return DefaultFunctionCaller
+}
+
+// WithContext stops the evaluation as soon as the context is done, the context error is
+// then returned. The context is also checked while functions evaluate expression references.
+func WithContext(ctx context.Context) Option {
+	return func(o Options) Options {
+		o.Context = ctx
+		return o
+	}
 }
 
 func WithFunctionCaller(functionCaller FunctionCaller) Option {
@@ -12,3 +80,131 @@ func WithFunctionCaller(functionCaller FunctionCaller) Option {
 		return o
 	}
 }
+
+// WithBindings sets the initial variable bindings of the evaluation, they replace
+// the bindings the interpreter was created with. Binding names include the leading `$`.
+func WithBindings(bindings binding.Bindings) Option {
+	return func(o Options) Options {
+		o.Bindings = bindings
+		return o
+	}
+}
+
+// WithVariables sets the initial variable bindings of the evaluation from a map
+// of values. Names can be given with or without the leading `$`, when a map has
+// both `x` and `$x` the value of `$x` is used.
+func WithVariables(variables map[string]any) Option {
+	bindings := binding.NewBindings()
+	for name, value := range variables {
+		if !strings.HasPrefix(name, "$") {
+			// the name with the prefix takes precedence, whatever the iteration order
+			if _, ok := variables["$"+name]; ok {
+				continue
+			}
+			name = "$" + name
+		}
+		bindings = bindings.Register(name, binding.NewBinding(value))
+	}
+	return WithBindings(bindings)
+}
+
+// WithMaxSteps limits the number of AST nodes evaluated during a single evaluation.
+func WithMaxSteps(maxSteps int) Option {
+	return func(o Options) Options {
+		o.MaxSteps = maxSteps
+		return o
+	}
+}
+
+// WithMaxDepth limits the nesting depth of AST nodes evaluation, including
+// nested expression references evaluated by functions.
+func WithMaxDepth(maxDepth int) Option {
+	return func(o Options) Options {
+		o.MaxDepth = maxDepth
+		return o
+	}
+}
+
+// WithMaxResultElements limits the total number of array elements and object
+// members that can be produced by projections, multi-selects, flatten, slices
+// and function calls during a single evaluation.
+func WithMaxResultElements(maxResultElements int) Option {
+	return func(o Options) Options {
+		o.MaxResultElements = maxResultElements
+		return o
+	}
+}
+
+// WithArbitraryPrecision evaluates numbers exactly: arithmetic, comparisons and numeric
+// functions convert numbers to arbitrary precision rationals and produce json.Number
+// values, so that integers above 2^53 or decimal amounts are not rounded to float64.
+// Decode the data with json.Decoder.UseNumber to keep it exact too. Results without
+// a finite decimal representation, like 1 / 3, are rounded to util.InexactDigits
+// digits and division by zero evaluates to null. Unless a function caller is given,
+// functions are called with PreciseFunctionCaller.
+func WithArbitraryPrecision() Option {
+	return func(o Options) Options {
+		o.ArbitraryPrecision = true
+		return o
+	}
+}
+
+// WithParallelism evaluates the right hand side of projections and filter projections,
+// and the expression of map(), in up to workers goroutines for arrays of at least
+// threshold elements. Results are in the same order as with a sequential evaluation and
+// the error reported is the one of the first failing element. Limits set with WithMaxSteps
+// and WithMaxResultElements are shared by all the goroutines. Custom function callers must
+// be safe for concurrent use. The virtual machine evaluates sequentially.
+func WithParallelism(workers int, threshold int) Option {
+	return func(o Options) Options {
+		o.Parallelism = workers
+		o.ParallelThreshold = threshold
+		return o
+	}
+}
+
+// WithDeterministicOrder makes value projections like `foo.*` visit the values of objects
+// in lexicographic order of their keys, the order in which keys(), values() and items()
+// return them, so that results do not depend on the iteration order of Go maps.
+func WithDeterministicOrder() Option {
+	return func(o Options) Options {
+		o.DeterministicOrder = true
+		return o
+	}
+}
+
+// WithOrderedObjects makes multi-select hashes, merge(), from_items() and group_by()
+// build *ordered.Object values, which keep their keys in the order they are written
+// or found and are encoded to JSON in that order, instead of map[string]any. Objects
+// decoded with the ordered package keep their order whatever the options: fields,
+// value projections, keys(), values(), items() and merge() follow it. Unless a function
+// caller is given, functions are called with OrderedFunctionCaller.
+func WithOrderedObjects() Option {
+	return func(o Options) Options {
+		o.OrderedObjects = true
+		return o
+	}
+}
+
+// WithTracer calls a tracer when entering and exiting every node of the expression and
+// around every function call, including the nodes of expression references evaluated by
+// functions. Nodes whose errors are discarded, like the left hand side of a projection,
+// are exited with their error. With WithParallelism the tracer is called concurrently by
+// several goroutines, unless it implements Forker. Several tracers are called in the order
+// they are registered.
+func WithTracer(tracer Tracer) Option {
+	return func(o Options) Options {
+		o.Tracer = addTracer(o.Tracer, tracer)
+		return o
+	}
+}
+
+// WithProfile records in a profile the number of times each node of the expression is
+// evaluated, the time spent evaluating it and the number of elements it produces.
+func WithProfile(profile *Profile) Option {
+	return func(o Options) Options {
+		// every evaluation times its nodes separately
+		o.Tracer = addTracer(o.Tracer, &profiler{profile: profile})
+		return o
+	}
+}
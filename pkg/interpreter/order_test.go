@@ -0,0 +1,129 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeterministicOrder(t *testing.T) {
+	data := map[string]any{
+		"ports": map[string]any{
+			"https": map[string]any{"number": 443.0},
+			"dns":   map[string]any{"number": 53.0},
+			"ssh":   map[string]any{"number": 22.0},
+			"http":  map[string]any{"number": 80.0},
+			"smtp":  map[string]any{"number": 25.0},
+		},
+	}
+	tests := []struct {
+		expression string
+		want       any
+	}{
+		{"ports.*.number", []any{53.0, 80.0, 443.0, 25.0, 22.0}},
+		{"ports.*.number | [0]", 53.0},
+		{"*.*.number[]", []any{53.0, 80.0, 443.0, 25.0, 22.0}},
+		{"keys(ports)", []any{"dns", "http", "https", "smtp", "ssh"}},
+		{"values(ports)[*].number", []any{53.0, 80.0, 443.0, 25.0, 22.0}},
+		{"items(ports)[*][0]", []any{"dns", "http", "https", "smtp", "ssh"}},
+		{"ports.*.missing", []any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			// map iteration order is random, evaluate several times to catch it
+			for i := 0; i < 10; i++ {
+				result, err := NewInterpreter(data, nil).Execute(ast, data, WithDeterministicOrder())
+				assert.NoError(err)
+				assert.Equal(tt.want, result)
+				result, err = NewVirtualMachine(data, nil).Run(CompileProgram(ast), data, WithDeterministicOrder())
+				assert.NoError(err)
+				assert.Equal(tt.want, result)
+			}
+		})
+	}
+}
+
+func TestOrderedObjects(t *testing.T) {
+	data, err := ordered.Unmarshal([]byte(`{
+		"service": {"name": "web", "image": "nginx", "env": {"TZ": "UTC", "LANG": "C"}},
+		"labels": [["tier", "front"], ["app", "shop"]],
+		"pods": [{"zone": "b"}, {"zone": "a"}, {"zone": "b"}],
+		"empty": {}
+	}`))
+	assert.NoError(t, err)
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"service", `{"name":"web","image":"nginx","env":{"TZ":"UTC","LANG":"C"}}`},
+		{"service.env.LANG", `"C"`},
+		{"service.*", `["web","nginx",{"TZ":"UTC","LANG":"C"}]`},
+		{"keys(service)", `["name","image","env"]`},
+		{"values(service.env)", `["UTC","C"]`},
+		{"items(service.env)", `[["TZ","UTC"],["LANG","C"]]`},
+		{"length(service)", `3`},
+		{"type(service)", `"object"`},
+		{"{image: service.image, name: service.name}", `{"image":"nginx","name":"web"}`},
+		{"service.{z: name, a: image}", `{"z":"web","a":"nginx"}`},
+		{"merge(service.env, {A: 'x', TZ: 'CET'})", `{"TZ":"CET","LANG":"C","A":"x"}`},
+		{"from_items(labels)", `{"tier":"front","app":"shop"}`},
+		{"group_by(pods, &zone)", `{"b":[{"zone":"b"},{"zone":"b"}],"a":[{"zone":"a"}]}`},
+		{"service.env == {LANG: 'C', TZ: 'UTC'}", `true`},
+		{"empty || 'default'", `"default"`},
+		{"to_string(service.env)", `"{\"TZ\":\"UTC\",\"LANG\":\"C\"}"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			for _, opts := range [][]Option{{WithOrderedObjects()}, {WithOrderedObjects(), WithArbitraryPrecision()}} {
+				result, err := NewInterpreter(data, nil).Execute(ast, data, opts...)
+				assert.NoError(err)
+				actual, err := json.Marshal(result)
+				assert.NoError(err)
+				assert.Equal(tt.want, string(actual))
+				result, err = NewVirtualMachine(data, nil).Run(CompileProgram(ast), data, opts...)
+				assert.NoError(err)
+				actual, err = json.Marshal(result)
+				assert.NoError(err)
+				assert.Equal(tt.want, string(actual))
+			}
+		})
+	}
+}
+
+func TestOrderedObjectsDisabled(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ordered.Unmarshal([]byte(`{"b": 1, "a": 2}`))
+	assert.NoError(err)
+	tests := []struct {
+		expression string
+		want       any
+	}{
+		{"{b: b, a: a}", map[string]any{"b": 1.0, "a": 2.0}},
+		{"from_items([['b', b]])", map[string]any{"b": 1.0}},
+		{"merge(`{}`, `{\"c\": 3}`)", map[string]any{"c": 3.0}},
+	}
+	for _, tt := range tests {
+		ast, err := parsing.NewParser().Parse(tt.expression)
+		assert.NoError(err)
+		result, err := NewInterpreter(data, nil).Execute(ast, data)
+		assert.NoError(err)
+		assert.Equal(tt.want, result)
+	}
+	// merging an ordered object keeps its order without the option
+	ast, err := parsing.NewParser().Parse("merge(@, {c: `3`})")
+	assert.NoError(err)
+	result, err := NewInterpreter(data, nil).Execute(ast, data)
+	assert.NoError(err)
+	actual, err := json.Marshal(result)
+	assert.NoError(err)
+	assert.Equal(`{"b":1,"a":2,"c":3}`, string(actual))
+}
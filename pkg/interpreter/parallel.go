@@ -0,0 +1,136 @@
+package interpreter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/jmespath-community/go-jmespath/pkg/util"
+)
+
+// parallel reports whether an array of the given length is evaluated in parallel.
+func (intr *treeInterpreter) parallel(length int) bool {
+	return intr.parallelism > 1 && length > 1 && length >= intr.parallelThreshold
+}
+
+// fork returns a copy of the interpreter that can evaluate concurrently with it.
+// Bindings are immutable and shared, the budget and the tracer are forked.
+func (intr *treeInterpreter) fork() *treeInterpreter {
+	forked := *intr
+	forked.budget = intr.budget.fork()
+	forked.tracer = forkTracer(intr.tracer)
+	return &forked
+}
+
+// evaluateParallel evaluates each element with a fork of the interpreter, elements are
+// split in chunks evaluated by up to parallelism goroutines. Results are returned in the
+// order of the elements. When evaluations fail, the error of the first failing element
+// is returned, like a sequential evaluation would.
+func (intr *treeInterpreter) evaluateParallel(elements []any, evaluate func(worker *treeInterpreter, element any) (any, error)) ([]any, error) {
+	workers := intr.parallelism
+	if workers > len(elements) {
+		workers = len(elements)
+	}
+	// a few chunks per worker balance the load when evaluations have different costs
+	size := (len(elements) + 4*workers - 1) / (4 * workers)
+	results := make([]any, len(elements))
+	var next int64
+	// failed is the index of the first failing element, it is read atomically
+	failed := int64(len(elements))
+	var failure error
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		// forks are created by the goroutine of the interpreter, see Forker
+		worker := intr.fork()
+		go func() {
+			defer wg.Done()
+			for {
+				start := int(atomic.AddInt64(&next, int64(size))) - size
+				if start >= len(elements) {
+					return
+				}
+				end := start + size
+				if end > len(elements) {
+					end = len(elements)
+				}
+				for i := start; i < end; i++ {
+					if int64(i) > atomic.LoadInt64(&failed) {
+						return
+					}
+					result, err := evaluate(worker, elements[i])
+					if err != nil {
+						mutex.Lock()
+						if int64(i) < failed {
+							atomic.StoreInt64(&failed, int64(i))
+							failure = err
+						}
+						mutex.Unlock()
+						return
+					}
+					results[i] = result
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if failure != nil {
+		return nil, failure
+	}
+	return results, nil
+}
+
+func (intr *treeInterpreter) projectParallel(ctx context.Context, node parsing.ASTNode, elements []any, functionCaller FunctionCaller) (any, error) {
+	results, err := intr.evaluateParallel(elements, func(worker *treeInterpreter, element any) (any, error) {
+		return worker.execute(ctx, node.Children[1], element, functionCaller)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return compact(results), nil
+}
+
+func (intr *treeInterpreter) filterProjectionParallel(ctx context.Context, node parsing.ASTNode, elements []any, functionCaller FunctionCaller) (any, error) {
+	results, err := intr.evaluateParallel(elements, func(worker *treeInterpreter, element any) (any, error) {
+		result, err := worker.execute(ctx, node.Children[2], element, functionCaller)
+		if err != nil || util.IsFalse(result) {
+			return nil, err
+		}
+		return worker.execute(ctx, node.Children[1], element, functionCaller)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return compact(results), nil
+}
+
+// parallelMap returns the elements of a call to the default map function that is evaluated
+// in parallel, the arguments are checked like the function would.
+func (intr *treeInterpreter) parallelMap(node parsing.ASTNode, arguments []any, functionCaller FunctionCaller) ([]any, bool) {
+	if intr.parallelism <= 1 || node.Value != "map" || len(arguments) != 2 {
+		return nil, false
+	}
+	switch functionCaller {
+	case DefaultFunctionCaller, PreciseFunctionCaller, OrderedFunctionCaller, preciseOrderedFunctionCaller:
+	default:
+		return nil, false
+	}
+	if node.Children[0].NodeType != parsing.ASTExpRef {
+		return nil, false
+	}
+	elements, ok := util.ToArray(arguments[1])
+	return elements, ok && intr.parallel(len(elements))
+}
+
+// compact removes the null results of a projection.
+func compact(results []any) []any {
+	collected := make([]any, 0, len(results))
+	for _, result := range results {
+		if result != nil {
+			collected = append(collected, result)
+		}
+	}
+	return collected
+}
@@ -0,0 +1,106 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+func parallelData() map[string]any {
+	items := make([]any, 1000)
+	for i := range items {
+		items[i] = map[string]any{
+			"id":    float64(i),
+			"name":  fmt.Sprintf("item-%d", i),
+			"even":  i%2 == 0,
+			"tags":  []any{float64(i % 7), float64(i % 3)},
+			"value": float64(i % 13),
+		}
+	}
+	failing := make([]any, 1000)
+	copy(failing, items)
+	failing[600] = map[string]any{"value": "first"}
+	failing[900] = map[string]any{"value": "second"}
+	users := make([]taggedUser, 500)
+	for i := range users {
+		users[i] = taggedUser{ID: i}
+	}
+	return map[string]any{"items": items, "failing": failing, "users": users}
+}
+
+func TestParallelism(t *testing.T) {
+	data := parallelData()
+	tests := []string{
+		"items[*].id",
+		"items[*].tags[*]",
+		"items[?even].name",
+		"items[?value > `6`].{id: id, value: value}",
+		"items[?sort_by(tags, &@)[0] == `0`].id",
+		"items[*].missing",
+		"items[10:900:3].id",
+		"map(&value, items)",
+		"map(&missing, items)",
+		"map(&map(&@, tags), items)",
+		"let $n = `5` in items[?value == $n].id",
+		"users[*].user_id",
+		"users[?user_id > `250`].user_id",
+		"items[*].abs(value)",
+		"failing[*].abs(value)",
+		"failing[?abs(value) > `1`].id",
+		"map(&abs(value), failing)",
+	}
+	for _, expression := range tests {
+		t.Run(expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser().Parse(expression)
+			assert.NoError(err)
+			want, wantErr := NewInterpreter(data, nil).Execute(ast, data)
+			for _, workers := range []int{2, 3, 8} {
+				got, gotErr := NewInterpreter(data, nil).Execute(ast, data, WithParallelism(workers, 16))
+				assert.Equal(want, got)
+				assert.Equal(wantErr, gotErr)
+			}
+		})
+	}
+}
+
+func TestParallelismThreshold(t *testing.T) {
+	assert := assert.New(t)
+	intr := &treeInterpreter{parallelism: 4, parallelThreshold: 100}
+	assert.False(intr.parallel(99))
+	assert.True(intr.parallel(100))
+	intr.parallelism = 1
+	assert.False(intr.parallel(100))
+}
+
+func TestParallelismBudget(t *testing.T) {
+	assert := assert.New(t)
+	data := parallelData()
+	ast, err := parsing.NewParser().Parse("items[*].name")
+	assert.NoError(err)
+	_, err = NewInterpreter(data, nil).Execute(ast, data, WithParallelism(4, 16), WithMaxSteps(500))
+	var budgetErr *BudgetExceededError
+	assert.True(errors.As(err, &budgetErr))
+	result, err := NewInterpreter(data, nil).Execute(ast, data, WithParallelism(4, 16), WithMaxSteps(1002), WithMaxDepth(2))
+	assert.NoError(err)
+	assert.Len(result, 1000)
+}
+
+func BenchmarkParallelFilter(b *testing.B) {
+	data := parallelData()
+	ast, err := parsing.NewParser().Parse("items[?sort_by(tags, &@)[0] == `0` && contains(name, '9')].id")
+	assert.NoError(b, err)
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := NewInterpreter(data, nil).Execute(ast, data, WithParallelism(workers, 64))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package interpreter
+
+import (
+	"math/big"
+
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/jmespath-community/go-jmespath/pkg/util"
+)
+
+// The operations below implement arbitrary precision, numbers are computed
+// as exact rationals and produced as json.Number values.
+
+func preciseUnaryArithmetic(operator any, operand any) (any, bool) {
+	num, ok := util.ToRat(operand)
+	if !ok {
+		return nil, true
+	}
+	switch operator {
+	case parsing.TOKPlus:
+		return util.RatToJSONNumber(num), true
+	case parsing.TOKMinus:
+		return util.RatToJSONNumber(num.Neg(num)), true
+	}
+	return nil, false
+}
+
+func preciseArithmetic(operator any, left any, right any) (any, bool) {
+	leftNum, ok := util.ToRat(left)
+	if !ok {
+		return nil, true
+	}
+	rightNum, ok := util.ToRat(right)
+	if !ok {
+		return nil, true
+	}
+	result := new(big.Rat)
+	switch operator {
+	case parsing.TOKPlus:
+		result.Add(leftNum, rightNum)
+	case parsing.TOKMinus:
+		result.Sub(leftNum, rightNum)
+	case parsing.TOKStar, parsing.TOKMultiply:
+		result.Mul(leftNum, rightNum)
+	case parsing.TOKDivide, parsing.TOKModulo, parsing.TOKDiv:
+		// exact numbers have no infinity, division by zero is null
+		if rightNum.Sign() == 0 {
+			return nil, true
+		}
+		result.Quo(leftNum, rightNum)
+		switch operator {
+		case parsing.TOKModulo:
+			// the remainder has the sign of the dividend, like math.Mod
+			truncated := new(big.Int).Quo(result.Num(), result.Denom())
+			result.Sub(leftNum, result.Mul(rightNum, new(big.Rat).SetInt(truncated)))
+		case parsing.TOKDiv:
+			result.SetInt(new(big.Int).Div(result.Num(), result.Denom()))
+		}
+	default:
+		return nil, false
+	}
+	return util.RatToJSONNumber(result), true
+}
+
+func preciseCompare(operator any, left any, right any) (any, bool) {
+	switch operator {
+	case parsing.TOKEQ:
+		return util.ObjsEqual(left, right), true
+	case parsing.TOKNE:
+		return !util.ObjsEqual(left, right), true
+	}
+	c, ok := util.CompareNumbers(left, right)
+	if !ok {
+		// not numbers, infinities or NaN
+		return compare(operator, left, right, false)
+	}
+	switch operator {
+	case parsing.TOKGT:
+		return c > 0, true
+	case parsing.TOKGTE:
+		return c >= 0, true
+	case parsing.TOKLT:
+		return c < 0, true
+	case parsing.TOKLTE:
+		return c <= 0, true
+	}
+	return nil, false
+}
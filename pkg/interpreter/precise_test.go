@@ -0,0 +1,95 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArbitraryPrecision(t *testing.T) {
+	decoder := json.NewDecoder(strings.NewReader(`{
+		"id": 9007199254740993,
+		"amounts": [0.1, 0.2, 0.3],
+		"prices": [{"price": 19.99}, {"price": 0.01}, {"price": 5}],
+		"big": 123456789012345678901234567890,
+		"float": 0.1
+	}`))
+	decoder.UseNumber()
+	var data any
+	assert.NoError(t, decoder.Decode(&data))
+	tests := []struct {
+		expression string
+		want       any
+	}{
+		{"id", json.Number("9007199254740993")},
+		{"id + `1`", json.Number("9007199254740994")},
+		{"id == `9007199254740992`", false},
+		{"id > `9007199254740992`", true},
+		{"id <= `9007199254740992`", false},
+		{"-id", json.Number("-9007199254740993")},
+		{"`0.1` + `0.2`", json.Number("0.3")},
+		{"float + `0.2`", json.Number("0.3")},
+		{"`0.3` - `0.1`", json.Number("0.2")},
+		{"big * `10`", json.Number("1234567890123456789012345678900")},
+		{"`1` / `4`", json.Number("0.25")},
+		{"`1` / `3`", json.Number("0.3333333333333333333333333333333333")},
+		{"`1` / `0`", nil},
+		{"`7.5` % `2`", json.Number("1.5")},
+		{"`-7` % `2`", json.Number("-1")},
+		{"`-7` // `2`", json.Number("-4")},
+		{"`7` // `0`", nil},
+		{"'a' + `1`", nil},
+		{"'a' < `1`", nil},
+		{"sum(amounts)", json.Number("0.6")},
+		{"avg(amounts)", json.Number("0.2")},
+		{"max(amounts)", json.Number("0.3")},
+		{"min([id, `9007199254740992`])", json.Number("9007199254740992")},
+		{"sort([id, `9007199254740992`, `1`])", []any{json.Number("1"), json.Number("9007199254740992"), json.Number("9007199254740993")}},
+		{"max(['a', 'b'])", "b"},
+		{"abs(`-9007199254740993`)", json.Number("9007199254740993")},
+		{"ceil(`-1.5`)", json.Number("-1")},
+		{"floor(`-1.5`)", json.Number("-2")},
+		{"ceil(big)", json.Number("123456789012345678901234567890")},
+		{"to_number('9007199254740993')", json.Number("9007199254740993")},
+		{"to_number('abc')", nil},
+		{"to_number('1e400')", json.Number("1" + strings.Repeat("0", 400))},
+		{"to_number('-1.5e-3')", json.Number("-0.0015")},
+		{"to_number('1/2')", nil},
+		{"to_number('0x10')", nil},
+		{"to_number('0x1p-2')", json.Number("0.25")},
+		{"length(amounts)", json.Number("3")},
+		{"sum(prices[*].price)", json.Number("25")},
+		{"max_by(prices, &price).price", json.Number("19.99")},
+		{"sort_by(prices, &price)[0].price", json.Number("0.01")},
+		{"prices[?price > `0.01`].price", []any{json.Number("19.99"), json.Number("5")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser(parsing.WithUseNumber()).Parse(tt.expression)
+			assert.NoError(err)
+			result, err := NewInterpreter(data, nil).Execute(ast, data, WithArbitraryPrecision())
+			assert.NoError(err)
+			assert.Equal(tt.want, result)
+			result, err = NewVirtualMachine(data, nil).Run(CompileProgram(ast), data, WithArbitraryPrecision())
+			assert.NoError(err)
+			assert.Equal(tt.want, result)
+		})
+	}
+}
+
+func TestArbitraryPrecisionWithFloats(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]any{"a": 0.1, "b": 0.2, "n": int64(9007199254740993)}
+	result, err := search(t, "a + b", data)
+	assert.NoError(err)
+	assert.Equal(0.30000000000000004, result)
+	ast, err := parsing.NewParser().Parse("[a + b, n + `0`]")
+	assert.NoError(err)
+	result, err = NewInterpreter(data, nil).Execute(ast, data, WithArbitraryPrecision())
+	assert.NoError(err)
+	assert.Equal([]any{json.Number("0.3"), json.Number("9007199254740993")}, result)
+}
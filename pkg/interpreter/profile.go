@@ -0,0 +1,163 @@
+package interpreter
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+)
+
+// NodeProfile is the cost of evaluating the nodes found at a span of the expression.
+type NodeProfile struct {
+	// Start and End are the span of the node in the expression.
+	Start int
+	End   int
+	// NodeType is the name of the type of the node, like ASTFilterProjection.
+	NodeType string
+	// Calls is the number of times the node was evaluated.
+	Calls int
+	// Time is the cumulative time spent evaluating the node, including its children.
+	Time time.Duration
+	// Elements is the number of elements of the arrays and objects built by the node,
+	// nodes that only select part of their input produce no elements.
+	Elements int
+}
+
+// Profile records the cost of every node of the expressions evaluated with
+// WithProfile. Evaluations with the same profile are accumulated, a profile can be
+// used concurrently. The zero value is an empty profile ready to use.
+type Profile struct {
+	mu    sync.Mutex
+	nodes map[profileKey]*NodeProfile
+}
+
+// profileKey identifies a node, nodes like a flatten and its projection share a span.
+type profileKey struct {
+	start, end int
+	nodeType   string
+}
+
+// NewProfile returns an empty profile.
+func NewProfile() *Profile {
+	return &Profile{nodes: map[profileKey]*NodeProfile{}}
+}
+
+// Report returns the profile of every node evaluated, ordered by their span in the
+// expression, a node comes before the nodes nested in it.
+func (p *Profile) Report() []NodeProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	report := make([]NodeProfile, 0, len(p.nodes))
+	for _, node := range p.nodes {
+		report = append(report, *node)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Start != report[j].Start {
+			return report[i].Start < report[j].Start
+		}
+		if report[i].End != report[j].End {
+			return report[i].End > report[j].End
+		}
+		return report[i].NodeType < report[j].NodeType
+	})
+	return report
+}
+
+func (p *Profile) record(node parsing.ASTNode, elapsed time.Duration, result any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := profileKey{start: node.Start, end: node.End, nodeType: node.NodeType.String()}
+	if p.nodes == nil {
+		p.nodes = map[profileKey]*NodeProfile{}
+	}
+	n, ok := p.nodes[key]
+	if !ok {
+		n = &NodeProfile{Start: node.Start, End: node.End, NodeType: key.nodeType}
+		p.nodes[key] = n
+	}
+	n.Calls++
+	n.Time += elapsed
+	if produced, ok := producedElements(node, result); ok {
+		n.Elements += produced
+	}
+}
+
+// profiler is the tracer recording into a profile, it times the nodes entered.
+type profiler struct {
+	profile *Profile
+	starts  []time.Time
+}
+
+func (p *profiler) Enter(parsing.ASTNode, any) {
+	p.starts = append(p.starts, time.Now())
+}
+
+func (p *profiler) Exit(node parsing.ASTNode, _ any, result any, _ error) {
+	start := p.starts[len(p.starts)-1]
+	p.starts = p.starts[:len(p.starts)-1]
+	p.profile.record(node, time.Since(start), result)
+}
+
+func (p *profiler) EnterFunction(string, []any) {}
+
+func (p *profiler) ExitFunction(string, []any, any, error) {}
+
+// Fork returns a profiler timing the nodes of a parallel evaluation into the same profile.
+func (p *profiler) Fork() Tracer {
+	return &profiler{profile: p.profile}
+}
+
+// tracers calls several tracers in order.
+type tracers []Tracer
+
+func (t tracers) Enter(node parsing.ASTNode, value any) {
+	for _, tracer := range t {
+		tracer.Enter(node, value)
+	}
+}
+
+func (t tracers) Exit(node parsing.ASTNode, value any, result any, err error) {
+	for _, tracer := range t {
+		tracer.Exit(node, value, result, err)
+	}
+}
+
+func (t tracers) EnterFunction(name string, arguments []any) {
+	for _, tracer := range t {
+		tracer.EnterFunction(name, arguments)
+	}
+}
+
+func (t tracers) ExitFunction(name string, arguments []any, result any, err error) {
+	for _, tracer := range t {
+		tracer.ExitFunction(name, arguments, result, err)
+	}
+}
+
+func (t tracers) Fork() Tracer {
+	forked := make(tracers, len(t))
+	for i, tracer := range t {
+		forked[i] = forkTracer(tracer)
+	}
+	return forked
+}
+
+// addTracer returns a tracer calling tracer after current, if any.
+func addTracer(current Tracer, tracer Tracer) Tracer {
+	switch t := current.(type) {
+	case nil:
+		return tracer
+	case tracers:
+		return append(t[:len(t):len(t)], tracer)
+	}
+	return tracers{current, tracer}
+}
+
+// forkTracer returns the tracer used by a parallel evaluation.
+func forkTracer(tracer Tracer) Tracer {
+	if f, ok := tracer.(Forker); ok {
+		return f.Fork()
+	}
+	return tracer
+}
@@ -0,0 +1,94 @@
+package interpreter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile(t *testing.T) {
+	data := map[string]any{
+		"people": []any{
+			map[string]any{"name": "bob", "age": 32.0},
+			map[string]any{"name": "alice", "age": 25.0},
+			map[string]any{"name": "carol"},
+		},
+	}
+	tests := []struct {
+		expression string
+		want       []string
+	}{{
+		expression: "people[?age > `30`].name",
+		want: []string{
+			"ASTFilterProjection people[?age > `30`].name calls=1 elements=1",
+			"ASTField people calls=1 elements=0",
+			"ASTComparator age > `30` calls=3 elements=0",
+			"ASTField age calls=3 elements=0",
+			"ASTLiteral `30` calls=3 elements=0",
+			"ASTField name calls=1 elements=0",
+		},
+	}, {
+		expression: "sort_by(people, &name)[*].name",
+		want: []string{
+			"ASTProjection sort_by(people, &name)[*].name calls=1 elements=3",
+			"ASTFunctionExpression sort_by(people, &name) calls=1 elements=3",
+			"ASTField people calls=1 elements=0",
+			"ASTExpRef &name calls=1 elements=0",
+			"ASTField name calls=3 elements=0",
+			"ASTField name calls=3 elements=0",
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			report := func(profile *Profile) []string {
+				var lines []string
+				for _, node := range profile.Report() {
+					assert.GreaterOrEqual(int64(node.Time), int64(0))
+					lines = append(lines, fmt.Sprintf("%s %s calls=%d elements=%d", node.NodeType, tt.expression[node.Start:node.End], node.Calls, node.Elements))
+				}
+				return lines
+			}
+			profile := NewProfile()
+			_, err = NewInterpreter(data, nil).Execute(ast, data, WithProfile(profile))
+			assert.NoError(err)
+			assert.Equal(tt.want, report(profile))
+			profile = NewProfile()
+			_, err = NewVirtualMachine(data, nil).Run(CompileProgram(ast), data, WithProfile(profile))
+			assert.NoError(err)
+			assert.Equal(tt.want, report(profile))
+		})
+	}
+}
+
+func TestProfileAccumulates(t *testing.T) {
+	assert := assert.New(t)
+	elements := make([]any, 100)
+	for i := range elements {
+		elements[i] = map[string]any{"n": float64(i)}
+	}
+	ast, err := parsing.NewParser().Parse("map(&to_string(n), @)")
+	assert.NoError(err)
+	var profile Profile
+	c := &counter{}
+	for i := 0; i < 3; i++ {
+		_, err := NewInterpreter(elements, nil).Execute(ast, elements, WithProfile(&profile), WithParallelism(4, 10), WithTracer(c))
+		assert.NoError(err)
+	}
+	calls := map[string]int{}
+	for _, node := range profile.Report() {
+		calls[node.NodeType] += node.Calls
+	}
+	assert.Equal(map[string]int{
+		"ASTFunctionExpression": 3 + 300,
+		"ASTExpRef":             3,
+		"ASTCurrentNode":        3,
+		"ASTField":              300,
+	}, calls)
+	// the other tracers are still called
+	assert.Equal(303, c.call)
+}
@@ -0,0 +1,43 @@
+package interpreter
+
+import (
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+)
+
+// Tracer observes an evaluation, see WithTracer. Every call to Enter is followed by a call
+// to Exit for the same node, nodes evaluated while a node is entered are nested in it.
+type Tracer interface {
+	// Enter is called before a node is evaluated against a value.
+	Enter(node parsing.ASTNode, value any)
+	// Exit is called after a node is evaluated against a value, with its result or error.
+	Exit(node parsing.ASTNode, value any, result any, err error)
+	// EnterFunction is called before a function is called with its arguments.
+	EnterFunction(name string, arguments []any)
+	// ExitFunction is called after a function is called, with its result or error.
+	ExitFunction(name string, arguments []any, result any, err error)
+}
+
+// Forker is implemented by tracers keeping the state of an evaluation, like the nodes
+// entered. With WithParallelism, each goroutine evaluating elements of an array calls a
+// fork of the tracer, returned by Fork, while the tracer waits for them. Fork is called
+// by the goroutine of the tracer. Tracers that do not implement Forker are called
+// concurrently by the goroutines.
+type Forker interface {
+	// Fork returns the tracer called by a goroutine evaluating elements in parallel.
+	Fork() Tracer
+}
+
+// traced is a node entered by the virtual machine and the value it evaluates.
+type traced struct {
+	node  *parsing.ASTNode
+	value any
+}
+
+// exitTraces exits the nodes entered since there were n traced nodes with an error.
+func (vm *virtualMachine) exitTraces(n int, err error) {
+	for len(vm.traces) > n {
+		t := vm.traces[len(vm.traces)-1]
+		vm.traces = vm.traces[:len(vm.traces)-1]
+		vm.tracer.Exit(*t.node, t.value, nil, err)
+	}
+}
@@ -0,0 +1,285 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a tracer recording the hooks it receives, one line per call.
+type recorder struct {
+	lines []string
+}
+
+func (r *recorder) Enter(node parsing.ASTNode, value any) {
+	r.lines = append(r.lines, fmt.Sprintf("enter %s %s", node.NodeType, encode(value)))
+}
+
+func (r *recorder) Exit(node parsing.ASTNode, value any, result any, err error) {
+	r.lines = append(r.lines, fmt.Sprintf("exit %s %s -> %s%s", node.NodeType, encode(value), encode(result), failed(err)))
+}
+
+func (r *recorder) EnterFunction(name string, arguments []any) {
+	r.lines = append(r.lines, fmt.Sprintf("call %s %s", name, encode(arguments)))
+}
+
+func (r *recorder) ExitFunction(name string, arguments []any, result any, err error) {
+	r.lines = append(r.lines, fmt.Sprintf("return %s %s -> %s%s", name, encode(arguments), encode(result), failed(err)))
+}
+
+func encode(value any) string {
+	if _, ok := value.(func(any) (any, error)); ok {
+		return "&"
+	}
+	if arguments, ok := value.([]any); ok {
+		encoded := make([]string, len(arguments))
+		for i, argument := range arguments {
+			encoded[i] = encode(argument)
+		}
+		return "[" + strings.Join(encoded, ",") + "]"
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+func failed(err error) string {
+	if err != nil {
+		return " error"
+	}
+	return ""
+}
+
+func TestTracer(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]any{"foo": map[string]any{"bar": []any{"a", "b"}}}
+	ast, err := parsing.NewParser().Parse("length(foo.bar)")
+	assert.NoError(err)
+	want := []string{
+		`enter ASTFunctionExpression {"foo":{"bar":["a","b"]}}`,
+		`enter ASTSubexpression {"foo":{"bar":["a","b"]}}`,
+		`enter ASTField {"foo":{"bar":["a","b"]}}`,
+		`exit ASTField {"foo":{"bar":["a","b"]}} -> {"bar":["a","b"]}`,
+		`enter ASTField {"bar":["a","b"]}`,
+		`exit ASTField {"bar":["a","b"]} -> ["a","b"]`,
+		`exit ASTSubexpression {"foo":{"bar":["a","b"]}} -> ["a","b"]`,
+		`call length [["a","b"]]`,
+		`return length [["a","b"]] -> 2`,
+		`exit ASTFunctionExpression {"foo":{"bar":["a","b"]}} -> 2`,
+	}
+	r := &recorder{}
+	result, err := NewInterpreter(data, nil).Execute(ast, data, WithTracer(r))
+	assert.NoError(err)
+	assert.Equal(2.0, result)
+	assert.Equal(want, r.lines)
+	r = &recorder{}
+	result, err = NewVirtualMachine(data, nil).Run(CompileProgram(ast), data, WithTracer(r))
+	assert.NoError(err)
+	assert.Equal(2.0, result)
+	assert.Equal(want, r.lines)
+}
+
+func TestTracerInterpreters(t *testing.T) {
+	data := map[string]any{
+		"people": []any{
+			map[string]any{"name": "bob", "age": 32.0},
+			map[string]any{"name": "alice", "age": 25.0},
+			map[string]any{"name": "carol"},
+		},
+		"tags": map[string]any{"env": "prod"},
+	}
+	tests := []struct {
+		expression string
+		wantErr    bool
+	}{
+		{expression: "people[*].name"},
+		{expression: "people[?age > `30`].name | [0]"},
+		{expression: "tags.*"},
+		{expression: "people[].[name, age][]"},
+		{expression: "{names: people[*].name, count: length(people)}"},
+		{expression: "sort_by(people[?age], &age)[*].name"},
+		{expression: "max_by(people[?age], &to_string(age))"},
+		{expression: "let $n = 'bob' in people[?name == $n].age"},
+		{expression: "abs('x')[?age].name"},
+		{expression: "people[0].name || 'none'"},
+		{expression: "length(people[0].age)", wantErr: true},
+		{expression: "people[*].abs(name)", wantErr: true},
+		{expression: "sort_by(people, &age)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			tree := &recorder{}
+			want, err := NewInterpreter(data, nil).Execute(ast, data, WithTracer(tree))
+			assert.Equal(tt.wantErr, err != nil)
+			vm := &recorder{}
+			result, err := NewVirtualMachine(data, nil).Run(CompileProgram(ast), data, WithTracer(vm))
+			assert.Equal(tt.wantErr, err != nil)
+			assert.Equal(want, result)
+			assert.Equal(tree.lines, vm.lines)
+			// every node and function entered is exited
+			depth := 0
+			for _, line := range tree.lines {
+				switch strings.SplitN(line, " ", 2)[0] {
+				case "enter", "call":
+					depth++
+				case "exit", "return":
+					depth--
+				}
+				assert.GreaterOrEqual(depth, 0)
+			}
+			assert.Equal(0, depth)
+		})
+	}
+}
+
+func TestTracerWithBudget(t *testing.T) {
+	assert := assert.New(t)
+	data := map[string]any{"foo": []any{1.0, 2.0, 3.0}}
+	ast, err := parsing.NewParser().Parse("foo[*].to_string(@)")
+	assert.NoError(err)
+	tree := &recorder{}
+	_, err = NewInterpreter(data, nil).Execute(ast, data, WithTracer(tree), WithMaxSteps(6))
+	assert.Error(err)
+	vm := &recorder{}
+	_, err = NewVirtualMachine(data, nil).Run(CompileProgram(ast), data, WithTracer(vm), WithMaxSteps(6))
+	assert.Error(err)
+	assert.Equal(tree.lines, vm.lines)
+	assert.Contains(tree.lines[len(tree.lines)-1], "exit ASTProjection")
+	assert.Contains(tree.lines[len(tree.lines)-1], "error")
+}
+
+// counter is a tracer counting the hooks it receives, it can be called concurrently.
+type counter struct {
+	sync.Mutex
+	enter, exit, call, ret int
+}
+
+func (c *counter) Enter(parsing.ASTNode, any) {
+	c.Lock()
+	defer c.Unlock()
+	c.enter++
+}
+
+func (c *counter) Exit(parsing.ASTNode, any, any, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.exit++
+}
+
+func (c *counter) EnterFunction(string, []any) {
+	c.Lock()
+	defer c.Unlock()
+	c.call++
+}
+
+func (c *counter) ExitFunction(string, []any, any, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.ret++
+}
+
+func TestTracerWithParallelism(t *testing.T) {
+	assert := assert.New(t)
+	elements := make([]any, 100)
+	for i := range elements {
+		elements[i] = map[string]any{"n": float64(i)}
+	}
+	ast, err := parsing.NewParser().Parse("map(&to_string(n), @)")
+	assert.NoError(err)
+	sequential := &counter{}
+	want, err := NewInterpreter(elements, nil).Execute(ast, elements, WithTracer(sequential))
+	assert.NoError(err)
+	parallel := &counter{}
+	result, err := NewInterpreter(elements, nil).Execute(ast, elements, WithTracer(parallel), WithParallelism(4, 10))
+	assert.NoError(err)
+	assert.Equal(want, result)
+	assert.Equal(sequential.enter, parallel.enter)
+	assert.Equal(parallel.enter, parallel.exit)
+	// map and to_string for every element
+	assert.Equal(101, parallel.call)
+	assert.Equal(parallel.call, parallel.ret)
+}
+
+// stack is a tracer checking that nodes are exited in the reverse order they are entered,
+// it is not safe for concurrent use and forks itself for parallel evaluations.
+type stack struct {
+	entered []parsing.ASTNode
+	// forks are the stacks of the parallel evaluations, counted by nodes the number
+	// of nodes entered by a stack and its forks.
+	forks []*stack
+	nodes int
+	err   error
+}
+
+func (s *stack) Enter(node parsing.ASTNode, _ any) {
+	s.entered = append(s.entered, node)
+	s.nodes++
+}
+
+func (s *stack) Exit(node parsing.ASTNode, _ any, _ any, _ error) {
+	if len(s.entered) == 0 || s.entered[len(s.entered)-1].NodeType != node.NodeType {
+		s.err = fmt.Errorf("exit %s not entered", node.NodeType)
+		return
+	}
+	s.entered = s.entered[:len(s.entered)-1]
+}
+
+func (s *stack) EnterFunction(string, []any) {}
+
+func (s *stack) ExitFunction(string, []any, any, error) {}
+
+func (s *stack) Fork() Tracer {
+	forked := &stack{}
+	s.forks = append(s.forks, forked)
+	return forked
+}
+
+// check returns the first error of the stack and its forks, and the nodes they entered.
+func (s *stack) check() (int, error) {
+	nodes, err := s.nodes, s.err
+	if err == nil && len(s.entered) != 0 {
+		err = fmt.Errorf("%d nodes not exited", len(s.entered))
+	}
+	for _, fork := range s.forks {
+		n, forkErr := fork.check()
+		nodes += n
+		if err == nil {
+			err = forkErr
+		}
+	}
+	return nodes, err
+}
+
+func TestForkerWithParallelism(t *testing.T) {
+	elements := make([]any, 100)
+	for i := range elements {
+		elements[i] = map[string]any{"n": float64(i), "tags": []any{"a", "b"}}
+	}
+	for _, expression := range []string{"map(&to_string(n), @)", "[*].tags[0]", "[?n > `10`].n", "[*].tags[?@ == 'a']"} {
+		t.Run(expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser().Parse(expression)
+			assert.NoError(err)
+			sequential := &stack{}
+			want, err := NewInterpreter(elements, nil).Execute(ast, elements, WithTracer(sequential))
+			assert.NoError(err)
+			parallel := &stack{}
+			result, err := NewInterpreter(elements, nil).Execute(ast, elements, WithTracer(parallel), WithParallelism(4, 10))
+			assert.NoError(err)
+			assert.Equal(want, result)
+			assert.NotEmpty(parallel.forks)
+			wantNodes, err := sequential.check()
+			assert.NoError(err)
+			nodes, err := parallel.check()
+			assert.NoError(err)
+			assert.Equal(wantNodes, nodes)
+		})
+	}
+}
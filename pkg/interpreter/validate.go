@@ -0,0 +1,37 @@
+package interpreter
+
+import (
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/functions"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+)
+
+// ValidateFunctions checks, without evaluating the AST, that every function it calls
+// is one of the given functions and is called with a supported number of arguments.
+// The returned error is located on the offending function call.
+func ValidateFunctions(node parsing.ASTNode, funcs ...functions.FunctionEntry) error {
+	table := map[string][]functions.ArgSpec{}
+	for _, f := range funcs {
+		table[f.Name] = f.Arguments
+	}
+	return validateFunctions(node, table)
+}
+
+func validateFunctions(node parsing.ASTNode, table map[string][]functions.ArgSpec) error {
+	if node.NodeType == parsing.ASTFunctionExpression {
+		name := node.Value.(string)
+		arguments, ok := table[name]
+		if !ok {
+			return locate(jperror.UnknownFunctionCalled(name), node)
+		}
+		if err := checkArity(name, arguments, len(node.Children)); err != nil {
+			return locate(err, node)
+		}
+	}
+	for _, child := range node.Children {
+		if err := validateFunctions(child, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package interpreter
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/jmespath-community/go-jmespath/pkg/binding"
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/jmespath-community/go-jmespath/pkg/util"
+)
+
+/*
+VirtualMachine is an interpreter running programs compiled
+from ASTs on a stack based virtual machine. It produces the
+same results and errors as the tree based interpreter.
+*/
+type VirtualMachine interface {
+	Interpreter
+	Run(*Program, any, ...Option) (any, error)
+}
+
+type virtualMachine struct {
+	root           any
+	bindings       binding.Bindings
+	budget         *budget
+	ctx            context.Context
+	functionCaller FunctionCaller
+	precise        bool
+	sorted         bool
+	ordered        bool
+	tracer         Tracer
+	// traces are the nodes entered and not exited yet when tracing.
+	traces []traced
+	// scopes saves the bindings replaced by let expressions.
+	scopes []binding.Bindings
+}
+
+// projection is the state of a projection loop.
+type projection struct {
+	elements  []any
+	next      int
+	collected []any
+	// single is set when a string is projected, the result is not collected.
+	single bool
+}
+
+// handler discards the errors raised in the left hand side of some nodes,
+// it records the state of the virtual machine to restore.
+type handler struct {
+	target      int
+	height      int
+	projections int
+	scopes      int
+	depth       int
+	traces      int
+}
+
+func NewVirtualMachine(data any, bindings binding.Bindings) VirtualMachine {
+	if bindings == nil {
+		bindings = binding.NewBindings()
+	}
+	return &virtualMachine{
+		root:     data,
+		bindings: bindings,
+	}
+}
+
+// Execute compiles the AST and runs the resulting program, see Run.
+func (vm *virtualMachine) Execute(node parsing.ASTNode, value any, opts ...Option) (any, error) {
+	return vm.Run(CompileProgram(node), value, opts...)
+}
+
+// Run runs a program against the input data "value". Compiling an AST once and
+// running the program many times avoids walking the AST on every evaluation.
+func (vm *virtualMachine) Run(program *Program, value any, opts ...Option) (any, error) {
+	o := newOptions(opts...)
+	vm.functionCaller = o.functionCaller()
+	if o.Bindings != nil {
+		bindings := vm.bindings
+		vm.bindings = o.Bindings
+		defer func() {
+			vm.bindings = bindings
+		}()
+	}
+	vm.ctx = o.context()
+	vm.budget = newBudget(o)
+	vm.precise = o.ArbitraryPrecision
+	vm.sorted = o.DeterministicOrder
+	vm.ordered = o.OrderedObjects
+	vm.tracer = o.Tracer
+	vm.scopes = nil
+	vm.traces = nil
+	code := program.code
+	if vm.budget != nil || vm.tracer != nil {
+		code = program.accounted
+	}
+	result, err := vm.run(code, value)
+	if err != nil {
+		return nil, err
+	}
+	// some nodes discard errors from their left hand side,
+	// make sure cancellation is never silently ignored
+	if err := vm.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (vm *virtualMachine) run(code []instruction, value any) (any, error) {
+	if err := checkContext(vm.ctx); err != nil {
+		return nil, err
+	}
+	stack := make([]any, 1, 8)
+	stack[0] = value
+	var projections []projection
+	var handlers []handler
+	scopes := len(vm.scopes)
+	traces := len(vm.traces)
+	for pc := 0; pc < len(code); pc++ {
+		ins := &code[pc]
+		top := len(stack) - 1
+		var err error
+		switch ins.op {
+		case opPop:
+			stack = stack[:top]
+		case opDup:
+			stack = append(stack, stack[top])
+		case opSwap:
+			stack[top-1], stack[top] = stack[top], stack[top-1]
+		case opLiteral:
+			stack[top] = ins.value
+		case opRoot:
+			stack[top] = vm.root
+		case opVariable:
+			stack[top], err = binding.Resolve(ins.name, vm.bindings)
+		case opField:
+			stack[top], err = extractField(stack[top], ins.name)
+		case opIndex:
+			stack[top] = index(stack[top], ins.arg)
+		case opSlice:
+			stack[top], err = slice(ins.parts, stack[top])
+		case opFlatten:
+			stack[top] = flatten(stack[top])
+		case opNot:
+			stack[top] = util.IsFalse(stack[top])
+		case opUnary:
+			stack[top], _ = unaryArithmetic(ins.value, stack[top], vm.precise)
+		case opArithmetic:
+			stack[top-2], _ = arithmetic(ins.value, stack[top-2], stack[top-1], vm.precise)
+			stack = stack[:top-1]
+		case opCompare:
+			stack[top-2], _ = compare(ins.value, stack[top-2], stack[top-1], vm.precise)
+			stack = stack[:top-1]
+		case opJumpIfNull:
+			if stack[top] == nil {
+				pc = ins.arg - 1
+			}
+		case opOr, opAnd:
+			left := stack[top]
+			stack = stack[:top]
+			if util.IsFalse(left) == (ins.op == opAnd) {
+				stack[top-1] = left
+				pc = ins.arg - 1
+			}
+		case opList:
+			collected := make([]any, ins.arg)
+			copy(collected, stack[top-ins.arg:top])
+			stack = append(stack[:top-ins.arg], collected)
+		case opHash:
+			if vm.ordered {
+				collected := ordered.NewObject(len(ins.names))
+				for i, key := range ins.names {
+					collected.Set(key, stack[top-ins.arg+i])
+				}
+				stack = append(stack[:top-ins.arg], collected)
+				break
+			}
+			collected := make(map[string]any, len(ins.names))
+			for i, key := range ins.names {
+				collected[key] = stack[top-ins.arg+i]
+			}
+			stack = append(stack[:top-ins.arg], collected)
+		case opExpRef:
+			sub := ins.code
+			stack[top] = func(data any) (any, error) {
+				return vm.run(sub, data)
+			}
+		case opCall:
+			if err = checkContext(vm.ctx); err != nil {
+				break
+			}
+			args := make([]any, ins.arg)
+			copy(args, stack[top-ins.arg:top])
+			var result any
+			result, err = vm.callFunction(ins.name, args)
+			if err != nil {
+				// point at the offending argument when there is one
+				if jpErr, ok := err.(*jperror.Error); ok && jpErr.Argument >= 0 && jpErr.Argument < len(ins.args) {
+					err = locate(err, ins.args[jpErr.Argument])
+				}
+				break
+			}
+			stack = append(stack[:top-ins.arg], result)
+		case opProjectArray, opProjectArrayOrString, opProjectValues:
+			left := stack[top]
+			stack = stack[:top]
+			p, ok := project(ins.op, left, vm.sorted)
+			if !ok {
+				stack = append(stack, nil)
+				pc = ins.arg - 1
+				break
+			}
+			projections = append(projections, p)
+		case opNext:
+			p := &projections[len(projections)-1]
+			if p.next == len(p.elements) {
+				pc = ins.arg - 1
+				break
+			}
+			if err = checkContext(vm.ctx); err != nil {
+				break
+			}
+			stack = append(stack, p.elements[p.next])
+			p.next++
+		case opCollect:
+			p := &projections[len(projections)-1]
+			if current := stack[top]; p.single || current != nil {
+				p.collected = append(p.collected, current)
+			}
+			stack = stack[:top]
+		case opEndProjection:
+			p := projections[len(projections)-1]
+			projections = projections[:len(projections)-1]
+			if p.single {
+				stack = append(stack, p.collected[0])
+			} else {
+				stack = append(stack, p.collected)
+			}
+		case opJump:
+			pc = ins.arg - 1
+		case opJumpIfFalse:
+			condition := stack[top]
+			stack = stack[:top]
+			if util.IsFalse(condition) {
+				pc = ins.arg - 1
+			}
+		case opTry:
+			h := handler{target: ins.arg, height: top, projections: len(projections), scopes: len(vm.scopes), traces: len(vm.traces)}
+			if vm.budget != nil {
+				h.depth = vm.budget.depth
+			}
+			handlers = append(handlers, h)
+		case opEndTry:
+			handlers = handlers[:len(handlers)-1]
+		case opBind:
+			n := len(ins.names)
+			vm.scopes = append(vm.scopes, vm.bindings)
+			for i, name := range ins.names {
+				vm.bindings = vm.bindings.Register(name, binding.NewBinding(stack[top-n+i]))
+			}
+			stack[top-n] = stack[top]
+			stack = stack[:top-n+1]
+		case opUnbind:
+			vm.restoreScopes(len(vm.scopes) - 1)
+		case opEnter:
+			if vm.tracer != nil {
+				vm.traces = append(vm.traces, traced{node: ins.node, value: stack[top]})
+				vm.tracer.Enter(*ins.node, stack[top])
+			}
+			if err = checkContext(vm.ctx); err == nil && vm.budget != nil {
+				err = vm.budget.enter()
+			}
+		case opExit:
+			if vm.budget != nil {
+				vm.budget.exit()
+				err = vm.budget.produced(*ins.node, stack[top])
+			}
+			if err == nil && vm.tracer != nil {
+				t := vm.traces[len(vm.traces)-1]
+				vm.traces = vm.traces[:len(vm.traces)-1]
+				vm.tracer.Exit(*t.node, t.value, stack[top], nil)
+			}
+		case opFail:
+			err = ins.value.(error)
+		}
+		if err != nil {
+			if ins.node != nil && ins.op != opEnter && ins.op != opExit {
+				err = locate(err, *ins.node)
+			}
+			if n := len(handlers); n != 0 && !mustPropagate(vm.ctx, err) {
+				h := handlers[n-1]
+				handlers = handlers[:n-1]
+				stack = append(stack[:h.height], nil)
+				projections = projections[:h.projections]
+				vm.restoreScopes(h.scopes)
+				if vm.tracer != nil {
+					vm.exitTraces(h.traces, err)
+				}
+				if vm.budget != nil {
+					vm.budget.depth = h.depth
+				}
+				pc = h.target - 1
+				continue
+			}
+			vm.restoreScopes(scopes)
+			if vm.tracer != nil {
+				vm.exitTraces(traces, err)
+			}
+			return nil, err
+		}
+	}
+	return stack[0], nil
+}
+
+// callFunction calls a function, between the function hooks of the tracer.
+func (vm *virtualMachine) callFunction(name string, arguments []any) (any, error) {
+	if vm.tracer == nil {
+		return vm.functionCaller.CallFunction(name, arguments)
+	}
+	vm.tracer.EnterFunction(name, arguments)
+	result, err := vm.functionCaller.CallFunction(name, arguments)
+	vm.tracer.ExitFunction(name, arguments, result, err)
+	return result, err
+}
+
+// restoreScopes restores the bindings saved when there were n scopes.
+func (vm *virtualMachine) restoreScopes(n int) {
+	if len(vm.scopes) > n {
+		vm.bindings = vm.scopes[n]
+		vm.scopes = vm.scopes[:n]
+	}
+}
+
+// project returns the state of a projection over the elements of a value,
+// or false if the value cannot be projected.
+func project(op opcode, value any, sorted bool) (projection, bool) {
+	switch op {
+	case opProjectValues:
+		if elements, ok := objectValues(value, sorted); ok {
+			return projection{elements: elements, collected: make([]any, 0, len(elements))}, true
+		}
+	default:
+		if elements, ok := value.([]any); ok {
+			return projection{elements: elements, collected: make([]any, 0, len(elements))}, true
+		}
+		if util.IsSliceType(value) {
+			v := reflect.ValueOf(value)
+			elements := make([]any, 0, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				elements = append(elements, v.Index(i).Interface())
+			}
+			return projection{elements: elements, collected: make([]any, 0, len(elements))}, true
+		}
+		if s, ok := value.(string); ok && op == opProjectArrayOrString {
+			return projection{elements: []any{s}, single: true}, true
+		}
+	}
+	return projection{}, false
+}
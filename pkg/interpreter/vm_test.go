@@ -0,0 +1,249 @@
+package interpreter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/jmespath-community/go-jmespath/pkg/binding"
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/functions"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+const virtualMachineData = `{
+	"foo": {"bar": [{"baz": 1, "qux": "a"}, {"baz": 2, "qux": "b"}, {"baz": null}], "s": "hello"},
+	"nums": [1, 2, 3, -4.5],
+	"nested": [[1, [2]], [3], 4],
+	"obj": {"k": {"v": 1}},
+	"t": true,
+	"f": false,
+	"strs": ["b", "a", "c"],
+	"people": [{"name": "a", "age": 30}, {"name": "b", "age": 20}]
+}`
+
+var virtualMachineExpressions = []string{
+	"@",
+	"foo",
+	"foo.bar[*].baz",
+	"foo.bar[?baz > `1`].qux",
+	"foo.bar[].baz",
+	"foo.bar[0].{a: baz, b: qux}",
+	"foo.bar[*].[baz, qux]",
+	"foo.bar[0:2].baz",
+	"foo.bar[-1].baz || 'default'",
+	"foo.bar[?qux == 'a'] | [0].baz",
+	"foo.s[::-1]",
+	"foo.s[1:3].length(@)",
+	"foo.s[:].unknown(@)",
+	"foo.s[0]",
+	"foo | bar | [0]",
+	"nested[]",
+	"nested[][]",
+	"nums[::-1]",
+	"nums[1:]",
+	"nums[::0]",
+	"nums[-1]",
+	"nums[10]",
+	"nums[0] + nums[1] * `2`",
+	"-nums[3]",
+	"+nums[3]",
+	"nums[0] // `2`",
+	"nums[0] % `2`",
+	"nums[?@ > `0`] | sum(@)",
+	"obj.*.v",
+	"obj.k.*",
+	"missing",
+	"missing[*]",
+	"missing[]",
+	"missing.*",
+	"missing[?@]",
+	"t[*]",
+	"t[?@]",
+	"`1` < `2`",
+	"foo == foo",
+	"foo != obj",
+	"foo.s == 'hello' && nums[0]",
+	"t && f",
+	"t || f",
+	"f || missing",
+	"!t",
+	"!missing",
+	"[nums[0], foo.s]",
+	"`\"literal\"`",
+	"'raw'",
+	"length(nums)",
+	"sort_by(people, &age)[*].name",
+	"map(&age, people)",
+	"map(&abs(@), strs)",
+	"max_by(people, &age).name",
+	"items(obj)",
+	"to_array(t)[0]",
+	"not_null(missing, foo.s)",
+	"abs('x')",
+	"sum(strs)",
+	"length()",
+	"unknown()",
+	"abs('x')[].foo",
+	"abs('x').*",
+	"abs('x')[?@]",
+	"[abs('x')][]",
+	"$",
+	"$.foo.s",
+	"$undefined",
+	"let $x = nums[0] in nums[?@ > $x]",
+	"let $a = `1`, $b = `2` in [$a, $b]",
+	"let $a = `1` in let $a = `2` in $a",
+	"let $a = `1` in [let $a = `2` in $a, $a]",
+	"let $a = `1` in [(let $a = `2` in abs('x'))[], $a]",
+	"let $a = `1` in map(&[@, $a], nums)",
+	"let $a = nums in people[*].[name, $a[0]][]",
+}
+
+func runVirtualMachine(t *testing.T, expression string, data any, opts ...Option) (any, error, any, error) {
+	t.Helper()
+	ast, err := parsing.NewParser().Parse(expression)
+	assert.NoError(t, err)
+	want, wantErr := NewInterpreter(data, nil).Execute(ast, data, opts...)
+	got, gotErr := NewVirtualMachine(data, nil).Run(CompileProgram(ast), data, opts...)
+	return want, wantErr, got, gotErr
+}
+
+func TestVirtualMachine(t *testing.T) {
+	var data any
+	assert.NoError(t, json.Unmarshal([]byte(virtualMachineData), &data))
+	for _, expression := range virtualMachineExpressions {
+		t.Run(expression, func(t *testing.T) {
+			assert := assert.New(t)
+			want, wantErr, got, gotErr := runVirtualMachine(t, expression, data)
+			assert.Equal(want, got)
+			assert.Equal(wantErr, gotErr)
+		})
+	}
+}
+
+func TestVirtualMachineBudget(t *testing.T) {
+	var data any
+	assert.NoError(t, json.Unmarshal([]byte(virtualMachineData), &data))
+	budgets := map[string]func(int) Option{
+		"steps":           WithMaxSteps,
+		"depth":           WithMaxDepth,
+		"result elements": WithMaxResultElements,
+	}
+	for _, expression := range virtualMachineExpressions {
+		for name, budget := range budgets {
+			for limit := 1; limit <= 12; limit++ {
+				t.Run(fmt.Sprintf("%s/%s/%d", expression, name, limit), func(t *testing.T) {
+					assert := assert.New(t)
+					want, wantErr, got, gotErr := runVirtualMachine(t, expression, data, budget(limit))
+					assert.Equal(want, got)
+					assert.Equal(wantErr, gotErr)
+				})
+			}
+		}
+	}
+}
+
+func TestVirtualMachineWithStructs(t *testing.T) {
+	tests := []struct {
+		expression string
+		data       any
+	}{
+		{"B[].Foo", sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}},
+		{"B[:].Foo", sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}},
+		{"B[? `true` ].Foo", sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}},
+		{"b[-1].foo", sliceType{A: "foo", B: []scalars{{"f1", "b1"}, {"correct", "b2"}}}},
+		{"C[-1].Foo", sliceType{A: "foo", C: []*scalars{{"f1", "b1"}, {"correct", "b2"}}}},
+		{"C || A", sliceType{A: "foo", C: nil}},
+		{"A[].B[].Foo", nestedSlice{A: []sliceType{{B: []scalars{{Foo: "f1a"}, {Foo: "f1b"}}}, {B: []scalars{{Foo: "f2a"}}}}}},
+		{"A[*].A", nestedSlice{A: []sliceType{{A: "first"}, {A: "second"}}}},
+		{"length(@)", []scalars{{"a1", "b1"}, {"a2", "b2"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			want, wantErr, got, gotErr := runVirtualMachine(t, tt.expression, tt.data)
+			assert.NoError(wantErr)
+			assert.Equal(want, got)
+			assert.Equal(wantErr, gotErr)
+		})
+	}
+}
+
+func TestVirtualMachineWithBindings(t *testing.T) {
+	assert := assert.New(t)
+	ast, err := parsing.NewParser().Parse("$foo")
+	assert.Nil(err)
+	vm := NewVirtualMachine(nil, binding.NewBindings().Register("$foo", binding.NewBinding("initial")))
+	result, err := vm.Execute(ast, nil, WithVariables(map[string]any{"foo": "option"}))
+	assert.Nil(err)
+	assert.Equal("option", result)
+	result, err = vm.Execute(ast, nil)
+	assert.Nil(err)
+	assert.Equal("initial", result)
+}
+
+func TestVirtualMachineContextCanceled(t *testing.T) {
+	tests := []struct {
+		expression string
+		data       any
+		wantCalls  int
+	}{
+		{"sort_by(@, &cancel(@))", []any{3.0, 2.0, 1.0}, 1},
+		{"cancel(@)[].foo", []any{1.0}, 1},
+		{"[*].cancel(@)", []any{1.0, 2.0, 3.0}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			calls := 0
+			caller := NewFunctionCaller(append(functions.GetDefaultFunctions(), functions.FunctionEntry{
+				Name: "cancel",
+				Handler: func(arguments []any) (any, error) {
+					calls++
+					cancel()
+					return arguments[0], nil
+				},
+			})...)
+			ast, err := parsing.NewParser().Parse(tt.expression)
+			assert.Nil(err)
+			result, err := NewVirtualMachine(nil, nil).Execute(ast, tt.data, WithFunctionCaller(caller), WithContext(ctx))
+			assert.ErrorIs(err, context.Canceled)
+			assert.Nil(result)
+			assert.Equal(tt.wantCalls, calls)
+		})
+	}
+}
+
+func BenchmarkVirtualMachineProjection(b *testing.B) {
+	var data any
+	if err := json.Unmarshal([]byte(virtualMachineData), &data); err != nil {
+		b.Fatal(err)
+	}
+	ast, _ := parsing.NewParser().Parse("foo.bar[?baz > `1`].qux")
+	program := CompileProgram(ast)
+	vm := NewVirtualMachine(nil, nil)
+	for i := 0; i < b.N; i++ {
+		if _, err := vm.Run(program, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestUnknownNodeError(t *testing.T) {
+	assert := assert.New(t)
+	node := parsing.ASTNode{NodeType: parsing.ASTEmpty}
+	_, err := NewInterpreter(nil, nil).Execute(node, nil)
+	var jpErr *jperror.Error
+	if assert.ErrorAs(err, &jpErr) {
+		assert.Equal(jperror.Unsupported, jpErr.Kind)
+	}
+	_, err = NewVirtualMachine(nil, nil).Execute(node, nil)
+	if assert.ErrorAs(err, &jpErr) {
+		assert.Equal(jperror.Unsupported, jpErr.Kind)
+	}
+}
@@ -0,0 +1,357 @@
+// Package locate evaluates expressions made of paths and returns, next to each value
+// they select, where the value is in the document.
+package locate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/jmespath-community/go-jmespath/pkg/util"
+)
+
+// Path locates a value in a document from its root. Its elements are object keys,
+// as strings, and array indexes, as ints.
+type Path []any
+
+// String returns the path as a JMESPath expression selecting the value, like
+// `foo.bar[3].baz`. Keys that are not identifiers are quoted and the root is `@`.
+func (p Path) String() string {
+	if len(p) == 0 {
+		return "@"
+	}
+	var b strings.Builder
+	for i, element := range p {
+		switch e := element.(type) {
+		case string:
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(quoteKey(e))
+		case int:
+			b.WriteByte('[')
+			b.WriteString(strconv.Itoa(e))
+			b.WriteByte(']')
+		}
+	}
+	return b.String()
+}
+
+// Pointer returns the path as a JSON Pointer, as defined by RFC 6901, like `/foo/bar/3/baz`.
+// The root is the empty string.
+func (p Path) Pointer() string {
+	var b strings.Builder
+	for _, element := range p {
+		b.WriteByte('/')
+		switch e := element.(type) {
+		case string:
+			b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(e))
+		case int:
+			b.WriteString(strconv.Itoa(e))
+		}
+	}
+	return b.String()
+}
+
+// append returns a copy of the path with an element added.
+func (p Path) append(element any) Path {
+	return append(p[:len(p):len(p)], element)
+}
+
+// Match is a value selected by an expression and its location in the document.
+type Match struct {
+	Path  Path
+	Value any
+}
+
+// Search evaluates an expression against a document made of objects, maps, *ordered.Object
+// or structs, and arrays, and returns the values it selects with their paths. Struct fields
+// are resolved like the interpreter resolves them and their path element is the field name
+// used by the expression.
+// The expression can be made of fields, indexes, slices, flatten, projections, value
+// projections, filter projections, sub-expressions, pipes, multi-select lists, `||`, `&&`,
+// `@` and `$`. Values built by projections are located one by one and null values are not
+// matches. Filter conditions can be any expression, they are evaluated with the options.
+// Other expressions, like function calls or literals, build values that are not found in
+// the document, an error of kind jperror.Unsupported is returned for them.
+func Search(ctx context.Context, node parsing.ASTNode, data any, opts ...interpreter.Option) ([]Match, error) {
+	l := &locator{
+		ctx:  ctx,
+		root: data,
+		opts: append(opts[:len(opts):len(opts)], interpreter.WithContext(ctx)),
+	}
+	result, err := l.eval(node, located{value: data, path: Path{}})
+	if err != nil {
+		return nil, err
+	}
+	matches := []Match{}
+	return result.matches(matches), nil
+}
+
+type locator struct {
+	ctx  context.Context
+	root any
+	opts []interpreter.Option
+}
+
+// located is the result of evaluating a node, a value found in the document at path or,
+// when isList is set, the results collected by a projection, a slice or a multi-select list.
+type located struct {
+	value  any
+	path   Path
+	list   []located
+	isList bool
+}
+
+func (r located) isNull() bool {
+	return !r.isList && r.value == nil
+}
+
+// materialize returns the value of a result, like the interpreter computes it.
+func (r located) materialize() any {
+	if !r.isList {
+		return r.value
+	}
+	values := make([]any, len(r.list))
+	for i, element := range r.list {
+		values[i] = element.materialize()
+	}
+	return values
+}
+
+// elements returns the elements of a result that is an array.
+func (r located) elements() ([]located, bool) {
+	if r.isList {
+		return r.list, true
+	}
+	array, ok := util.ToArray(r.value)
+	if !ok {
+		return nil, false
+	}
+	elements := make([]located, len(array))
+	for i, value := range array {
+		elements[i] = located{value: value, path: r.path.append(i)}
+	}
+	return elements, true
+}
+
+// matches appends the values of a result that are not null to matches.
+func (r located) matches(matches []Match) []Match {
+	if r.isList {
+		for _, element := range r.list {
+			matches = element.matches(matches)
+		}
+		return matches
+	}
+	if r.value == nil {
+		return matches
+	}
+	return append(matches, Match{Path: r.path, Value: r.value})
+}
+
+func (l *locator) eval(node parsing.ASTNode, current located) (located, error) {
+	if err := l.ctx.Err(); err != nil {
+		return located{}, err
+	}
+	switch node.NodeType {
+	case parsing.ASTIdentity, parsing.ASTCurrentNode:
+		return current, nil
+	case parsing.ASTRootNode:
+		return located{value: l.root, path: Path{}}, nil
+	case parsing.ASTField:
+		if current.isList {
+			return located{}, nil
+		}
+		key := node.Value.(string)
+		var value any
+		switch object := current.value.(type) {
+		case map[string]any:
+			value = object[key]
+		case *ordered.Object:
+			value, _ = object.Get(key)
+		default:
+			// structs and other maps are resolved like the interpreter resolves them
+			var err error
+			if value, err = interpreter.NewInterpreter(l.root, nil).Execute(node, object, l.opts...); err != nil {
+				return located{}, err
+			}
+		}
+		return located{value: value, path: current.path.append(key)}, nil
+	case parsing.ASTIndex:
+		elements, ok := current.elements()
+		if !ok {
+			return located{}, nil
+		}
+		i := node.Value.(int)
+		if i < 0 {
+			i += len(elements)
+		}
+		if i < 0 || i >= len(elements) {
+			return located{}, nil
+		}
+		return elements[i], nil
+	case parsing.ASTSlice:
+		elements, ok := current.elements()
+		if !ok {
+			return located{}, nil
+		}
+		sliced, err := util.Slice(elements, util.MakeSliceParams(node.Value.([]*int)))
+		if err != nil {
+			return located{}, err
+		}
+		return located{list: sliced, isList: true}, nil
+	case parsing.ASTFlatten:
+		left, err := l.eval(node.Children[0], current)
+		if err != nil {
+			return located{}, err
+		}
+		elements, ok := left.elements()
+		if !ok {
+			return located{}, nil
+		}
+		flattened := []located{}
+		for _, element := range elements {
+			if nested, ok := element.elements(); ok {
+				flattened = append(flattened, nested...)
+			} else {
+				flattened = append(flattened, element)
+			}
+		}
+		return located{list: flattened, isList: true}, nil
+	case parsing.ASTProjection:
+		left, err := l.eval(node.Children[0], current)
+		if err != nil {
+			return located{}, err
+		}
+		elements, ok := left.elements()
+		if !ok {
+			return located{}, nil
+		}
+		return l.project(node.Children[1], elements)
+	case parsing.ASTValueProjection:
+		left, err := l.eval(node.Children[0], current)
+		if err != nil {
+			return located{}, err
+		}
+		object, ok := util.ToObject(left.value)
+		if left.isList || !ok {
+			return located{}, nil
+		}
+		keys, _ := util.ObjectKeys(left.value)
+		elements := make([]located, len(keys))
+		for i, key := range keys {
+			elements[i] = located{value: object[key], path: left.path.append(key)}
+		}
+		return l.project(node.Children[1], elements)
+	case parsing.ASTFilterProjection:
+		left, err := l.eval(node.Children[0], current)
+		if err != nil {
+			// like the interpreter, the filter evaluates to null
+			if l.mustPropagate(err) {
+				return located{}, err
+			}
+			return located{}, nil
+		}
+		elements, ok := left.elements()
+		if !ok {
+			return located{}, nil
+		}
+		matching := []located{}
+		for _, element := range elements {
+			value := element.materialize()
+			result, err := interpreter.NewInterpreter(l.root, nil).Execute(node.Children[2], value, l.opts...)
+			if err != nil {
+				return located{}, err
+			}
+			if !util.IsFalse(result) {
+				matching = append(matching, element)
+			}
+		}
+		return l.project(node.Children[1], matching)
+	case parsing.ASTSubexpression, parsing.ASTIndexExpression:
+		left, err := l.eval(node.Children[0], current)
+		if err != nil {
+			return located{}, err
+		}
+		if left.isNull() {
+			return located{}, nil
+		}
+		return l.eval(node.Children[1], left)
+	case parsing.ASTPipe:
+		result := current
+		for _, child := range node.Children {
+			var err error
+			if result, err = l.eval(child, result); err != nil {
+				return located{}, err
+			}
+		}
+		return result, nil
+	case parsing.ASTMultiSelectList:
+		if current.isNull() {
+			return located{}, nil
+		}
+		collected := make([]located, 0, len(node.Children))
+		for _, child := range node.Children {
+			result, err := l.eval(child, current)
+			if err != nil {
+				return located{}, err
+			}
+			collected = append(collected, result)
+		}
+		return located{list: collected, isList: true}, nil
+	case parsing.ASTOrExpression, parsing.ASTAndExpression:
+		left, err := l.eval(node.Children[0], current)
+		if err != nil {
+			return located{}, err
+		}
+		if util.IsFalse(left.materialize()) == (node.NodeType == parsing.ASTAndExpression) {
+			return left, nil
+		}
+		return l.eval(node.Children[1], current)
+	}
+	return located{}, jperror.New(jperror.Unsupported, "cannot locate values built by the expression").Locate(node.Start, node.End)
+}
+
+// mustPropagate reports whether an error of the left side of a filter projection must
+// be returned, the interpreter ignores it unless the evaluation is aborted. Expressions
+// that cannot be located are reported too.
+func (l *locator) mustPropagate(err error) bool {
+	var budgetErr *interpreter.BudgetExceededError
+	var jpErr *jperror.Error
+	return l.ctx.Err() != nil || errors.As(err, &budgetErr) || errors.As(err, &jpErr) && jpErr.Kind == jperror.Unsupported
+}
+
+// project evaluates a node against elements and collects the results that are not null.
+func (l *locator) project(node parsing.ASTNode, elements []located) (located, error) {
+	collected := []located{}
+	for _, element := range elements {
+		result, err := l.eval(node, element)
+		if err != nil {
+			return located{}, err
+		}
+		if !result.isNull() {
+			collected = append(collected, result)
+		}
+	}
+	return located{list: collected, isList: true}, nil
+}
+
+// quoteKey returns an object key as it appears in a path, quoted unless it is a valid identifier.
+func quoteKey(key string) string {
+	for i, c := range key {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			quoted, _ := json.Marshal(key)
+			return string(quoted)
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
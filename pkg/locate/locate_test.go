@@ -0,0 +1,242 @@
+package locate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+const document = `{
+	"spec": {
+		"containers": [
+			{"name": "web", "privileged": false, "ports": [80, 443]},
+			{"name": "agent", "privileged": true, "ports": []},
+			{"name": "proxy", "privileged": true, "ports": [8080]}
+		],
+		"volumes": {"data": {"size": 10}, "logs": {"size": 2}}
+	},
+	"matrix": [[1, 2], [3], 4],
+	"a/b": {"c~d": 1, "e f": 2},
+	"empty": null
+}`
+
+func TestSearch(t *testing.T) {
+	var data any
+	assert.NoError(t, json.Unmarshal([]byte(document), &data))
+	tests := []struct {
+		expression string
+		paths      []string
+	}{
+		{"@", []string{"@"}},
+		{"spec.containers[0].name", []string{"spec.containers[0].name"}},
+		{"spec.containers[-1].name", []string{"spec.containers[2].name"}},
+		{"spec.containers[5]", []string{}},
+		{"spec.missing", []string{}},
+		{"empty", []string{}},
+		{"spec.containers[*].name", []string{"spec.containers[0].name", "spec.containers[1].name", "spec.containers[2].name"}},
+		{"spec.containers[?privileged].name", []string{"spec.containers[1].name", "spec.containers[2].name"}},
+		{"spec.containers[?name == 'web'].ports[1]", []string{"spec.containers[0].ports[1]"}},
+		{"spec.containers[*].ports[]", []string{"spec.containers[0].ports[0]", "spec.containers[0].ports[1]", "spec.containers[2].ports[0]"}},
+		{"spec.containers[*].ports[*]", []string{"spec.containers[0].ports[0]", "spec.containers[0].ports[1]", "spec.containers[2].ports[0]"}},
+		{"spec.containers[1:].name", []string{"spec.containers[1].name", "spec.containers[2].name"}},
+		{"spec.containers[::-2].name", []string{"spec.containers[2].name", "spec.containers[0].name"}},
+		{"spec.containers[*].name | [1]", []string{"spec.containers[1].name"}},
+		{"spec.volumes.*.size", []string{"spec.volumes.data.size", "spec.volumes.logs.size"}},
+		{"matrix[]", []string{"matrix[0][0]", "matrix[0][1]", "matrix[1][0]", "matrix[2]"}},
+		{"matrix[][0]", []string{}},
+		{"[spec.containers[0].name, missing, matrix[2]]", []string{"spec.containers[0].name", "matrix[2]"}},
+		{"missing || spec.containers[0].name", []string{"spec.containers[0].name"}},
+		{"spec && matrix[1]", []string{"matrix[1]"}},
+		{`"a/b".*`, []string{`"a/b"."c~d"`, `"a/b"."e f"`}},
+		{"spec.containers[?name == $.spec.containers[2].name].name", []string{"spec.containers[2].name"}},
+		{"spec.containers[0] | $.matrix[1][0]", []string{"matrix[1][0]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			matches, err := Search(context.Background(), ast, data)
+			assert.NoError(err)
+			paths := []string{}
+			for _, match := range matches {
+				paths = append(paths, match.Path.String())
+			}
+			assert.Equal(tt.paths, paths)
+			// the values are the ones found at the paths
+			for _, match := range matches {
+				path, err := parsing.NewParser().Parse(match.Path.String())
+				assert.NoError(err)
+				value, err := interpreter.NewInterpreter(data, nil).Execute(path, data)
+				assert.NoError(err)
+				assert.Equal(value, match.Value)
+			}
+		})
+	}
+}
+
+func TestSearchOrderedObject(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ordered.Unmarshal([]byte(`{"volumes": {"logs": {"size": 2}, "data": {"size": 10}}}`))
+	assert.NoError(err)
+	ast, err := parsing.NewParser().Parse("volumes.*.size")
+	assert.NoError(err)
+	matches, err := Search(context.Background(), ast, data)
+	assert.NoError(err)
+	assert.Equal([]Match{
+		{Path: Path{"volumes", "logs", "size"}, Value: 2.0},
+		{Path: Path{"volumes", "data", "size"}, Value: 10.0},
+	}, matches)
+}
+
+func TestSearchStructs(t *testing.T) {
+	type container struct {
+		Name  string `json:"name"`
+		Ports []int  `json:"ports,omitempty"`
+	}
+	type spec struct {
+		Containers []*container
+		Labels     map[string]string `json:"labels"`
+	}
+	data := map[string]any{
+		"spec": spec{
+			Containers: []*container{{Name: "web", Ports: []int{80, 443}}, {Name: "agent"}, nil},
+			Labels:     map[string]string{"app": "web"},
+		},
+	}
+	tests := []struct {
+		expression string
+		want       []Match
+	}{{
+		expression: "spec.containers[*].name",
+		want: []Match{
+			{Path: Path{"spec", "containers", 0, "name"}, Value: "web"},
+			{Path: Path{"spec", "containers", 1, "name"}, Value: "agent"},
+		},
+	}, {
+		expression: "spec.Containers[0].ports[-1]",
+		want:       []Match{{Path: Path{"spec", "Containers", 0, "ports", 1}, Value: 443}},
+	}, {
+		expression: "spec.containers[1].ports",
+		want:       []Match{},
+	}, {
+		expression: "spec.labels.app",
+		want:       []Match{{Path: Path{"spec", "labels", "app"}, Value: "web"}},
+	}, {
+		expression: "spec.containers[?name == 'agent'].name",
+		want:       []Match{{Path: Path{"spec", "containers", 1, "name"}, Value: "agent"}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			matches, err := Search(context.Background(), ast, data)
+			assert.NoError(err)
+			assert.Equal(tt.want, matches)
+		})
+	}
+}
+
+func TestSearchUnsupported(t *testing.T) {
+	for _, expression := range []string{"length(spec)", "spec.containers[*].length(name)", "`1`", "{a: spec}", "!spec", "spec.containers | sort_by(@, &name)[0]"} {
+		t.Run(expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser().Parse(expression)
+			assert.NoError(err)
+			_, err = Search(context.Background(), ast, map[string]any{"spec": map[string]any{"containers": []any{map[string]any{}}}})
+			var jpErr *jperror.Error
+			assert.True(errors.As(err, &jpErr))
+			assert.Equal(jperror.Unsupported, jpErr.Kind)
+			assert.True(jpErr.HasLocation())
+		})
+	}
+}
+
+func TestSearchFilterErrors(t *testing.T) {
+	data := map[string]any{"a": []any{map[string]any{"b": "x"}}, "c": []any{[]any{1.0}}}
+	tests := []struct {
+		expression string
+		wantErr    bool
+	}{
+		{"(a[?abs(b)])[?b]", false},
+		{"(c[::0])[?@]", false},
+		{"a[?abs(b)]", true},
+		{"abs(a)[?b]", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			_, wantErr := interpreter.NewInterpreter(data, nil).Execute(ast, data)
+			matches, err := Search(context.Background(), ast, data)
+			if tt.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(wantErr)
+			assert.NoError(err)
+			assert.Equal([]Match{}, matches)
+		})
+	}
+	assert := assert.New(t)
+	ast, err := parsing.NewParser().Parse("(a[?abs(b)])[?b]")
+	assert.NoError(err)
+	_, err = Search(context.Background(), ast, data, interpreter.WithMaxSteps(1))
+	var budgetErr *interpreter.BudgetExceededError
+	assert.ErrorAs(err, &budgetErr)
+}
+
+func TestSearchStructsWithOptions(t *testing.T) {
+	type container struct {
+		Name string `json:"name"`
+	}
+	assert := assert.New(t)
+	ast, err := parsing.NewParser().Parse("containers[*].name")
+	assert.NoError(err)
+	profile := interpreter.NewProfile()
+	matches, err := Search(context.Background(), ast, map[string]any{"containers": []any{container{Name: "web"}}}, interpreter.WithProfile(profile))
+	assert.NoError(err)
+	assert.Equal([]Match{{Path: Path{"containers", 0, "name"}, Value: "web"}}, matches)
+	// the struct fields are resolved with the options
+	assert.Len(profile.Report(), 1)
+}
+
+func TestSearchCanceled(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ast, err := parsing.NewParser().Parse("a[*]")
+	assert.NoError(err)
+	_, err = Search(ctx, ast, map[string]any{"a": []any{1.0}})
+	assert.ErrorIs(err, context.Canceled)
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		path    Path
+		str     string
+		pointer string
+	}{
+		{Path{}, "@", ""},
+		{Path{"foo", "bar", 3, "baz"}, "foo.bar[3].baz", "/foo/bar/3/baz"},
+		{Path{0, 1}, "[0][1]", "/0/1"},
+		{Path{"a/b", "c~d", "", "_x1"}, `"a/b"."c~d"."".` + "_x1", "/a~1b/c~0d//_x1"},
+		{Path{"1a", `q"`}, `"1a"."q\""`, `/1a/q"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.str, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tt.str, tt.path.String())
+			assert.Equal(tt.pointer, tt.path.Pointer())
+		})
+	}
+}
@@ -0,0 +1,363 @@
+// Package mutate changes the values of a document addressed by an expression.
+package mutate
+
+import (
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/jmespath-community/go-jmespath/pkg/util"
+)
+
+// Set sets the values addressed by an expression and returns the document. Missing fields
+// are added, and objects are created for missing fields in the middle of the path, but
+// arrays are not extended: indexes out of range are ignored. See Update for the expressions
+// that can address values.
+func Set(data any, node parsing.ASTNode, value any, opts ...Option) (any, error) {
+	m := newMutator(data, opts)
+	m.create = true
+	return m.apply(node, func(any, bool) (any, action, error) {
+		return value, replace, nil
+	})
+}
+
+// Update replaces the values addressed by an expression with the result of a function and
+// returns the document. The function is not called for missing values. The expression
+// can be made of fields, indexes, slices, flatten, projections, value projections, filter
+// projections and sub-expressions, like `spec.containers[?privileged].securityContext`.
+// Filter conditions can be any expression, they are evaluated with the interpreter options,
+// see WithInterpreterOptions. Other expressions return an error of kind jperror.Unsupported.
+//
+// Documents are made of objects, map[string]any or *ordered.Object, and []any arrays,
+// which are modified in place unless WithCopyOnWrite is given. The document
+// returned must be used, arrays are copied when elements are removed and the root is
+// replaced when the expression is `@`.
+func Update(data any, node parsing.ASTNode, update func(any) any, opts ...Option) (any, error) {
+	m := newMutator(data, opts)
+	return m.apply(node, func(value any, found bool) (any, action, error) {
+		if !found {
+			return value, keep, nil
+		}
+		return update(value), replace, nil
+	})
+}
+
+// Delete removes the values addressed by an expression from their objects or arrays and
+// returns the document, see Update. Deleting the root returns nil.
+func Delete(data any, node parsing.ASTNode, opts ...Option) (any, error) {
+	m := newMutator(data, opts)
+	return m.apply(node, func(value any, found bool) (any, action, error) {
+		if !found {
+			return value, keep, nil
+		}
+		return nil, remove, nil
+	})
+}
+
+// action is what to do with a value after applying an operation.
+type action int
+
+const (
+	// keep leaves the value unchanged.
+	keep action = iota
+	// replace stores the result in place of the value.
+	replace
+	// remove removes the value from its object or array.
+	remove
+)
+
+// operation changes a value, found is false when the value is missing.
+type operation func(value any, found bool) (any, action, error)
+
+type mutator struct {
+	root any
+	opts []interpreter.Option
+	// create makes fields of missing objects create the objects.
+	create bool
+	// copy makes changes copy the objects and arrays they modify.
+	copy bool
+}
+
+func newMutator(data any, opts []Option) *mutator {
+	o := newOptions(opts...)
+	return &mutator{root: data, opts: o.Interpreter, copy: o.CopyOnWrite}
+}
+
+func (m *mutator) apply(node parsing.ASTNode, op operation) (any, error) {
+	result, a, err := m.update(node, m.root, true, op)
+	if err != nil {
+		return nil, err
+	}
+	switch a {
+	case replace:
+		return result, nil
+	case remove:
+		return nil, nil
+	}
+	return m.root, nil
+}
+
+// update applies an operation to the values a node addresses in a value, and returns
+// what to do with the value.
+func (m *mutator) update(node parsing.ASTNode, value any, found bool, op operation) (any, action, error) {
+	switch node.NodeType {
+	case parsing.ASTIdentity, parsing.ASTCurrentNode:
+		return op(value, found)
+	case parsing.ASTField:
+		return m.field(node.Value.(string), value, op)
+	case parsing.ASTIndex:
+		return m.index(node.Value.(int), value, op)
+	case parsing.ASTSlice:
+		return m.elements(value, node.Value.([]*int), op)
+	case parsing.ASTSubexpression, parsing.ASTIndexExpression:
+		return m.update(node.Children[0], value, found, func(left any, found bool) (any, action, error) {
+			return m.update(node.Children[1], left, found, op)
+		})
+	case parsing.ASTFlatten:
+		flatten := func(element any, found bool) (any, action, error) {
+			if _, ok := element.([]any); ok {
+				return m.elements(element, nil, op)
+			}
+			return op(element, found)
+		}
+		if yieldsElements(node.Children[0]) {
+			return m.update(node.Children[0], value, found, flatten)
+		}
+		return m.update(node.Children[0], value, found, func(left any, found bool) (any, action, error) {
+			return m.elements(left, nil, flatten)
+		})
+	case parsing.ASTProjection:
+		left, right := node.Children[0], node.Children[1]
+		project := func(element any, found bool) (any, action, error) {
+			return m.update(right, element, found, op)
+		}
+		if yieldsElements(left) {
+			return m.update(left, value, found, project)
+		}
+		return m.update(left, value, found, func(left any, found bool) (any, action, error) {
+			return m.elements(left, nil, project)
+		})
+	case parsing.ASTValueProjection:
+		right := node.Children[1]
+		return m.update(node.Children[0], value, found, func(left any, found bool) (any, action, error) {
+			return m.values(left, func(element any, found bool) (any, action, error) {
+				return m.update(right, element, found, op)
+			})
+		})
+	case parsing.ASTFilterProjection:
+		right, condition := node.Children[1], node.Children[2]
+		return m.update(node.Children[0], value, found, func(left any, found bool) (any, action, error) {
+			return m.elements(left, nil, func(element any, found bool) (any, action, error) {
+				matches, err := interpreter.NewInterpreter(m.root, nil).Execute(condition, element, m.opts...)
+				if err != nil {
+					return nil, keep, err
+				}
+				if util.IsFalse(matches) {
+					return element, keep, nil
+				}
+				return m.update(right, element, found, op)
+			})
+		})
+	}
+	return nil, keep, jperror.New(jperror.Unsupported, "cannot change values addressed by the expression").Locate(node.Start, node.End)
+}
+
+// field applies an operation to a field of an object.
+func (m *mutator) field(name string, value any, op operation) (any, action, error) {
+	switch object := value.(type) {
+	case map[string]any:
+		current, found := object[name]
+		result, a, err := op(current, found)
+		if err != nil || a == keep {
+			return value, keep, err
+		}
+		object = m.own(object).(map[string]any)
+		if a == remove {
+			delete(object, name)
+		} else {
+			object[name] = result
+		}
+		return object, replace, nil
+	case *ordered.Object:
+		current, found := object.Get(name)
+		result, a, err := op(current, found)
+		if err != nil || a == keep {
+			return value, keep, err
+		}
+		object = m.own(object).(*ordered.Object)
+		if a == remove {
+			object.Delete(name)
+		} else {
+			object.Set(name, result)
+		}
+		return object, replace, nil
+	case nil:
+		if !m.create {
+			return value, keep, nil
+		}
+		result, a, err := op(nil, false)
+		if err != nil || a != replace {
+			return value, keep, err
+		}
+		return map[string]any{name: result}, replace, nil
+	}
+	return value, keep, nil
+}
+
+// index applies an operation to an element of an array.
+func (m *mutator) index(i int, value any, op operation) (any, action, error) {
+	array, ok := value.([]any)
+	if !ok {
+		return value, keep, nil
+	}
+	if i < 0 {
+		i += len(array)
+	}
+	if i < 0 || i >= len(array) {
+		return value, keep, nil
+	}
+	result, a, err := op(array[i], true)
+	switch {
+	case err != nil || a == keep:
+		return value, keep, err
+	case a == remove:
+		return append(array[:i:i], array[i+1:]...), replace, nil
+	}
+	array = m.own(array).([]any)
+	array[i] = result
+	return array, replace, nil
+}
+
+// elements applies an operation to the elements of an array, or to those selected by
+// slice parts.
+func (m *mutator) elements(value any, parts []*int, op operation) (any, action, error) {
+	array, ok := value.([]any)
+	if !ok {
+		return value, keep, nil
+	}
+	indexes := make([]int, len(array))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	if parts != nil {
+		var err error
+		if indexes, err = util.Slice(indexes, util.MakeSliceParams(parts)); err != nil {
+			return value, keep, err
+		}
+	}
+	changed := false
+	removed := make(map[int]bool)
+	for _, i := range indexes {
+		result, a, err := op(array[i], true)
+		if err != nil {
+			return value, keep, err
+		}
+		switch a {
+		case replace:
+			if !changed {
+				array = m.own(array).([]any)
+			}
+			array[i] = result
+			changed = true
+		case remove:
+			removed[i] = true
+		}
+	}
+	if len(removed) != 0 {
+		kept := make([]any, 0, len(array)-len(removed))
+		for i, element := range array {
+			if !removed[i] {
+				kept = append(kept, element)
+			}
+		}
+		return kept, replace, nil
+	}
+	if changed {
+		return array, replace, nil
+	}
+	return value, keep, nil
+}
+
+// values applies an operation to the values of an object.
+func (m *mutator) values(value any, op operation) (any, action, error) {
+	keys, ok := util.ObjectKeys(value)
+	if !ok {
+		return value, keep, nil
+	}
+	changed := false
+	for _, key := range keys {
+		var current any
+		switch object := value.(type) {
+		case map[string]any:
+			current = object[key]
+		case *ordered.Object:
+			current, _ = object.Get(key)
+		}
+		result, a, err := op(current, true)
+		if err != nil {
+			return value, keep, err
+		}
+		if a == keep {
+			continue
+		}
+		if !changed {
+			value = m.own(value)
+			changed = true
+		}
+		switch object := value.(type) {
+		case map[string]any:
+			if a == remove {
+				delete(object, key)
+			} else {
+				object[key] = result
+			}
+		case *ordered.Object:
+			if a == remove {
+				object.Delete(key)
+			} else {
+				object.Set(key, result)
+			}
+		}
+	}
+	if changed {
+		return value, replace, nil
+	}
+	return value, keep, nil
+}
+
+// own returns the object or array to modify, a shallow copy of it with copy on write.
+func (m *mutator) own(value any) any {
+	if !m.copy {
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for key, element := range v {
+			copied[key] = element
+		}
+		return copied
+	case *ordered.Object:
+		copied := ordered.NewObject(v.Len())
+		for _, key := range v.Keys() {
+			element, _ := v.Get(key)
+			copied.Set(key, element)
+		}
+		return copied
+	case []any:
+		return append(make([]any, 0, len(v)), v...)
+	}
+	return value
+}
+
+// yieldsElements reports whether a node applies operations to the elements of the array
+// it addresses rather than to the array.
+func yieldsElements(node parsing.ASTNode) bool {
+	switch node.NodeType {
+	case parsing.ASTProjection, parsing.ASTFilterProjection, parsing.ASTValueProjection, parsing.ASTFlatten:
+		return true
+	case parsing.ASTIndexExpression:
+		return node.Children[1].NodeType == parsing.ASTSlice
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+package mutate
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+const document = `{
+	"spec": {
+		"containers": [
+			{"name": "web", "privileged": false, "ports": [80, 443]},
+			{"name": "agent", "privileged": true, "ports": []},
+			{"name": "proxy", "privileged": true, "ports": [8080]}
+		],
+		"volumes": {"data": {"size": 10}, "logs": {"size": 2}}
+	},
+	"matrix": [[1, 2], [3], 4]
+}`
+
+func parse(t *testing.T, s string) any {
+	var data any
+	assert.NoError(t, json.Unmarshal([]byte(s), &data))
+	return data
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		expression string
+		value      any
+		want       string
+	}{
+		{"spec.containers[0].name", "www", `{"containers": [{"name": "www"}, {"name": "agent"}, {"name": "proxy"}]}`},
+		{"spec.containers[-1].image", "nginx", `{"containers": [{"name": "web"}, {"name": "agent"}, {"name": "proxy", "image": "nginx"}]}`},
+		{"spec.containers[5].name", "none", `{"containers": [{"name": "web"}, {"name": "agent"}, {"name": "proxy"}]}`},
+		{"spec.containers[*].name", "x", `{"containers": [{"name": "x"}, {"name": "x"}, {"name": "x"}]}`},
+		{"spec.containers[?name != 'web'].name", "x", `{"containers": [{"name": "web"}, {"name": "x"}, {"name": "x"}]}`},
+		{"spec.containers[1:].name", "x", `{"containers": [{"name": "web"}, {"name": "x"}, {"name": "x"}]}`},
+		{"spec.containers[::-2].name", "x", `{"containers": [{"name": "x"}, {"name": "agent"}, {"name": "x"}]}`},
+		{"spec.containers[*].labels.app", "shop", `{"containers": [{"name": "web", "labels": {"app": "shop"}}, {"name": "agent", "labels": {"app": "shop"}}, {"name": "proxy", "labels": {"app": "shop"}}]}`},
+		{"spec.containers", "none", `{"containers": "none"}`},
+		{"spec.containers[0]", "none", `{"containers": ["none", {"name": "agent"}, {"name": "proxy"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			data := parse(t, `{"spec": {"containers": [{"name": "web"}, {"name": "agent"}, {"name": "proxy"}]}}`)
+			ast, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			result, err := Set(data, ast, tt.value)
+			assert.NoError(err)
+			assert.Equal(parse(t, `{"spec": `+tt.want+`}`), result)
+		})
+	}
+}
+
+func TestSetCreatesObjects(t *testing.T) {
+	assert := assert.New(t)
+	ast, err := parsing.NewParser().Parse("metadata.labels.app")
+	assert.NoError(err)
+	result, err := Set(map[string]any{}, ast, "shop")
+	assert.NoError(err)
+	assert.Equal(map[string]any{"metadata": map[string]any{"labels": map[string]any{"app": "shop"}}}, result)
+	result, err = Set(nil, ast, "shop")
+	assert.NoError(err)
+	assert.Equal(map[string]any{"metadata": map[string]any{"labels": map[string]any{"app": "shop"}}}, result)
+	// arrays are not created
+	ast, err = parsing.NewParser().Parse("items[0].name")
+	assert.NoError(err)
+	result, err = Set(map[string]any{}, ast, "x")
+	assert.NoError(err)
+	assert.Equal(map[string]any{}, result)
+	// the root is replaced
+	ast, err = parsing.NewParser().Parse("@")
+	assert.NoError(err)
+	result, err = Set(map[string]any{}, ast, "x")
+	assert.NoError(err)
+	assert.Equal("x", result)
+}
+
+func TestUpdate(t *testing.T) {
+	double := func(v any) any {
+		if n, ok := v.(float64); ok {
+			return n * 2
+		}
+		return v
+	}
+	containers := func(web string, agent string, proxy string) string {
+		return `[` +
+			`{"name": "web", "privileged": false, "ports": ` + web + `},` +
+			`{"name": "agent", "privileged": true, "ports": ` + agent + `},` +
+			`{"name": "proxy", "privileged": true, "ports": ` + proxy + `}]`
+	}
+	tests := []struct {
+		expression string
+		// key is the dotted path of the part of the document compared with want
+		key  string
+		want string
+	}{
+		{"spec.volumes.*.size", "spec.volumes", `{"data": {"size": 20}, "logs": {"size": 4}}`},
+		{"spec.volumes.data.size", "spec.volumes", `{"data": {"size": 20}, "logs": {"size": 2}}`},
+		{"spec.volumes.cache.size", "spec.volumes", `{"data": {"size": 10}, "logs": {"size": 2}}`},
+		{"spec.containers[*].ports[*]", "spec.containers", containers(`[160, 886]`, `[]`, `[16160]`)},
+		{"spec.containers[*].ports[]", "spec.containers", containers(`[160, 886]`, `[]`, `[16160]`)},
+		{"spec.containers[?privileged].ports[0]", "spec.containers", containers(`[80, 443]`, `[]`, `[16160]`)},
+		{"matrix[]", "matrix", `[[2, 4], [6], 8]`},
+		{"matrix[][]", "matrix", `[[2, 4], [6], 8]`},
+		{"matrix[0][1]", "matrix", `[[1, 4], [3], 4]`},
+		{"matrix[*][0]", "matrix", `[[2, 2], [6], 4]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			data := parse(t, document)
+			ast, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			result, err := Update(data, ast, double)
+			assert.NoError(err)
+			var actual any = result
+			for _, key := range strings.Split(tt.key, ".") {
+				actual = actual.(map[string]any)[key]
+			}
+			assert.Equal(parse(t, tt.want), actual)
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"spec.volumes.logs", `{"spec": {"volumes": {"data": {}}, "containers": [{"name": "web", "privileged": false}, {"name": "agent", "privileged": true}]}, "matrix": [[1, 2], [3], 4]}`},
+		{"spec.containers[?privileged]", `{"spec": {"volumes": {"data": {}, "logs": {}}, "containers": [{"name": "web", "privileged": false}]}, "matrix": [[1, 2], [3], 4]}`},
+		{"spec.containers[*].privileged", `{"spec": {"volumes": {"data": {}, "logs": {}}, "containers": [{"name": "web"}, {"name": "agent"}]}, "matrix": [[1, 2], [3], 4]}`},
+		{"spec.containers[-1]", `{"spec": {"volumes": {"data": {}, "logs": {}}, "containers": [{"name": "web", "privileged": false}]}, "matrix": [[1, 2], [3], 4]}`},
+		{"spec.*", `{"spec": {}, "matrix": [[1, 2], [3], 4]}`},
+		{"spec.missing", `{"spec": {"volumes": {"data": {}, "logs": {}}, "containers": [{"name": "web", "privileged": false}, {"name": "agent", "privileged": true}]}, "matrix": [[1, 2], [3], 4]}`},
+		{"matrix[]", `{"spec": {"volumes": {"data": {}, "logs": {}}, "containers": [{"name": "web", "privileged": false}, {"name": "agent", "privileged": true}]}, "matrix": [[], []]}`},
+		{"matrix[0:2]", `{"spec": {"volumes": {"data": {}, "logs": {}}, "containers": [{"name": "web", "privileged": false}, {"name": "agent", "privileged": true}]}, "matrix": [4]}`},
+		{"matrix[*][0]", `{"spec": {"volumes": {"data": {}, "logs": {}}, "containers": [{"name": "web", "privileged": false}, {"name": "agent", "privileged": true}]}, "matrix": [[2], [], 4]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			data := parse(t, `{"spec": {"volumes": {"data": {}, "logs": {}}, "containers": [{"name": "web", "privileged": false}, {"name": "agent", "privileged": true}]}, "matrix": [[1, 2], [3], 4]}`)
+			ast, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			result, err := Delete(data, ast)
+			assert.NoError(err)
+			assert.Equal(parse(t, tt.want), result)
+		})
+	}
+}
+
+func TestDeleteRoot(t *testing.T) {
+	assert := assert.New(t)
+	ast, err := parsing.NewParser().Parse("@")
+	assert.NoError(err)
+	result, err := Delete(map[string]any{"a": 1.0}, ast)
+	assert.NoError(err)
+	assert.Nil(result)
+}
+
+func TestOrderedObject(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ordered.Unmarshal([]byte(`{"b": {"y": 1, "x": 2}, "a": 3}`))
+	assert.NoError(err)
+	ast, err := parsing.NewParser().Parse("b.z")
+	assert.NoError(err)
+	data, err = Set(data, ast, 0.0)
+	assert.NoError(err)
+	ast, err = parsing.NewParser().Parse("b.y")
+	assert.NoError(err)
+	data, err = Set(data, ast, 4.0)
+	assert.NoError(err)
+	ast, err = parsing.NewParser().Parse("a")
+	assert.NoError(err)
+	data, err = Delete(data, ast)
+	assert.NoError(err)
+	actual, err := json.Marshal(data)
+	assert.NoError(err)
+	assert.Equal(`{"b":{"y":4,"x":2,"z":0}}`, string(actual))
+}
+
+func TestCopyOnWrite(t *testing.T) {
+	double := func(v any) any {
+		if n, ok := v.(float64); ok {
+			return n * 2
+		}
+		return v
+	}
+	operations := map[string]func(any, parsing.ASTNode, ...Option) (any, error){
+		"set": func(data any, ast parsing.ASTNode, opts ...Option) (any, error) {
+			return Set(data, ast, "x", opts...)
+		},
+		"update": func(data any, ast parsing.ASTNode, opts ...Option) (any, error) {
+			return Update(data, ast, double, opts...)
+		},
+		"delete": Delete,
+	}
+	expressions := []string{
+		"spec.containers[0].name",
+		"spec.containers[*].ports[*]",
+		"spec.containers[?privileged].ports[0]",
+		"spec.containers[1:].labels.app",
+		"spec.containers[-1]",
+		"spec.volumes.*.size",
+		"spec.*",
+		"matrix[]",
+		"matrix[*][0]",
+		"matrix[0:2]",
+		"missing.field",
+		"@",
+	}
+	for name, operation := range operations {
+		for _, expression := range expressions {
+			t.Run(name+" "+expression, func(t *testing.T) {
+				assert := assert.New(t)
+				ast, err := parsing.NewParser().Parse(expression)
+				assert.NoError(err)
+				want, err := operation(parse(t, document), ast)
+				assert.NoError(err)
+				data := parse(t, document)
+				result, err := operation(data, ast, WithCopyOnWrite())
+				assert.NoError(err)
+				assert.Equal(want, result)
+				assert.Equal(parse(t, document), data)
+			})
+		}
+	}
+}
+
+func TestCopyOnWriteSharesUnchangedValues(t *testing.T) {
+	assert := assert.New(t)
+	data := parse(t, document).(map[string]any)
+	ast, err := parsing.NewParser().Parse("spec.volumes.data.size")
+	assert.NoError(err)
+	result, err := Set(data, ast, 1.0, WithCopyOnWrite())
+	assert.NoError(err)
+	spec, changed := data["spec"].(map[string]any), result.(map[string]any)["spec"].(map[string]any)
+	assert.Equal(10.0, spec["volumes"].(map[string]any)["data"].(map[string]any)["size"])
+	assert.Equal(1.0, changed["volumes"].(map[string]any)["data"].(map[string]any)["size"])
+	assert.Same(&data["matrix"].([]any)[0], &result.(map[string]any)["matrix"].([]any)[0])
+	assert.Same(&spec["containers"].([]any)[0], &changed["containers"].([]any)[0])
+}
+
+func TestInterpreterOptions(t *testing.T) {
+	assert := assert.New(t)
+	ast, err := parsing.NewParser().Parse("items[?id == $id].name")
+	assert.NoError(err)
+	data := map[string]any{"items": []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}}}
+	variables := WithInterpreterOptions(interpreter.WithVariables(map[string]any{"id": 2.0}))
+	result, err := Set(data, ast, "b", variables, WithCopyOnWrite())
+	assert.NoError(err)
+	assert.Equal(map[string]any{"items": []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0, "name": "b"}}}, result)
+	assert.Equal(map[string]any{"items": []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}}}, data)
+	_, err = Set(data, ast, "b")
+	assert.Error(err)
+}
+
+func TestCopyOnWriteOrderedObject(t *testing.T) {
+	assert := assert.New(t)
+	data, err := ordered.Unmarshal([]byte(`{"b": {"y": 1, "x": 2}, "a": 3}`))
+	assert.NoError(err)
+	ast, err := parsing.NewParser().Parse("b.*")
+	assert.NoError(err)
+	result, err := Delete(data, ast, WithCopyOnWrite())
+	assert.NoError(err)
+	actual, err := json.Marshal(result)
+	assert.NoError(err)
+	assert.Equal(`{"b":{},"a":3}`, string(actual))
+	actual, err = json.Marshal(data)
+	assert.NoError(err)
+	assert.Equal(`{"b":{"y":1,"x":2},"a":3}`, string(actual))
+}
+
+func TestUnsupported(t *testing.T) {
+	for _, expression := range []string{"length(a)", "a | b", "$.a", "a || b", "[a, b]", "a[*].to_string(@)"} {
+		t.Run(expression, func(t *testing.T) {
+			assert := assert.New(t)
+			ast, err := parsing.NewParser().Parse(expression)
+			assert.NoError(err)
+			_, err = Set(map[string]any{"a": []any{1.0}}, ast, 1.0)
+			var jpErr *jperror.Error
+			assert.True(errors.As(err, &jpErr))
+			assert.Equal(jperror.Unsupported, jpErr.Kind)
+		})
+	}
+}
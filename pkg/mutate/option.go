@@ -0,0 +1,43 @@
+package mutate
+
+import "github.com/jmespath-community/go-jmespath/pkg/interpreter"
+
+// Option configures Set, Update and Delete.
+type Option func(Options) Options
+
+type Options struct {
+	// CopyOnWrite makes changes copy the objects and arrays they modify.
+	CopyOnWrite bool
+	// Interpreter are the options evaluating filter conditions.
+	Interpreter []interpreter.Option
+}
+
+func newOptions(opts ...Option) Options {
+	var o Options
+	for _, opt := range opts {
+		if opt != nil {
+			o = opt(o)
+		}
+	}
+	return o
+}
+
+// WithCopyOnWrite leaves the document unchanged: the objects and arrays on the paths
+// of the values that change are copied, the other values are shared by the document
+// and the returned one.
+func WithCopyOnWrite() Option {
+	return func(o Options) Options {
+		o.CopyOnWrite = true
+		return o
+	}
+}
+
+// WithInterpreterOptions evaluates filter conditions with the given options, like
+// interpreter.WithVariables or interpreter.WithFunctionCaller. Options given several
+// times are applied in order.
+func WithInterpreterOptions(opts ...interpreter.Option) Option {
+	return func(o Options) Options {
+		o.Interpreter = append(o.Interpreter[:len(o.Interpreter):len(o.Interpreter)], opts...)
+		return o
+	}
+}
@@ -0,0 +1,219 @@
+// Package optimizer rewrites parsed ASTs into equivalent ASTs that are cheaper to evaluate.
+package optimizer
+
+import (
+	"encoding/json"
+
+	"github.com/jmespath-community/go-jmespath/pkg/functions"
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/jmespath-community/go-jmespath/pkg/util"
+)
+
+var defaultFunctions = map[string]bool{}
+
+func init() {
+	for _, entry := range functions.GetDefaultFunctions() {
+		defaultFunctions[entry.Name] = true
+	}
+	// the objects they build depend on interpreter.WithOrderedObjects
+	for _, name := range []string{"from_items", "merge"} {
+		delete(defaultFunctions, name)
+	}
+}
+
+// Option configures the optimizer.
+type Option func(Options) Options
+
+type Options struct {
+	// Precision is set when the precision of the evaluations is known.
+	Precision bool
+	// ArbitraryPrecision is set when expressions are evaluated with interpreter.WithArbitraryPrecision.
+	ArbitraryPrecision bool
+}
+
+// WithPrecision declares whether the optimized expression is evaluated with
+// interpreter.WithArbitraryPrecision. Operations producing numbers are only folded
+// when the precision is declared, their results depend on it.
+func WithPrecision(arbitrary bool) Option {
+	return func(o Options) Options {
+		o.Precision = true
+		o.ArbitraryPrecision = arbitrary
+		return o
+	}
+}
+
+// Optimize returns an AST equivalent to the given one that is cheaper to evaluate.
+// It folds operations on literals, removes redundant references to the current node,
+// collapses nested pipes and pre-evaluates calls to default functions whose arguments
+// are all literals. Function calls are assumed to use the default implementations.
+// Operations failing on their literals are kept, so that the error is reported when
+// the expression is evaluated, and so are operations producing numbers unless the
+// precision is declared, see WithPrecision. The given AST is not modified.
+func Optimize(node parsing.ASTNode, opts ...Option) parsing.ASTNode {
+	var o Options
+	for _, opt := range opts {
+		if opt != nil {
+			o = opt(o)
+		}
+	}
+	return optimize(node, o)
+}
+
+func optimize(node parsing.ASTNode, o Options) parsing.ASTNode {
+	if len(node.Children) != 0 {
+		children := make([]parsing.ASTNode, len(node.Children))
+		for i, child := range node.Children {
+			children[i] = optimize(child, o)
+		}
+		node.Children = children
+	}
+	switch node.NodeType {
+	case parsing.ASTPipe:
+		return collapsePipe(node)
+	case parsing.ASTSubexpression:
+		// @.foo is foo, as long as foo is null when @ is null
+		if isCurrent(node.Children[0]) && preservesNull(node.Children[1]) {
+			return node.Children[1]
+		}
+	case parsing.ASTIndexExpression:
+		// the right hand side of projections like foo[*][0] is @[0], which is [0],
+		// slices are kept since projections of sliced strings depend on this shape
+		if isCurrent(node.Children[0]) && node.Children[1].NodeType == parsing.ASTIndex {
+			return node.Children[1]
+		}
+	case parsing.ASTOrExpression:
+		if left := node.Children[0]; left.NodeType == parsing.ASTLiteral {
+			if util.IsFalse(left.Value) {
+				return node.Children[1]
+			}
+			return left
+		}
+	case parsing.ASTAndExpression:
+		if left := node.Children[0]; left.NodeType == parsing.ASTLiteral {
+			if util.IsFalse(left.Value) {
+				return left
+			}
+			return node.Children[1]
+		}
+	case parsing.ASTArithmeticExpression, parsing.ASTArithmeticUnaryExpression, parsing.ASTComparator, parsing.ASTNotExpression:
+		if allLiterals(node.Children, o) {
+			return fold(node, o)
+		}
+	case parsing.ASTFunctionExpression:
+		if defaultFunctions[node.Value.(string)] && allLiterals(node.Children, o) {
+			return fold(node, o)
+		}
+	}
+	return node
+}
+
+// collapsePipe merges nested pipes into a single pipe and drops the references to
+// the current node, which pass their input through.
+func collapsePipe(node parsing.ASTNode) parsing.ASTNode {
+	var children []parsing.ASTNode
+	for _, child := range node.Children {
+		switch {
+		case child.NodeType == parsing.ASTPipe:
+			children = append(children, child.Children...)
+		case !isCurrent(child):
+			children = append(children, child)
+		}
+	}
+	switch len(children) {
+	case 0:
+		return node.Children[0]
+	case 1:
+		return children[0]
+	}
+	node.Children = children
+	return node
+}
+
+// fold replaces a node by the literal it evaluates to. Results holding numbers
+// are only folded when the precision is declared.
+func fold(node parsing.ASTNode, o Options) parsing.ASTNode {
+	var opts []interpreter.Option
+	if o.ArbitraryPrecision {
+		opts = append(opts, interpreter.WithArbitraryPrecision())
+	}
+	result, err := interpreter.NewInterpreter(nil, nil).Execute(node, nil, opts...)
+	if err != nil || (!o.Precision && hasNumber(result)) {
+		return node
+	}
+	return parsing.ASTNode{
+		NodeType: parsing.ASTLiteral,
+		Value:    result,
+		Start:    node.Start,
+		End:      node.End,
+	}
+}
+
+// allLiterals reports whether nodes are literals that can be folded. Unless the
+// precision is declared, literals holding json.Number values are not folded, their
+// evaluation depends on the precision.
+func allLiterals(nodes []parsing.ASTNode, o Options) bool {
+	for _, node := range nodes {
+		if node.NodeType != parsing.ASTLiteral || (!o.Precision && hasJSONNumber(node.Value)) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasJSONNumber(value any) bool {
+	switch v := value.(type) {
+	case json.Number:
+		return true
+	case []any:
+		for _, item := range v {
+			if hasJSONNumber(item) {
+				return true
+			}
+		}
+	case map[string]any:
+		for _, item := range v {
+			if hasJSONNumber(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasNumber reports whether a value holds numbers.
+func hasNumber(value any) bool {
+	switch v := value.(type) {
+	case float64, json.Number:
+		return true
+	case []any:
+		for _, item := range v {
+			if hasNumber(item) {
+				return true
+			}
+		}
+	case map[string]any:
+		for _, item := range v {
+			if hasNumber(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isCurrent(node parsing.ASTNode) bool {
+	return node.NodeType == parsing.ASTCurrentNode || node.NodeType == parsing.ASTIdentity
+}
+
+// preservesNull reports whether a node always evaluates to null against null.
+func preservesNull(node parsing.ASTNode) bool {
+	switch node.NodeType {
+	case parsing.ASTField, parsing.ASTIndex, parsing.ASTCurrentNode, parsing.ASTIdentity:
+		return true
+	case parsing.ASTSubexpression, parsing.ASTIndexExpression, parsing.ASTProjection,
+		parsing.ASTFilterProjection, parsing.ASTValueProjection, parsing.ASTFlatten:
+		return preservesNull(node.Children[0])
+	}
+	return false
+}
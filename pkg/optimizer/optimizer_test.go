@@ -0,0 +1,193 @@
+package optimizer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"foo.bar", "foo.bar"},
+		{"`1` + `2`", "`3`"},
+		{"`1` + `2` * `3`", "`7`"},
+		{"foo + `2` * `3`", "foo + `6`"},
+		{"-`2`", "`-2`"},
+		{"`1` < `2`", "`true`"},
+		{"'a' == 'b'", "`false`"},
+		{"!`[]`", "`true`"},
+		{"`true` || foo", "`true`"},
+		{"`false` || foo", "foo"},
+		{"`null` && foo", "`null`"},
+		{"'a' && foo", "foo"},
+		{"length('abc')", "`3`"},
+		{"upper('abc')", "'ABC'"},
+		{"join(', ', ['a', 'b'])", "join(', ', ['a', 'b'])"},
+		{"join(', ', `[\"a\", \"b\"]`)", "'a, b'"},
+		{"length(foo)", "length(foo)"},
+		{"abs('x')", "abs('x')"},
+		{"unknown(`1`)", "unknown(`1`)"},
+		{"@.foo", "foo"},
+		{"@.foo[0].bar", "foo[0].bar"},
+		{"@.length(@)", "@.length(@)"},
+		{"@.[foo]", "@.[foo]"},
+		{"foo | @", "foo"},
+		{"@ | foo", "foo"},
+		{"@ | @", "@"},
+		{"a | b | c", "a | b | c"},
+		{"a | (b | c) | d", "a | b | c | d"},
+		{"(a | b) | (c | d)", "a | b | c | d"},
+		{"foo[?bar == `1` + `1`]", "foo[?bar == `2`]"},
+		{"map(&(`1` + `1`), foo)", "map(&`2`, foo)"},
+		{"foo[*][0]", "foo[*][0]"},
+		{"foo[?bar][-1].baz", "foo[?bar][-1].baz"},
+		{"*[0]", "*[0]"},
+		{"[0]", "[0]"},
+		{"foo[*][1:2]", "foo[*][1:2]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			node, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, parsing.Format(Optimize(node, WithPrecision(false))))
+		})
+	}
+}
+
+func TestOptimizeRemovesIdentityIndexes(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       parsing.ASTNode
+	}{{
+		expression: "foo[*][0]",
+		want: parsing.ASTNode{NodeType: parsing.ASTProjection, Children: []parsing.ASTNode{
+			{NodeType: parsing.ASTField, Value: "foo"},
+			{NodeType: parsing.ASTIndex, Value: 0},
+		}},
+	}, {
+		expression: "[0]",
+		want:       parsing.ASTNode{NodeType: parsing.ASTIndex, Value: 0},
+	}, {
+		expression: "foo[*][1:2]",
+		want: parsing.ASTNode{NodeType: parsing.ASTProjection, Children: []parsing.ASTNode{
+			{NodeType: parsing.ASTField, Value: "foo"},
+			{NodeType: parsing.ASTProjection, Children: []parsing.ASTNode{
+				{NodeType: parsing.ASTIndexExpression, Children: []parsing.ASTNode{
+					{NodeType: parsing.ASTIdentity},
+					{NodeType: parsing.ASTSlice},
+				}},
+				{NodeType: parsing.ASTIdentity},
+			}},
+		}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			node, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(t, err)
+			assert.Equal(t, shape(tt.want), shape(Optimize(node)))
+		})
+	}
+}
+
+// shape returns the node types and the field and index values of an AST.
+func shape(node parsing.ASTNode) any {
+	var children []any
+	for _, child := range node.Children {
+		children = append(children, shape(child))
+	}
+	value := node.Value
+	if node.NodeType != parsing.ASTField && node.NodeType != parsing.ASTIndex {
+		value = nil
+	}
+	return []any{node.NodeType.String(), value, children}
+}
+
+func TestOptimizeWithoutPrecision(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"`1` + `2`", "`1` + `2`"},
+		{"-`2`", "-`2`"},
+		{"length('abc')", "length('abc')"},
+		{"sort(`[2,1]`)", "sort(`[2,1]`)"},
+		{"`1` < `2`", "`true`"},
+		{"upper('abc')", "'ABC'"},
+		{"to_string(`1`)", "'1'"},
+		{"foo[?bar == `1` + `1`]", "foo[?bar == `1` + `1`]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			node, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, parsing.Format(Optimize(node)))
+		})
+	}
+}
+
+func TestOptimizeWithArbitraryPrecision(t *testing.T) {
+	assert := assert.New(t)
+	node, err := parsing.NewParser(parsing.WithUseNumber()).Parse("`0.1` + `0.2`")
+	assert.NoError(err)
+	optimized := Optimize(node, WithPrecision(true))
+	assert.Equal(parsing.ASTLiteral, optimized.NodeType)
+	assert.Equal(json.Number("0.3"), optimized.Value)
+}
+
+func TestOptimizePreservesResults(t *testing.T) {
+	var data any
+	err := json.Unmarshal([]byte(`{"foo": [{"bar": 1}, {"bar": 2}, null], "a": {"b": {"c": {"d": "x"}}}, "s": "abc"}`), &data)
+	assert.NoError(t, err)
+	expressions := []string{
+		"@.foo[*].bar",
+		"foo[?bar == `1` + `1`]",
+		"foo | @ | [0] | @.bar",
+		"a | (b | c) | d",
+		"`false` || foo[-1]",
+		"`true` && s",
+		"length('abc') + length(s)",
+		"@.missing",
+		"@.missing[0].bar",
+		"abs('x')",
+		"foo[*][0]",
+		"s[0:2]",
+		"[s, s][*][0:2]",
+		"[[`1`, `2`], `null`, 's'][*][-1]",
+	}
+	for _, expression := range expressions {
+		t.Run(expression, func(t *testing.T) {
+			assert := assert.New(t)
+			node, err := parsing.NewParser().Parse(expression)
+			assert.NoError(err)
+			want, wantErr := interpreter.NewInterpreter(data, nil).Execute(node, data)
+			got, gotErr := interpreter.NewInterpreter(data, nil).Execute(Optimize(node, WithPrecision(false)), data)
+			assert.Equal(want, got)
+			assert.Equal(wantErr, gotErr)
+			// without a declared precision the results are the same with both precisions
+			want, wantErr = interpreter.NewInterpreter(data, nil).Execute(node, data, interpreter.WithArbitraryPrecision())
+			got, gotErr = interpreter.NewInterpreter(data, nil).Execute(Optimize(node), data, interpreter.WithArbitraryPrecision())
+			assert.Equal(want, got)
+			assert.Equal(wantErr, gotErr)
+		})
+	}
+}
+
+func TestOptimizeDoesNotModifyTheAST(t *testing.T) {
+	node, err := parsing.NewParser().Parse("(a | b) | `1` + `2`")
+	assert.NoError(t, err)
+	formatted := parsing.Format(node)
+	Optimize(node)
+	assert.Equal(t, formatted, parsing.Format(node))
+}
+
+func TestOptimizeDoesNotFoldJSONNumbers(t *testing.T) {
+	node, err := parsing.NewParser(parsing.WithUseNumber()).Parse("`0.1` + `0.2` || foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "`0.1` + `0.2` || foo", parsing.Format(Optimize(node)))
+}
@@ -0,0 +1,196 @@
+// Package ordered provides a JSON object type that preserves the order of its keys,
+// and decoding functions producing it.
+package ordered
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Object is a JSON object that remembers the order in which its keys were set.
+// It is encoded to JSON with its keys in that order. The zero value is an empty
+// object ready to use, an Object is not safe for concurrent modification.
+type Object struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewObject returns an empty object with room for the given number of keys.
+func NewObject(capacity int) *Object {
+	return &Object{
+		keys:   make([]string, 0, capacity),
+		values: make(map[string]any, capacity),
+	}
+}
+
+// Len returns the number of keys of the object.
+func (o *Object) Len() int {
+	if o == nil {
+		return 0
+	}
+	return len(o.keys)
+}
+
+// Get returns the value of a key and whether the key is present.
+func (o *Object) Get(key string) (any, bool) {
+	if o == nil {
+		return nil, false
+	}
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// Set sets the value of a key. A new key is added after the existing ones,
+// an existing key keeps its position.
+func (o *Object) Set(key string, value any) {
+	if o.values == nil {
+		o.values = map[string]any{}
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Delete removes a key from the object, if present.
+func (o *Object) Delete(key string) {
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Keys returns the keys of the object in order.
+func (o *Object) Keys() []string {
+	keys := make([]string, o.Len())
+	if o != nil {
+		copy(keys, o.keys)
+	}
+	return keys
+}
+
+// Values returns the values of the object in the order of their keys.
+func (o *Object) Values() []any {
+	values := make([]any, 0, o.Len())
+	for _, key := range o.Keys() {
+		values = append(values, o.values[key])
+	}
+	return values
+}
+
+// Map returns the keys and values of the object as a map, nested objects are not converted.
+func (o *Object) Map() map[string]any {
+	m := make(map[string]any, o.Len())
+	for _, key := range o.Keys() {
+		m[key] = o.values[key]
+	}
+	return m
+}
+
+// MarshalJSON encodes the object with its keys in order.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.Keys() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a JSON object keeping the order of its keys, nested objects
+// are decoded as *Object. Like encoding/json, null leaves the object unchanged and
+// the last value of a duplicate key wins.
+func (o *Object) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	value, err := Decode(decoder)
+	if err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case *Object:
+		*o = *v
+		return nil
+	}
+	return errors.New("ordered: cannot unmarshal non-object JSON value into Object")
+}
+
+// Decode reads the next JSON value from a decoder like json.Decoder.Decode into
+// an any, except that objects are decoded as *Object. Numbers are decoded as float64,
+// or as json.Number when the decoder uses numbers.
+func Decode(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		object := NewObject(0)
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := Decode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.Set(key.(string), value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return object, nil
+	case json.Delim('['):
+		array := []any{}
+		for decoder.More() {
+			value, err := Decode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return array, nil
+	}
+	return token, nil
+}
+
+// Unmarshal decodes a JSON document like json.Unmarshal into an any, except that
+// objects are decoded as *Object.
+func Unmarshal(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	value, err := Decode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	return value, nil
+}
@@ -0,0 +1,111 @@
+package ordered
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObject(t *testing.T) {
+	assert := assert.New(t)
+	var object Object
+	assert.Equal(0, object.Len())
+	object.Set("b", 1.0)
+	object.Set("a", 2.0)
+	object.Set("c", 3.0)
+	object.Set("b", 4.0)
+	assert.Equal(3, object.Len())
+	assert.Equal([]string{"b", "a", "c"}, object.Keys())
+	assert.Equal([]any{4.0, 2.0, 3.0}, object.Values())
+	value, ok := object.Get("a")
+	assert.True(ok)
+	assert.Equal(2.0, value)
+	_, ok = object.Get("d")
+	assert.False(ok)
+	object.Delete("a")
+	object.Delete("d")
+	assert.Equal([]string{"b", "c"}, object.Keys())
+	assert.Equal(map[string]any{"b": 4.0, "c": 3.0}, object.Map())
+	object.Set("a", 5.0)
+	assert.Equal([]string{"b", "c", "a"}, object.Keys())
+	var nilObject *Object
+	assert.Equal(0, nilObject.Len())
+	assert.Equal([]string{}, nilObject.Keys())
+}
+
+func TestMarshalJSON(t *testing.T) {
+	assert := assert.New(t)
+	nested := NewObject(2)
+	nested.Set("z", []any{1.0, "x"})
+	nested.Set("y", nil)
+	object := NewObject(0)
+	object.Set("b", nested)
+	object.Set("a", map[string]any{"d": true, "c": false})
+	data, err := json.Marshal(object)
+	assert.NoError(err)
+	assert.Equal(`{"b":{"z":[1,"x"],"y":null},"a":{"c":false,"d":true}}`, string(data))
+	data, err = json.MarshalIndent(NewObject(0), "", "  ")
+	assert.NoError(err)
+	assert.Equal(`{}`, string(data))
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: `{"b": 1, "a": {"y": [true, {"d": null, "c": "x"}], "x": 2}}`, want: `{"b":1,"a":{"y":[true,{"d":null,"c":"x"}],"x":2}}`},
+		{input: `{"b": 1, "a": 2, "b": 3}`, want: `{"b":3,"a":2}`},
+		{input: `[{"b": 1, "a": 2}, 3]`, want: `[{"b":1,"a":2},3]`},
+		{input: `"text"`, want: `"text"`},
+		{input: `null`, want: `null`},
+		{input: `{"a": 1`, wantErr: true},
+		{input: `{"a": 1} {}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert := assert.New(t)
+			value, err := Unmarshal([]byte(tt.input))
+			if tt.wantErr {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			data, err := json.Marshal(value)
+			assert.NoError(err)
+			assert.Equal(tt.want, string(data))
+		})
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	assert := assert.New(t)
+	var document struct {
+		Labels *Object `json:"labels"`
+		Spec   Object  `json:"spec"`
+	}
+	err := json.Unmarshal([]byte(`{"labels": {"tier": "web", "app": "shop"}, "spec": {"replicas": 2, "image": "nginx"}}`), &document)
+	assert.NoError(err)
+	assert.Equal([]string{"tier", "app"}, document.Labels.Keys())
+	assert.Equal([]string{"replicas", "image"}, document.Spec.Keys())
+	var object Object
+	assert.Error(json.Unmarshal([]byte(`[1]`), &object))
+}
+
+func TestDecode(t *testing.T) {
+	assert := assert.New(t)
+	decoder := json.NewDecoder(strings.NewReader(`{"id": 9007199254740993} [1.5]`))
+	decoder.UseNumber()
+	value, err := Decode(decoder)
+	assert.NoError(err)
+	id, _ := value.(*Object).Get("id")
+	assert.Equal(json.Number("9007199254740993"), id)
+	value, err = Decode(decoder)
+	assert.NoError(err)
+	assert.Equal([]any{json.Number("1.5")}, value)
+	_, err = Decode(decoder)
+	assert.Error(err)
+}
@@ -0,0 +1,295 @@
+package parsing
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// closed is the binding power of expressions that are not extended
+// by the operators that may follow them, like literals or function calls.
+const closed = 1000
+
+// Format returns a JMESPath expression for the AST.
+// For every AST produced by the parser, parsing the returned expression
+// produces the same AST again, apart from source spans.
+// Parentheses are only added where they are needed to preserve the
+// structure of the AST.
+func Format(node ASTNode) string {
+	text, _ := format(node, -1, 0)
+	return text
+}
+
+// format formats a node that is parsed with the given right binding power and
+// followed by an operator of the given binding power, wrapping it in parentheses
+// if the parser would otherwise not produce the same node.
+// It returns the text of the node and the binding power an operator following
+// the node must exceed to be parsed as part of the node.
+func format(node ASTNode, rbp int, follow int) (string, int) {
+	text, lbp, open := formatNode(node)
+	if lbp <= rbp || open < follow {
+		return "(" + text + ")", closed
+	}
+	return text, open
+}
+
+// formatLeft formats the left operand of an operator with the given binding power.
+func formatLeft(node ASTNode, bindingPower int) string {
+	text, _ := format(node, -1, bindingPower)
+	return text
+}
+
+// formatNode formats a node without enclosing parentheses. It returns the text of
+// the node, the binding power of its leading operator and the binding power an
+// operator following the node must exceed to be parsed as part of the node.
+func formatNode(node ASTNode) (string, int, int) {
+	switch node.NodeType {
+	case ASTField:
+		return formatIdentifier(node.Value.(string)), closed, closed
+	case ASTLiteral:
+		return formatLiteral(node.Value), closed, closed
+	case ASTCurrentNode, ASTIdentity:
+		return "@", closed, closed
+	case ASTRootNode:
+		return "$", closed, closed
+	case ASTVariable:
+		return node.Value.(string), closed, closed
+	case ASTIndex, ASTSlice:
+		return formatIndex(node), closed, closed
+	case ASTKeyValPair:
+		value, _ := format(node.Children[0], 0, 0)
+		return formatIdentifier(node.Value.(string)) + ": " + value, closed, closed
+	case ASTMultiSelectList:
+		items := formatList(node.Children)
+		if len(node.Children) == 1 && items == "*" {
+			// [*] would be parsed as a projection
+			items = "(*)"
+		}
+		return "[" + items + "]", closed, closed
+	case ASTMultiSelectHash:
+		return "{" + formatList(node.Children) + "}", closed, closed
+	case ASTFunctionExpression:
+		return node.Value.(string) + "(" + formatList(node.Children) + ")", bindingPowers[TOKLparen], closed
+	case ASTExpRef:
+		operand, open := format(node.Children[0], bindingPowers[TOKExpref], 0)
+		if strings.HasPrefix(operand, "&") {
+			// && would be lexed as an and operator
+			operand = " " + operand
+		}
+		return "&" + operand, closed, min(bindingPowers[TOKExpref], open)
+	case ASTNotExpression:
+		operand, open := format(node.Children[0], bindingPowers[TOKNot], 0)
+		return "!" + operand, closed, min(bindingPowers[TOKNot], open)
+	case ASTArithmeticUnaryExpression:
+		tokenType := node.Value.(TokType)
+		operand, open := format(node.Children[0], bindingPowers[tokenType], 0)
+		return operators[tokenType] + operand, closed, min(bindingPowers[tokenType], open)
+	case ASTArithmeticExpression, ASTComparator:
+		tokenType := node.Value.(TokType)
+		return formatBinary(node, operators[tokenType], bindingPowers[tokenType])
+	case ASTOrExpression:
+		return formatBinary(node, "||", bindingPowers[TOKOr])
+	case ASTAndExpression:
+		return formatBinary(node, "&&", bindingPowers[TOKAnd])
+	case ASTPipe:
+		return formatBinary(node, "|", bindingPowers[TOKPipe])
+	case ASTBinding:
+		value, open := format(node.Children[1], 0, 0)
+		return formatLeft(node.Children[0], bindingPowers[TOKAssign]) + " = " + value, bindingPowers[TOKAssign], min(0, open)
+	case ASTBindings:
+		return formatList(node.Children), closed, 0
+	case ASTLetExpression:
+		bindings, _, _ := formatNode(node.Children[0])
+		body, open := format(node.Children[1], 0, 0)
+		return "let " + bindings + " in " + body, closed, min(0, open)
+	case ASTSubexpression:
+		left := formatLeft(node.Children[0], bindingPowers[TOKDot])
+		right, open := formatDotRHS(node.Children[1], bindingPowers[TOKDot])
+		return left + right, bindingPowers[TOKDot], open
+	case ASTIndexExpression:
+		left, lbp := formatProjected(node.Children[0], bindingPowers[TOKLbracket])
+		return left + formatIndex(node.Children[1]), lbp, closed
+	case ASTProjection:
+		var left string
+		lbp := bindingPowers[TOKLbracket]
+		rbp := bindingPowers[TOKStar]
+		switch projected := node.Children[0]; {
+		case projected.NodeType == ASTFlatten:
+			lbp = bindingPowers[TOKFlatten]
+			rbp = bindingPowers[TOKFlatten]
+			left, lbp = formatProjected(projected.Children[0], lbp)
+			left += "[]"
+		case projected.NodeType == ASTIndexExpression && projected.Children[1].NodeType == ASTSlice:
+			left, lbp = formatProjected(projected.Children[0], lbp)
+			left += formatIndex(projected.Children[1])
+		default:
+			left, lbp = formatProjected(projected, lbp)
+			left += "[*]"
+		}
+		right, open := formatProjectionRHS(node.Children[1], rbp)
+		return left + right, lbp, open
+	case ASTFilterProjection:
+		left, lbp := formatProjected(node.Children[0], bindingPowers[TOKFilter])
+		condition, _ := format(node.Children[2], 0, 0)
+		right, open := formatProjectionRHS(node.Children[1], bindingPowers[TOKFilter])
+		return left + "[?" + condition + "]" + right, lbp, open
+	case ASTValueProjection:
+		if node.Children[0].NodeType == ASTIdentity {
+			right, open := formatProjectionRHS(node.Children[1], bindingPowers[TOKStar])
+			return "*" + right, closed, open
+		}
+		left := formatLeft(node.Children[0], bindingPowers[TOKDot])
+		right, open := formatProjectionRHS(node.Children[1], bindingPowers[TOKDot])
+		return left + ".*" + right, bindingPowers[TOKDot], open
+	case ASTFlatten:
+		left, lbp := formatProjected(node.Children[0], bindingPowers[TOKFlatten])
+		return left + "[]", lbp, bindingPowers[TOKFlatten]
+	}
+	return "", closed, closed
+}
+
+var operators = map[TokType]string{
+	TOKPlus:     "+",
+	TOKMinus:    "-",
+	TOKStar:     "*",
+	TOKMultiply: "×",
+	TOKDivide:   "/",
+	TOKModulo:   "%",
+	TOKDiv:      "//",
+	TOKEQ:       "==",
+	TOKNE:       "!=",
+	TOKLT:       "<",
+	TOKLTE:      "<=",
+	TOKGT:       ">",
+	TOKGTE:      ">=",
+}
+
+// formatBinary formats a left associative operator applied to the children of the node,
+// which may have more than two children.
+func formatBinary(node ASTNode, operator string, bindingPower int) (string, int, int) {
+	text := formatLeft(node.Children[0], bindingPower)
+	open := closed
+	for i, child := range node.Children[1:] {
+		follow := bindingPower
+		if i == len(node.Children)-2 {
+			follow = 0
+		}
+		var right string
+		right, open = format(child, bindingPower, follow)
+		text += " " + operator + " " + right
+	}
+	return text, bindingPower, min(bindingPower, open)
+}
+
+func formatList(nodes []ASTNode) string {
+	items := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		item, _ := format(node, 0, 0)
+		items = append(items, item)
+	}
+	return strings.Join(items, ", ")
+}
+
+// formatProjected formats the left operand of a projection or index expression,
+// which is omitted when it is the identity node implied by the grammar.
+func formatProjected(node ASTNode, bindingPower int) (string, int) {
+	if node.NodeType == ASTIdentity {
+		return "", closed
+	}
+	return formatLeft(node, bindingPower), bindingPower
+}
+
+// formatDotRHS formats the right hand side of a dot operator.
+func formatDotRHS(node ASTNode, bindingPower int) (string, int) {
+	if node.NodeType == ASTMultiSelectList || node.NodeType == ASTMultiSelectHash {
+		text, _, _ := formatNode(node)
+		return "." + text, closed
+	}
+	text, open := format(node, bindingPower, 0)
+	return "." + text, min(bindingPower, open)
+}
+
+// formatProjectionRHS formats the expression applied to every element of a projection.
+// The returned binding power is the one an operator following the projection must
+// exceed to be parsed as part of the projection.
+func formatProjectionRHS(node ASTNode, bindingPower int) (string, int) {
+	if node.NodeType == ASTIdentity {
+		// any token with a binding power of 10 or more
+		// would be parsed as the right hand side
+		return "", bindingPowers[TOKFlatten]
+	}
+	if node.NodeType == ASTMultiSelectHash {
+		return formatDotRHS(node, bindingPower)
+	}
+	text, open := format(node, bindingPower, 0)
+	if strings.HasPrefix(text, "[") {
+		return text, min(bindingPower, open)
+	}
+	return "." + text, min(bindingPower, open)
+}
+
+func formatIndex(node ASTNode) string {
+	if node.NodeType == ASTIndex {
+		return "[" + strconv.Itoa(node.Value.(int)) + "]"
+	}
+	parts := node.Value.([]*int)
+	text := "["
+	for i, part := range parts {
+		if i > 0 && (i < 2 || part != nil) {
+			text += ":"
+		}
+		if part != nil {
+			text += strconv.Itoa(*part)
+		}
+	}
+	return text + "]"
+}
+
+func formatIdentifier(name string) string {
+	if isUnquotedIdentifier(name) {
+		return name
+	}
+	return formatJSON(name)
+}
+
+func isUnquotedIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func formatLiteral(value any) string {
+	if s, ok := value.(string); ok {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		s = strings.ReplaceAll(s, `'`, `\'`)
+		return "'" + s + "'"
+	}
+	return "`" + strings.ReplaceAll(formatJSON(value), "`", "\\`") + "`"
+}
+
+func formatJSON(value any) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "null"
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,190 @@
+package parsing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	expressions := []string{
+		"foo",
+		"foo.bar.baz",
+		`"foo bar"."with \"quotes\""`,
+		`"1st"`,
+		`"let"`,
+		"@",
+		"$",
+		"$.foo",
+		"$foo",
+		"`null`",
+		"`true`",
+		"`1.5`",
+		"`[1, 2, {\"a\": \"b\"}]`",
+		"`\"json\"`",
+		"`\"back\\`tick\"`",
+		"'raw string'",
+		`'it\'s'`,
+		`'back\\slash'`,
+		`'a\b'`,
+		"foo[0]",
+		"foo[-1]",
+		"[0]",
+		"foo[0][1]",
+		"foo[*]",
+		"foo[*].bar",
+		"foo[*].bar.baz",
+		"foo[*].bar[*].baz",
+		"foo[*][0]",
+		"foo[*][0][1]",
+		"foo[*][a, b]",
+		"foo[*].[a, b]",
+		"foo[*].[a, b].c",
+		"foo[*].{a: a, b: b}",
+		"foo[*].{a: a}.b",
+		"[*]",
+		"[*].foo",
+		"foo[]",
+		"foo[].bar",
+		"foo[][]",
+		"foo[*][]",
+		"foo[][0]",
+		"[]",
+		"[].foo",
+		"foo[1:2]",
+		"foo[:2]",
+		"foo[1:]",
+		"foo[::-1]",
+		"foo[1:2:3]",
+		"foo[:]",
+		"[1:2].foo",
+		"foo[1:2][0]",
+		"foo.*",
+		"foo.*.bar",
+		"foo.*.bar.baz",
+		"foo.*[0]",
+		"*",
+		"*.foo",
+		"foo[?bar]",
+		"foo[?bar == `1`].baz",
+		"foo[?bar][]",
+		"foo[?a.b > `2` && c || !d]",
+		"[?foo]",
+		"foo[?bar][?baz]",
+		"foo | bar",
+		"foo | bar | baz",
+		"foo | (bar | baz)",
+		"(foo | bar).baz",
+		"(foo[*]).bar",
+		"(foo[*])[0]",
+		"(foo[]).bar",
+		"(foo.*).bar",
+		"(foo[?a]).bar",
+		"foo || bar && baz",
+		"(foo || bar) && baz",
+		"foo && (bar || baz)",
+		"!foo",
+		"!foo.bar",
+		"!(foo.bar)",
+		"!!foo",
+		"!(foo || bar)",
+		"a == b",
+		"a != b",
+		"a < b",
+		"a <= b",
+		"a > b",
+		"a >= b",
+		"(a == b) == c",
+		"a + b",
+		"a - b * c",
+		"(a - b) * c",
+		"a + b + c",
+		"a + (b + c)",
+		"a × b ÷ c",
+		"a % b // c",
+		"-a",
+		"+a",
+		"-a.b",
+		"-(a * b)",
+		"(-a) * b",
+		"- -a",
+		"a - -b",
+		"(foo[*]) * `2`",
+		"length(foo)",
+		"foo.length(@)",
+		"sort_by(foo, &bar)",
+		"sort_by(foo, &(bar | baz))",
+		"map(&(&a), foo)",
+		"(&a) | b",
+		"[a, b.c, `1`]",
+		"[[a, b], c]",
+		"[*, a]",
+		"{a: a, \"b c\": b, d: {e: e}}",
+		"foo.[a, b]",
+		"foo.{a: a}",
+		"foo.{a: a}.b",
+		"foo.[a][0]",
+		"let $x = foo in $x.bar",
+		"let $x = foo, $y = bar in [$x, $y]",
+		"let $x = a | b in c",
+		"let $x = foo in let $y = $x in $y",
+		"(let $x = foo in $x).bar",
+		"[let $x = foo in $x, b]",
+		"foo[*].bar | baz[0] || `1`",
+		"a[?b == `1`].c[*].d | [0]",
+		"a[*].b[?c].d",
+		"max_by(people[?age > `18`], &age).name",
+		"items(@)[*][0]",
+	}
+	for _, expression := range expressions {
+		t.Run(expression, func(t *testing.T) {
+			assert := assert.New(t)
+			node, err := NewParser().Parse(expression)
+			assert.NoError(err)
+			formatted := Format(node)
+			reparsed, err := NewParser().Parse(formatted)
+			assert.NoError(err, formatted)
+			assert.Equal(withoutSpans(node), withoutSpans(reparsed), formatted)
+			assert.Equal(formatted, Format(reparsed))
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"foo.bar", "foo.bar"},
+		{"foo   |   bar", "foo | bar"},
+		{"(foo).bar", "foo.bar"},
+		{"((foo || bar)) && baz", "(foo || bar) && baz"},
+		{`"foo"`, "foo"},
+		{`"foo.bar"`, `"foo.bar"`},
+		{"`\"foo\"`", "'foo'"},
+		{`foo[?bar=='baz']`, "foo[?bar == 'baz']"},
+		{"foo[1:2:]", "foo[1:2]"},
+		{"foo[*][bar]", "foo[*][bar]"},
+		{"foo[*].[bar]", "foo[*][bar]"},
+		{"sort_by(@,&a)", "sort_by(@, &a)"},
+		{"let $x=a in $x", "let $x = a in $x"},
+		{"a-b", "a - b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			node, err := NewParser().Parse(tt.expression)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, Format(node))
+		})
+	}
+}
+
+func withoutSpans(node ASTNode) ASTNode {
+	node.Start, node.End = 0, 0
+	children := node.Children
+	node.Children = nil
+	for _, child := range children {
+		node.Children = append(node.Children, withoutSpans(child))
+	}
+	return node
+}
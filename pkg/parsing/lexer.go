@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 	"unicode/utf8"
+
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
 )
 
 type token struct {
@@ -41,6 +43,14 @@ func (e SyntaxError) Error() string {
 	return "SyntaxError: " + e.msg
 }
 
+// Unwrap exposes the syntax error as a jperror.Error of kind syntax,
+// allowing callers to categorize all errors with errors.As.
+func (e SyntaxError) Unwrap() error {
+	err := jperror.New(jperror.Syntax, e.Error())
+	err.Expression = e.Expression
+	return err.Locate(e.Offset, e.Offset+1)
+}
+
 // HighlightLocation will show where the syntax error occurred.
 // It will place a "^" character on a line below the expression
 // at the point where the syntax error occurred.
@@ -127,6 +137,34 @@ func (t token) String() string {
 		t.tokenType, t.value, t.position, t.length)
 }
 
+// span returns the byte offsets of the source text of the token in the expression,
+// including the delimiters of quoted identifiers and literals.
+func (t token) span(expression string) (int, int) {
+	switch t.tokenType {
+	case TOKQuotedIdentifier:
+		return t.position, scanDelimited(expression, t.position+1, '"', t.position+t.length+2)
+	case TOKStringLiteral:
+		return t.position - 1, scanDelimited(expression, t.position, '\'', t.position+t.length+1)
+	case TOKJSONLiteral:
+		return t.position - 1, scanDelimited(expression, t.position, '`', t.position+t.length+1)
+	}
+	return t.position, t.position + len(t.value)
+}
+
+// scanDelimited returns the offset following the closing delimiter of a quoted
+// token whose content starts at offset start, skipping escaped characters.
+func scanDelimited(expression string, start int, delimiter byte, fallback int) int {
+	for i := start; i < len(expression); i++ {
+		switch expression[i] {
+		case '\\':
+			i++
+		case delimiter:
+			return i + 1
+		}
+	}
+	return fallback
+}
+
 // NewLexer creates a new JMESPath lexer.
 func NewLexer() *Lexer {
 	lexer := Lexer{}
@@ -414,7 +452,11 @@ func (lexer *Lexer) consumeQuotedIdentifier() (token, error) {
 	var decoded string
 	asJSON := []byte("\"" + value + "\"")
 	if err := json.Unmarshal(asJSON, &decoded); err != nil {
-		return token{}, err
+		return token{}, SyntaxError{
+			msg:        "Invalid quoted identifier: " + err.Error(),
+			Expression: lexer.expression,
+			Offset:     start - 1,
+		}
 	}
 	return token{
 		tokenType: TOKQuotedIdentifier,
@@ -0,0 +1,234 @@
+package parsing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ASTSchemaVersion is the version of the JSON representation of ASTs.
+// It is incremented whenever the representation changes in a way
+// older readers could not understand.
+const ASTSchemaVersion = 1
+
+// MarshalJSON encodes the AST using the following schema:
+//
+//	{"version": 1, "root": <node>}
+//
+// where each node is encoded as an object with the fields:
+//
+//	type      the name of the node type, e.g. "ASTField"
+//	value     the payload of the node, if any, see below
+//	children  the child nodes, if any
+//	start     the offset of the first byte of the node in the expression
+//	end       the offset after the last byte of the node in the expression
+//	exact     true for literals holding json.Number values, see WithUseNumber, their
+//	          numbers are decoded as json.Number instead of float64
+//
+// The value depends on the node type:
+//
+//	ASTField, ASTKeyValPair      the field name
+//	ASTFunctionExpression        the function name
+//	ASTVariable                  the variable name, including the leading $
+//	ASTLiteral                   the literal JSON value
+//	ASTIndex                     the index, as an integer
+//	ASTSlice                     [start, stop, step], with null for omitted parts
+//	ASTComparator,
+//	ASTArithmeticExpression,
+//	ASTArithmeticUnaryExpression the operator token name, e.g. "TOKEQ"
+func (node ASTNode) MarshalJSON() ([]byte, error) {
+	root, err := toJSONNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonAST{Version: ASTSchemaVersion, Root: &root})
+}
+
+// UnmarshalJSON decodes an AST encoded by MarshalJSON.
+func (node *ASTNode) UnmarshalJSON(data []byte) error {
+	var ast jsonAST
+	if err := json.Unmarshal(data, &ast); err != nil {
+		return err
+	}
+	if ast.Version != ASTSchemaVersion {
+		return fmt.Errorf("unsupported AST schema version: %d", ast.Version)
+	}
+	if ast.Root == nil {
+		return fmt.Errorf("missing AST root")
+	}
+	decoded, err := fromJSONNode(*ast.Root)
+	if err != nil {
+		return err
+	}
+	*node = decoded
+	return nil
+}
+
+type jsonAST struct {
+	Version int       `json:"version"`
+	Root    *jsonNode `json:"root"`
+}
+
+type jsonNode struct {
+	Type     string          `json:"type"`
+	Value    json.RawMessage `json:"value,omitempty"`
+	Children []jsonNode      `json:"children,omitempty"`
+	Start    int             `json:"start,omitempty"`
+	End      int             `json:"end,omitempty"`
+	Exact    bool            `json:"exact,omitempty"`
+}
+
+var (
+	nodeTypesByName = map[string]astNodeType{}
+	tokTypesByName  = map[string]TokType{}
+)
+
+func init() {
+	for nodeType := ASTEmpty; nodeType <= ASTBinding; nodeType++ {
+		nodeTypesByName[nodeType.String()] = nodeType
+	}
+	for tokType := TOKUnknown; tokType <= TOKEOF; tokType++ {
+		tokTypesByName[tokType.String()] = tokType
+	}
+}
+
+func toJSONNode(node ASTNode) (jsonNode, error) {
+	out := jsonNode{
+		Type:  node.NodeType.String(),
+		Start: node.Start,
+		End:   node.End,
+	}
+	if _, ok := nodeTypesByName[out.Type]; !ok {
+		return out, fmt.Errorf("unknown AST node type: %s", out.Type)
+	}
+	value, err := marshalValue(node)
+	if err != nil {
+		return out, err
+	}
+	out.Value = value
+	out.Exact = node.NodeType == ASTLiteral && hasNumber(node.Value)
+	for _, child := range node.Children {
+		encoded, err := toJSONNode(child)
+		if err != nil {
+			return out, err
+		}
+		out.Children = append(out.Children, encoded)
+	}
+	return out, nil
+}
+
+func fromJSONNode(in jsonNode) (ASTNode, error) {
+	nodeType, ok := nodeTypesByName[in.Type]
+	if !ok {
+		return ASTNode{}, fmt.Errorf("unknown AST node type: %s", in.Type)
+	}
+	node := ASTNode{
+		NodeType: nodeType,
+		Start:    in.Start,
+		End:      in.End,
+	}
+	value, err := unmarshalValue(nodeType, in.Value, in.Exact)
+	if err != nil {
+		return node, err
+	}
+	node.Value = value
+	for _, child := range in.Children {
+		decoded, err := fromJSONNode(child)
+		if err != nil {
+			return node, err
+		}
+		node.Children = append(node.Children, decoded)
+	}
+	return node, nil
+}
+
+func marshalValue(node ASTNode) (json.RawMessage, error) {
+	switch node.NodeType {
+	case ASTComparator, ASTArithmeticExpression, ASTArithmeticUnaryExpression:
+		tokType, ok := node.Value.(TokType)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for %s: %v", node.NodeType, node.Value)
+		}
+		return json.Marshal(tokType.String())
+	case ASTField, ASTKeyValPair, ASTFunctionExpression, ASTVariable, ASTLiteral, ASTIndex, ASTSlice:
+		return json.Marshal(node.Value)
+	}
+	if node.Value != nil {
+		return nil, fmt.Errorf("unexpected value for %s: %v", node.NodeType, node.Value)
+	}
+	return nil, nil
+}
+
+func unmarshalValue(nodeType astNodeType, data json.RawMessage, exact bool) (any, error) {
+	switch nodeType {
+	case ASTComparator, ASTArithmeticExpression, ASTArithmeticUnaryExpression:
+		var name string
+		if err := json.Unmarshal(data, &name); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", nodeType, err)
+		}
+		tokType, ok := tokTypesByName[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid value for %s: unknown token %s", nodeType, name)
+		}
+		return tokType, nil
+	case ASTField, ASTKeyValPair, ASTFunctionExpression, ASTVariable:
+		var name string
+		if err := json.Unmarshal(data, &name); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", nodeType, err)
+		}
+		return name, nil
+	case ASTIndex:
+		var index int
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", nodeType, err)
+		}
+		return index, nil
+	case ASTSlice:
+		var parts []*int
+		if err := json.Unmarshal(data, &parts); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", nodeType, err)
+		}
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid value for %s: expected 3 parts, got %d", nodeType, len(parts))
+		}
+		return parts, nil
+	case ASTLiteral:
+		if len(data) == 0 {
+			return nil, fmt.Errorf("missing value for %s", nodeType)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		if exact {
+			decoder.UseNumber()
+		}
+		var literal any
+		if err := decoder.Decode(&literal); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", nodeType, err)
+		}
+		return literal, nil
+	}
+	if len(data) != 0 {
+		return nil, fmt.Errorf("unexpected value for %s", nodeType)
+	}
+	return nil, nil
+}
+
+// hasNumber reports whether a literal value contains json.Number values.
+func hasNumber(value any) bool {
+	switch v := value.(type) {
+	case json.Number:
+		return true
+	case []any:
+		for _, element := range v {
+			if hasNumber(element) {
+				return true
+			}
+		}
+	case map[string]any:
+		for _, element := range v {
+			if hasNumber(element) {
+				return true
+			}
+		}
+	}
+	return false
+}
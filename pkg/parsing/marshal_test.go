@@ -0,0 +1,125 @@
+package parsing
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalJSONRoundTrip(t *testing.T) {
+	expressions := []string{
+		"foo.bar.baz",
+		`"foo bar"."with \"quotes\""`,
+		"@",
+		"$.foo",
+		"$foo",
+		"`null`",
+		"`1.5`",
+		"`[1, 2, {\"a\": \"b\"}]`",
+		"'raw string'",
+		"foo[-1]",
+		"foo[*].bar[*].baz",
+		"foo[][0]",
+		"foo[1:2:3]",
+		"foo[::-1]",
+		"foo.*.bar",
+		"foo[?a.b > `2` && c || !d]",
+		"foo | (bar | baz)",
+		"a != b",
+		"a - b * c",
+		"a × b ÷ c",
+		"a % b // c",
+		"-a.b",
+		"sort_by(foo, &(bar | baz))",
+		"[a, b.c, `1`]",
+		"{a: a, \"b c\": b, d: {e: e}}",
+		"let $x = foo, $y = bar in [$x, $y]",
+		"max_by(people[?age > `18`], &age).name",
+	}
+	for _, useNumber := range []bool{false, true} {
+		var opts []Option
+		if useNumber {
+			opts = append(opts, WithUseNumber())
+		}
+		for _, expression := range expressions {
+			t.Run(fmt.Sprintf("%s/%t", expression, useNumber), func(t *testing.T) {
+				assert := assert.New(t)
+				node, err := NewParser(opts...).Parse(expression)
+				assert.NoError(err)
+				data, err := json.Marshal(node)
+				assert.NoError(err)
+				var decoded ASTNode
+				assert.NoError(json.Unmarshal(data, &decoded))
+				assert.Equal(node, decoded)
+			})
+		}
+	}
+}
+
+func TestMarshalJSONExactLiteral(t *testing.T) {
+	assert := assert.New(t)
+	node, err := NewParser(WithUseNumber()).Parse("`[9007199254740993, {\"a\": 0.1}]`")
+	assert.NoError(err)
+	data, err := json.Marshal(node)
+	assert.NoError(err)
+	assert.JSONEq(`{"version":1,"root":{"type":"ASTLiteral","value":[9007199254740993,{"a":0.1}],"exact":true,"end":32}}`, string(data))
+	var decoded ASTNode
+	assert.NoError(json.Unmarshal(data, &decoded))
+	assert.Equal([]any{json.Number("9007199254740993"), map[string]any{"a": json.Number("0.1")}}, decoded.Value)
+}
+
+func TestMarshalJSON(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{
+			"foo",
+			`{"version":1,"root":{"type":"ASTField","value":"foo","end":3}}`,
+		},
+		{
+			"a[1:]",
+			`{"version":1,"root":{"type":"ASTProjection","children":[` +
+				`{"type":"ASTIndexExpression","children":[{"type":"ASTField","value":"a","end":1},{"type":"ASTSlice","value":[1,null,null],"start":2,"end":4}],"end":5},` +
+				`{"type":"ASTIdentity","start":5,"end":5}],"end":5}}`,
+		},
+		{
+			"a == `null`",
+			`{"version":1,"root":{"type":"ASTComparator","value":"TOKEQ","children":[` +
+				`{"type":"ASTField","value":"a","end":1},{"type":"ASTLiteral","value":null,"start":5,"end":11}],"end":11}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			node, err := NewParser().Parse(tt.expression)
+			assert.NoError(t, err)
+			data, err := json.Marshal(node)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(data))
+		})
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"unsupported version", `{"version":2,"root":{"type":"ASTField","value":"foo"}}`, "unsupported AST schema version: 2"},
+		{"missing root", `{"version":1}`, "missing AST root"},
+		{"unknown node type", `{"version":1,"root":{"type":"ASTUnknown"}}`, "unknown AST node type: ASTUnknown"},
+		{"unknown token", `{"version":1,"root":{"type":"ASTComparator","value":"TOKFoo"}}`, "invalid value for ASTComparator: unknown token TOKFoo"},
+		{"invalid slice", `{"version":1,"root":{"type":"ASTSlice","value":[1]}}`, "invalid value for ASTSlice: expected 3 parts, got 1"},
+		{"missing literal", `{"version":1,"root":{"type":"ASTLiteral"}}`, "missing value for ASTLiteral"},
+		{"unexpected value", `{"version":1,"root":{"type":"ASTPipe","value":1}}`, "unexpected value for ASTPipe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node ASTNode
+			assert.EqualError(t, json.Unmarshal([]byte(tt.data), &node), tt.want)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package parsing
+
+type Option func(Options) Options
+
+type Options struct {
+	UseNumber bool
+}
+
+// WithUseNumber decodes numbers in JSON literals as json.Number instead of float64,
+// preserving their exact value.
+func WithUseNumber() Option {
+	return func(o Options) Options {
+		o.UseNumber = true
+		return o
+	}
+}
@@ -2,7 +2,9 @@ package parsing
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 )
@@ -44,10 +46,14 @@ const (
 )
 
 // ASTNode represents the abstract syntax tree of a JMESPath expression.
+// Start and End are the byte offsets of the part of the expression the node
+// was parsed from, nodes implied by the grammar have an empty span.
 type ASTNode struct {
 	NodeType astNodeType
 	Value    any
 	Children []ASTNode
+	Start    int
+	End      int
 }
 
 func (node ASTNode) String() string {
@@ -129,11 +135,17 @@ type Parser struct {
 	expression string
 	tokens     []token
 	index      int
+	options    Options
 }
 
 // NewParser creates a new JMESPath parser.
-func NewParser() *Parser {
+func NewParser(opts ...Option) *Parser {
 	p := Parser{}
+	for _, opt := range opts {
+		if opt != nil {
+			p.options = opt(p.options)
+		}
+	}
 	return &p
 }
 
@@ -165,11 +177,13 @@ func (p *Parser) parseTokens(tokens []token) (ASTNode, error) {
 func (p *Parser) parseExpression(bindingPower int) (ASTNode, error) {
 	var err error
 	leftToken := p.lookaheadToken(0)
+	start, _ := leftToken.span(p.expression)
 	p.advance()
 	leftNode, err := p.nud(leftToken)
 	if err != nil {
 		return ASTNode{}, err
 	}
+	leftNode = p.withSpan(leftNode, start)
 	currentToken := p.current()
 	for bindingPower < bindingPowers[currentToken] {
 		p.advance()
@@ -177,6 +191,7 @@ func (p *Parser) parseExpression(bindingPower int) (ASTNode, error) {
 		if err != nil {
 			return ASTNode{}, err
 		}
+		leftNode = p.withSpan(leftNode, start)
 		currentToken = p.current()
 	}
 	return leftNode, nil
@@ -189,10 +204,11 @@ func (p *Parser) parseIndexExpression() (ASTNode, error) {
 	indexStr := p.lookaheadToken(0).value
 	parsedInt, err := strconv.Atoi(indexStr)
 	if err != nil {
-		return ASTNode{}, err
+		return ASTNode{}, p.syntaxError("Invalid index: " + indexStr)
 	}
-	indexNode := ASTNode{NodeType: ASTIndex, Value: parsedInt}
+	start := p.lookaheadToken(0).position
 	p.advance()
+	indexNode := p.withSpan(ASTNode{NodeType: ASTIndex, Value: parsedInt}, start)
 	if err := p.match(TOKRbracket); err != nil {
 		return ASTNode{}, err
 	}
@@ -202,6 +218,7 @@ func (p *Parser) parseIndexExpression() (ASTNode, error) {
 func (p *Parser) parseSliceExpression() (ASTNode, error) {
 	parts := []*int{nil, nil, nil}
 	index := 0
+	start := p.lookaheadToken(0).position
 	current := p.current()
 	for current != TOKRbracket && index < 3 {
 		if current == TOKColon {
@@ -213,7 +230,7 @@ func (p *Parser) parseSliceExpression() (ASTNode, error) {
 		} else if current == TOKNumber {
 			parsedInt, err := strconv.Atoi(p.lookaheadToken(0).value)
 			if err != nil {
-				return ASTNode{}, err
+				return ASTNode{}, p.syntaxError("Invalid slice index: " + p.lookaheadToken(0).value)
 			}
 			parts[index] = &parsedInt
 			p.advance()
@@ -222,13 +239,14 @@ func (p *Parser) parseSliceExpression() (ASTNode, error) {
 		}
 		current = p.current()
 	}
+	slice := p.withSpan(ASTNode{
+		NodeType: ASTSlice,
+		Value:    parts,
+	}, start)
 	if err := p.match(TOKRbracket); err != nil {
 		return ASTNode{}, err
 	}
-	return ASTNode{
-		NodeType: ASTSlice,
-		Value:    parts,
-	}, nil
+	return slice, nil
 }
 
 func isKeyword(token token, keyword string) bool {
@@ -296,7 +314,7 @@ func (p *Parser) led(tokenType TokType, node ASTNode) (ASTNode, error) {
 	case TOKFilter:
 		return p.parseFilter(node)
 	case TOKFlatten:
-		left := ASTNode{NodeType: ASTFlatten, Children: []ASTNode{node}}
+		left := p.withSpan(ASTNode{NodeType: ASTFlatten, Children: []ASTNode{node}}, node.Start)
 		right, err := p.parseProjectionRHS(bindingPowers[TOKFlatten])
 		return ASTNode{
 			NodeType: ASTProjection,
@@ -360,10 +378,9 @@ func (p *Parser) nud(token token) (ASTNode, error) {
 			Value:    token.value,
 		}, nil
 	case TOKJSONLiteral:
-		var parsed any
-		err := json.Unmarshal([]byte(token.value), &parsed)
+		parsed, err := p.decodeJSON(token.value)
 		if err != nil {
-			return ASTNode{}, err
+			return ASTNode{}, p.syntaxErrorToken("Invalid JSON literal: "+err.Error(), token)
 		}
 		return ASTNode{NodeType: ASTLiteral, Value: parsed}, nil
 	case TOKStringLiteral:
@@ -390,24 +407,24 @@ func (p *Parser) nud(token token) (ASTNode, error) {
 		expr, err := p.parseExpression(bindingPowers[TOKMinus])
 		return ASTNode{NodeType: ASTArithmeticUnaryExpression, Value: TOKMinus, Children: []ASTNode{expr}}, err
 	case TOKStar:
-		left := ASTNode{NodeType: ASTIdentity}
+		left := p.implicitIdentity(token.position)
 		var right ASTNode
 		var err error
 		if p.current() == TOKRbracket {
-			right = ASTNode{NodeType: ASTIdentity}
+			right = p.implicitIdentity(p.lookaheadToken(0).position)
 		} else {
 			right, err = p.parseProjectionRHS(bindingPowers[TOKStar])
 		}
 		return ASTNode{NodeType: ASTValueProjection, Children: []ASTNode{left, right}}, err
 	case TOKFilter:
-		return p.parseFilter(ASTNode{NodeType: ASTIdentity})
+		return p.parseFilter(p.implicitIdentity(token.position))
 	case TOKLbrace:
 		return p.parseMultiSelectHash()
 	case TOKFlatten:
-		left := ASTNode{
+		left := p.withSpan(ASTNode{
 			NodeType: ASTFlatten,
-			Children: []ASTNode{{NodeType: ASTIdentity}},
-		}
+			Children: []ASTNode{p.implicitIdentity(token.position)},
+		}, token.position)
 		right, err := p.parseProjectionRHS(bindingPowers[TOKFlatten])
 		if err != nil {
 			return ASTNode{}, err
@@ -421,7 +438,7 @@ func (p *Parser) nud(token token) (ASTNode, error) {
 			if err != nil {
 				return ASTNode{}, err
 			}
-			return p.projectIfSlice(ASTNode{NodeType: ASTIdentity}, right)
+			return p.projectIfSlice(p.implicitIdentity(token.position), right)
 		} else if tokenType == TOKStar && p.lookahead(1) == TOKRbracket {
 			p.advance()
 			p.advance()
@@ -431,7 +448,7 @@ func (p *Parser) nud(token token) (ASTNode, error) {
 			}
 			return ASTNode{
 				NodeType: ASTProjection,
-				Children: []ASTNode{{NodeType: ASTIdentity}, right},
+				Children: []ASTNode{p.implicitIdentity(token.position), right},
 			}, nil
 		} else {
 			return p.parseMultiSelectList()
@@ -469,6 +486,7 @@ func (p *Parser) nud(token token) (ASTNode, error) {
 }
 
 func (p *Parser) parseMultiSelectList() (ASTNode, error) {
+	start := p.lookaheadToken(-1).position
 	var expressions []ASTNode
 	for {
 		expression, err := p.parseExpression(0)
@@ -488,16 +506,18 @@ func (p *Parser) parseMultiSelectList() (ASTNode, error) {
 	if err != nil {
 		return ASTNode{}, err
 	}
-	return ASTNode{
+	return p.withSpan(ASTNode{
 		NodeType: ASTMultiSelectList,
 		Children: expressions,
-	}, nil
+	}, start), nil
 }
 
 func (p *Parser) parseMultiSelectHash() (ASTNode, error) {
+	start := p.lookaheadToken(-1).position
 	var children []ASTNode
 	for {
 		keyToken := p.lookaheadToken(0)
+		keyStart, _ := keyToken.span(p.expression)
 		if err := p.match(TOKUnquotedIdentifier); err != nil {
 			if err := p.match(TOKQuotedIdentifier); err != nil {
 				return ASTNode{}, p.syntaxError("Expected tQuotedIdentifier or tUnquotedIdentifier")
@@ -512,11 +532,11 @@ func (p *Parser) parseMultiSelectHash() (ASTNode, error) {
 		if err != nil {
 			return ASTNode{}, err
 		}
-		node := ASTNode{
+		node := p.withSpan(ASTNode{
 			NodeType: ASTKeyValPair,
 			Value:    keyName,
 			Children: []ASTNode{value},
-		}
+		}, keyStart)
 		children = append(children, node)
 		if p.current() == TOKComma {
 			err := p.match(TOKComma)
@@ -531,17 +551,17 @@ func (p *Parser) parseMultiSelectHash() (ASTNode, error) {
 			break
 		}
 	}
-	return ASTNode{
+	return p.withSpan(ASTNode{
 		NodeType: ASTMultiSelectHash,
 		Children: children,
-	}, nil
+	}, start), nil
 }
 
 func (p *Parser) projectIfSlice(left ASTNode, right ASTNode) (ASTNode, error) {
-	indexExpr := ASTNode{
+	indexExpr := p.withSpan(ASTNode{
 		NodeType: ASTIndexExpression,
 		Children: []ASTNode{left, right},
-	}
+	}, left.Start)
 	if right.NodeType == ASTSlice {
 		right, err := p.parseProjectionRHS(bindingPowers[TOKStar])
 		return ASTNode{
@@ -563,7 +583,7 @@ func (p *Parser) parseFilter(node ASTNode) (ASTNode, error) {
 		return ASTNode{}, err
 	}
 	if p.current() == TOKFlatten {
-		right = ASTNode{NodeType: ASTIdentity}
+		right = p.implicitIdentity(p.lookaheadToken(0).position)
 	} else {
 		right, err = p.parseProjectionRHS(bindingPowers[TOKFilter])
 		if err != nil {
@@ -598,7 +618,7 @@ func (p *Parser) parseDotRHS(bindingPower int) (ASTNode, error) {
 func (p *Parser) parseProjectionRHS(bindingPower int) (ASTNode, error) {
 	current := p.current()
 	if bindingPowers[current] < 10 {
-		return ASTNode{NodeType: ASTIdentity}, nil
+		return p.implicitIdentity(p.lookaheadToken(0).position), nil
 	} else if current == TOKLbracket {
 		return p.parseExpression(bindingPower)
 	} else if current == TOKFilter {
@@ -623,15 +643,17 @@ func (p *Parser) parseLetExpression() (ASTNode, error) {
 	if err != nil {
 		return ASTNode{}, err
 	}
+	bindingsNode := ASTNode{
+		NodeType: ASTBindings,
+		Children: bindings,
+	}
+	if len(bindings) != 0 {
+		bindingsNode.Start = bindings[0].Start
+		bindingsNode.End = bindings[len(bindings)-1].End
+	}
 	return ASTNode{
 		NodeType: ASTLetExpression,
-		Children: []ASTNode{
-			{
-				NodeType: ASTBindings,
-				Children: bindings,
-			},
-			expression,
-		},
+		Children: []ASTNode{bindingsNode, expression},
 	}, nil
 }
 
@@ -697,6 +719,26 @@ func (p *Parser) advance() {
 	p.index++
 }
 
+// withSpan sets the span of a node, from the start offset
+// to the end of the last consumed token.
+func (p *Parser) withSpan(node ASTNode, start int) ASTNode {
+	node.Start = start
+	node.End = start
+	if p.index > 0 {
+		_, end := p.lookaheadToken(-1).span(p.expression)
+		if end > start {
+			node.End = end
+		}
+	}
+	return node
+}
+
+// implicitIdentity creates an identity node that is implied by
+// the grammar and therefore has an empty span.
+func (p *Parser) implicitIdentity(offset int) ASTNode {
+	return ASTNode{NodeType: ASTIdentity, Start: offset, End: offset}
+}
+
 func tokensOneOf(elements []TokType, token TokType) bool {
 	for _, elem := range elements {
 		if elem == token {
@@ -714,6 +756,26 @@ func (p *Parser) syntaxError(msg string) SyntaxError {
 	}
 }
 
+// decodeJSON decodes the value of a JSON literal, numbers are decoded
+// as json.Number when the parser uses numbers.
+func (p *Parser) decodeJSON(value string) (any, error) {
+	if !p.options.UseNumber {
+		var parsed any
+		err := json.Unmarshal([]byte(value), &parsed)
+		return parsed, err
+	}
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	var parsed any
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	return parsed, nil
+}
+
 // Create a SyntaxError based on the provided token.
 // This differs from syntaxError() which creates a SyntaxError
 // based on the current lookahead token.
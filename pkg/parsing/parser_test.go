@@ -1,6 +1,7 @@
 package parsing
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -179,6 +180,27 @@ func TestParsingErrors(t *testing.T) {
 	}
 }
 
+func TestParsingJSONLiteralsWithUseNumber(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       any
+	}{
+		{"`9007199254740993`", json.Number("9007199254740993")},
+		{"`[0.1, \"a\"]`", []any{json.Number("0.1"), "a"}},
+		{"`{\"a\": 1e400}`", map[string]any{"a": json.Number("1e400")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			node, err := NewParser(WithUseNumber()).Parse(tt.expression)
+			assert.NoError(err)
+			assert.Equal(tt.want, node.Value)
+		})
+	}
+	_, err := NewParser(WithUseNumber()).Parse("`1 2`")
+	assert.Error(t, err)
+}
+
 var prettyPrinted = `ASTProjection {
   children: {
     ASTField {
@@ -241,6 +263,42 @@ func TestPrettyPrintedCompNode(t *testing.T) {
 	assert.Equal(parsed.PrettyPrint(0), prettyPrintedCompNode)
 }
 
+func TestParsedSpans(t *testing.T) {
+	tests := []struct {
+		expression string
+		path       []int
+		want       string
+	}{
+		{"foo.bar[0]", nil, "foo.bar[0]"},
+		{"foo.bar[0]", []int{1}, "bar[0]"},
+		{"foo.bar[0]", []int{1, 1}, "0"},
+		{"foo[?a == `1`].\"b c\"", []int{1}, `"b c"`},
+		{"foo[?a == `1`].\"b c\"", []int{2}, "a == `1`"},
+		{"foo[?a == `1`].\"b c\"", []int{2, 1}, "`1`"},
+		{"[*].{x: 'y', z: a[1:2]}", []int{1, 0}, "x: 'y'"},
+		{"[*].{x: 'y', z: a[1:2]}", []int{1, 0, 0}, "'y'"},
+		{"[*].{x: 'y', z: a[1:2]}", []int{1, 1, 0, 0, 1}, "1:2"},
+		{"let $x = a, $y = b in sort_by(@, &foo)", []int{0}, "$x = a, $y = b"},
+		{"let $x = a, $y = b in sort_by(@, &foo)", []int{1}, "sort_by(@, &foo)"},
+		{"let $x = a, $y = b in sort_by(@, &foo)", []int{1, 1}, "&foo"},
+		{"a[].b[] || !c", []int{0, 0, 0}, "a[].b"},
+		{"(a + b) * -c", []int{1}, "-c"},
+		{"*.foo", []int{0}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			parser := NewParser()
+			node, err := parser.Parse(tt.expression)
+			assert.Nil(err)
+			for _, i := range tt.path {
+				node = node.Children[i]
+			}
+			assert.Equal(tt.want, tt.expression[node.Start:node.End])
+		})
+	}
+}
+
 func BenchmarkParseIdentifier(b *testing.B) {
 	runParseBenchmark(b, exprIdentifier)
 }
@@ -0,0 +1,424 @@
+// Package stream evaluates parsed expressions against JSON documents read from a
+// json.Decoder, without decoding the whole document first.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/jmespath-community/go-jmespath/pkg/util"
+)
+
+// Search evaluates an expression against the next JSON value read from the decoder
+// and consumes that value entirely. The result is the same as evaluating the expression
+// against the decoded value, but paths made of fields, indexes, slices, projections and
+// filter projections are evaluated while the value is read: only the values they select
+// are decoded, and array elements are decoded and evaluated one at a time. The rest of
+// the expression is evaluated on the decoded values, and expressions referring to the
+// root with `$` decode the whole value. Options apply to each of those evaluations, the
+// limits of interpreter.WithMaxSteps, WithMaxDepth and WithMaxResultElements are
+// therefore checked against each of them separately rather than the whole search.
+func Search(ctx context.Context, node parsing.ASTNode, decoder *json.Decoder, opts ...interpreter.Option) (any, error) {
+	s := &streamer{
+		ctx:     ctx,
+		decoder: decoder,
+		opts:    append(opts[:len(opts):len(opts)], interpreter.WithContext(ctx)),
+	}
+	if usesRoot(node) {
+		return plan{node: node}.next(s)
+	}
+	return compile(node, plan{node: parsing.ASTNode{NodeType: parsing.ASTIdentity}}).next(s)
+}
+
+type streamer struct {
+	ctx     context.Context
+	decoder *json.Decoder
+	opts    []interpreter.Option
+}
+
+// plan evaluates an expression, streaming the values it reads when possible.
+type plan struct {
+	// node is the expression evaluated by the plan.
+	node parsing.ASTNode
+	// stream evaluates the expression against the next value of the decoder,
+	// it is nil when the value has to be decoded first.
+	stream func(s *streamer) (any, error)
+}
+
+// next evaluates the plan against the next value of the decoder.
+func (p plan) next(s *streamer) (any, error) {
+	if p.stream != nil {
+		return p.stream(s)
+	}
+	var value any
+	if err := s.decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return p.eval(s, value)
+}
+
+// eval evaluates the plan against a decoded value.
+func (p plan) eval(s *streamer, value any) (any, error) {
+	if p.node.NodeType == parsing.ASTIdentity {
+		return value, nil
+	}
+	return interpreter.NewInterpreter(value, nil).Execute(p.node, value, s.opts...)
+}
+
+// compile returns the plan evaluating a node then the given plan on its result.
+func compile(node parsing.ASTNode, then plan) plan {
+	switch node.NodeType {
+	case parsing.ASTIdentity, parsing.ASTCurrentNode:
+		return then
+	case parsing.ASTPipe:
+		for i := len(node.Children) - 1; i >= 0; i-- {
+			then = compile(node.Children[i], then)
+		}
+		return then
+	case parsing.ASTSubexpression:
+		// unlike a pipe, the right side is not evaluated when the left side is null,
+		// the nodes streamed by the right side already evaluate to null against null
+		right := compile(node.Children[1], then)
+		self := plan{node: pipe(subexpression(node.Children[1]), then.node), stream: right.stream}
+		return compile(node.Children[0], self)
+	case parsing.ASTField:
+		name := node.Value.(string)
+		self := plan{node: pipe(node, then.node)}
+		self.stream = func(s *streamer) (any, error) {
+			return s.field(self, name, then)
+		}
+		return self
+	case parsing.ASTIndexExpression:
+		right := node.Children[1]
+		if right.NodeType == parsing.ASTIndex && right.Value.(int) >= 0 {
+			i := right.Value.(int)
+			self := plan{node: pipe(right, then.node)}
+			self.stream = func(s *streamer) (any, error) {
+				return s.index(self, i, then)
+			}
+			return compile(node.Children[0], self)
+		}
+	case parsing.ASTProjection:
+		left, right := node.Children[0], node.Children[1]
+		element := compile(right, plan{node: parsing.ASTNode{NodeType: parsing.ASTIdentity}})
+		if left.NodeType == parsing.ASTIndexExpression && left.Children[1].NodeType == parsing.ASTSlice {
+			parts := left.Children[1].Value.([]*int)
+			if !isForwardSlice(parts) {
+				break
+			}
+			sliced := parsing.ASTNode{
+				NodeType: parsing.ASTIndexExpression,
+				Children: []parsing.ASTNode{{NodeType: parsing.ASTIdentity}, left.Children[1]},
+			}
+			self := plan{node: pipe(projection(node, sliced), then.node)}
+			self.stream = func(s *streamer) (any, error) {
+				return s.project(self, parts, element, then)
+			}
+			return compile(left.Children[0], self)
+		}
+		if left.NodeType == parsing.ASTFlatten {
+			break
+		}
+		self := plan{node: pipe(projection(node, parsing.ASTNode{NodeType: parsing.ASTIdentity}), then.node)}
+		self.stream = func(s *streamer) (any, error) {
+			return s.project(self, nil, element, then)
+		}
+		return compile(left, self)
+	case parsing.ASTFilterProjection:
+		left, right, condition := node.Children[0], node.Children[1], node.Children[2]
+		if canFail(left) {
+			// the errors of the left side evaluate the filter to null, they
+			// can only be told apart from the errors of the plan then when
+			// the filter is evaluated on the decoded value
+			break
+		}
+		self := plan{node: pipe(projection(node, parsing.ASTNode{NodeType: parsing.ASTIdentity}), then.node)}
+		self.stream = func(s *streamer) (any, error) {
+			return s.filter(self, condition, plan{node: right}, then)
+		}
+		return compile(left, self)
+	}
+	return plan{node: pipe(node, then.node)}
+}
+
+// field streams the next value for the plan self, the plan then is evaluated against
+// the value of the named field of an object and the other values are skipped.
+func (s *streamer) field(self plan, name string, then plan) (any, error) {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return s.evalOther(self, token)
+	}
+	var result any
+	found := false
+	for s.decoder.More() {
+		if err := s.ctx.Err(); err != nil {
+			return nil, err
+		}
+		key, err := s.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if key == name {
+			// like encoding/json, the last duplicate key wins
+			if result, err = then.next(s); err != nil {
+				return nil, err
+			}
+			found = true
+		} else if err := s.skip(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.decoder.Token(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return then.eval(s, nil)
+	}
+	return result, nil
+}
+
+// index streams the next value for the plan self, the plan then is evaluated against
+// an element of an array and the other elements are skipped.
+func (s *streamer) index(self plan, index int, then plan) (any, error) {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('[') {
+		return s.evalOther(self, token)
+	}
+	var result any
+	found := false
+	for i := 0; s.decoder.More(); i++ {
+		if err := s.ctx.Err(); err != nil {
+			return nil, err
+		}
+		if i == index {
+			if result, err = then.next(s); err != nil {
+				return nil, err
+			}
+			found = true
+		} else if err := s.skip(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.decoder.Token(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return then.eval(s, nil)
+	}
+	return result, nil
+}
+
+// project streams the next value for the plan self, the element plan is evaluated against
+// the elements of an array, or those selected by the slice parts, and the plan then against
+// the collected results.
+func (s *streamer) project(self plan, parts []*int, element plan, then plan) (any, error) {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('[') {
+		return s.evalOther(self, token)
+	}
+	collected := []any{}
+	for i := 0; s.decoder.More(); i++ {
+		if err := s.ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !inSlice(parts, i) {
+			if err := s.skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		result, err := element.next(s)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			collected = append(collected, result)
+		}
+	}
+	if _, err := s.decoder.Token(); err != nil {
+		return nil, err
+	}
+	return then.eval(s, collected)
+}
+
+// filter streams the next value for the plan self, the elements of an array are decoded one
+// at a time and the element plan is evaluated against those matching the condition, then
+// the plan then is evaluated against the collected results.
+func (s *streamer) filter(self plan, condition parsing.ASTNode, element plan, then plan) (any, error) {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('[') {
+		return s.evalOther(self, token)
+	}
+	collected := []any{}
+	test := plan{node: condition}
+	for s.decoder.More() {
+		if err := s.ctx.Err(); err != nil {
+			return nil, err
+		}
+		var value any
+		if err := s.decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		matches, err := test.eval(s, value)
+		if err != nil {
+			return nil, err
+		}
+		if util.IsFalse(matches) {
+			continue
+		}
+		result, err := element.eval(s, value)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			collected = append(collected, result)
+		}
+	}
+	if _, err := s.decoder.Token(); err != nil {
+		return nil, err
+	}
+	return then.eval(s, collected)
+}
+
+// evalOther evaluates a plan against a value that is not of the type it streams, given
+// its first token. Arrays and objects are skipped, the plan evaluates the same against
+// empty ones.
+func (s *streamer) evalOther(p plan, token json.Token) (any, error) {
+	switch token {
+	case json.Delim('{'):
+		if err := s.skipRest(token); err != nil {
+			return nil, err
+		}
+		return p.eval(s, map[string]any{})
+	case json.Delim('['):
+		if err := s.skipRest(token); err != nil {
+			return nil, err
+		}
+		return p.eval(s, []any{})
+	}
+	return p.eval(s, token)
+}
+
+// skip reads the next value without decoding it.
+func (s *streamer) skip() error {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return err
+	}
+	return s.skipRest(token)
+}
+
+// skipRest reads the rest of a value whose first token was read.
+func (s *streamer) skipRest(token json.Token) error {
+	depth := 0
+	for {
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+		var err error
+		if token, err = s.decoder.Token(); err != nil {
+			return err
+		}
+	}
+}
+
+// isForwardSlice reports whether a slice selects elements without knowing the
+// length of the array, its start and stop are not negative and its step is positive.
+func isForwardSlice(parts []*int) bool {
+	start, stop, step := parts[0], parts[1], parts[2]
+	return (start == nil || *start >= 0) && (stop == nil || *stop >= 0) && (step == nil || *step > 0)
+}
+
+func inSlice(parts []*int, i int) bool {
+	if parts == nil {
+		return true
+	}
+	start, stop, step := 0, -1, 1
+	if parts[0] != nil {
+		start = *parts[0]
+	}
+	if parts[1] != nil {
+		stop = *parts[1]
+	}
+	if parts[2] != nil {
+		step = *parts[2]
+	}
+	return i >= start && (stop < 0 || i < stop) && (i-start)%step == 0
+}
+
+// projection returns a copy of a projection node with the given left side.
+func projection(node parsing.ASTNode, left parsing.ASTNode) parsing.ASTNode {
+	children := make([]parsing.ASTNode, len(node.Children))
+	copy(children, node.Children)
+	children[0] = left
+	node.Children = children
+	return node
+}
+
+// subexpression returns a node evaluating right on the current node unless it is null.
+func subexpression(right parsing.ASTNode) parsing.ASTNode {
+	return parsing.ASTNode{
+		NodeType: parsing.ASTSubexpression,
+		Children: []parsing.ASTNode{{NodeType: parsing.ASTCurrentNode}, right},
+	}
+}
+
+// pipe returns a node evaluating left then right on its result.
+func pipe(left parsing.ASTNode, right parsing.ASTNode) parsing.ASTNode {
+	if right.NodeType == parsing.ASTIdentity {
+		return left
+	}
+	return parsing.ASTNode{NodeType: parsing.ASTPipe, Children: []parsing.ASTNode{left, right}}
+}
+
+// canFail reports whether evaluating an expression can fail, except when the evaluation
+// is aborted: it calls functions, refers to variables or has slices with a zero step.
+func canFail(node parsing.ASTNode) bool {
+	switch node.NodeType {
+	case parsing.ASTFunctionExpression, parsing.ASTVariable:
+		return true
+	case parsing.ASTSlice:
+		if step := node.Value.([]*int)[2]; step != nil && *step == 0 {
+			return true
+		}
+	}
+	for _, child := range node.Children {
+		if canFail(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesRoot reports whether an expression refers to the root of the document.
+func usesRoot(node parsing.ASTNode) bool {
+	if node.NodeType == parsing.ASTRootNode {
+		return true
+	}
+	for _, child := range node.Children {
+		if usesRoot(child) {
+			return true
+		}
+	}
+	return false
+}
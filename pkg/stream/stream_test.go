@@ -0,0 +1,216 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jmespath-community/go-jmespath/pkg/interpreter"
+	"github.com/jmespath-community/go-jmespath/pkg/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+const streamData = `{
+	"records": [
+		{"id": 1, "status": "ok", "tags": ["a"]},
+		{"id": 2, "status": "error", "tags": ["b", "c"], "nested": {"id": 20}},
+		{"id": 3, "status": "error"},
+		"not a record",
+		{"id": 4, "status": "ok", "tags": []}
+	],
+	"meta": {"count": 5, "source": "export", "meta": {"count": 6}},
+	"name": "records",
+	"matrix": [[1, 2], [3, 4]],
+	"meta": {"count": 7, "source": "duplicate"}
+}`
+
+var streamExpressions = []string{
+	"records",
+	"meta.count",
+	"meta.meta.count",
+	"meta.missing.count",
+	"missing",
+	"name",
+	"name.foo",
+	"name[0]",
+	"name[0:2]",
+	"name[*]",
+	"name[?id]",
+	"meta[0]",
+	"meta[*]",
+	"meta[?count]",
+	"records.id",
+	"records[1].id",
+	"records[10].id",
+	"records[-1].id",
+	"records[1].tags[1]",
+	"records[*].id",
+	"records[*].nested.id",
+	"records[*].tags[0]",
+	"records[*].tags[*]",
+	"records[1:4].id",
+	"records[::2].id",
+	"records[:2]",
+	"records[-2:].id",
+	"records[::-1].id",
+	"records[?status=='error'].id",
+	"records[?status=='error'] | [0].id",
+	"records[?status=='error'].id | length(@)",
+	"records[?tags[0] == 'b'].nested",
+	"records[?id > `2`][status, id]",
+	"records[].id",
+	"records[*].id | [0]",
+	"matrix[*][0]",
+	"matrix[0][1]",
+	"matrix[*][*]",
+	"length(meta.*)",
+	"@.meta.source",
+	"meta | source",
+	"length(records)",
+	"{count: meta.count}",
+	"records[*].id | $.meta.count",
+	"records[?id == $.meta.count].id",
+	"let $n = meta.count in records[?id < $n].id",
+}
+
+func TestSearch(t *testing.T) {
+	var data any
+	assert.NoError(t, json.Unmarshal([]byte(streamData), &data))
+	for _, expression := range streamExpressions {
+		t.Run(expression, func(t *testing.T) {
+			assert := assert.New(t)
+			node, err := parsing.NewParser().Parse(expression)
+			assert.NoError(err)
+			want, wantErr := interpreter.NewInterpreter(data, nil).Execute(node, data)
+			decoder := json.NewDecoder(strings.NewReader(streamData))
+			got, err := Search(context.Background(), node, decoder)
+			assert.Equal(wantErr, err)
+			assert.Equal(want, got)
+			// the whole document is consumed
+			assert.False(decoder.More())
+		})
+	}
+}
+
+func TestSearchNullSubexpressions(t *testing.T) {
+	tests := []struct {
+		document   string
+		expression string
+	}{
+		{`{"x": 1}`, "a.not_null(@, 'x')"},
+		{`{"x": 1}`, "a.length(@)"},
+		{`{"x": 1}`, "a.[b]"},
+		{`{"x": 1}`, "a.{k: b}"},
+		{`{"x": 1}`, "a.b.length(@)"},
+		{`{"x": 1}`, "a.length(@) | not_null(@, 'y')"},
+		{`{"a": null}`, "a.not_null(@, 'x')"},
+		{`{"a": null}`, "a.b.not_null(@, 'x')"},
+		{`{"a": {"b": null}}`, "a.b.length(@)"},
+		{`{"a": {"b": [1, 2]}}`, "a[0].not_null(@, 'z')"},
+		{`{"a": {"b": [1, 2]}}`, "a.b[0].not_null(@, 'z')"},
+		{`{"a": {"b": [1, 2]}}`, "a.b[5].not_null(@, 'z')"},
+		{`{"a": {"b": [1, 2]}}`, "a.b.length(@)"},
+		{`{"a": [{"b": 1}, {"c": 2}]}`, "a[*].b.not_null(@, 'z')"},
+		{`null`, "a.length(@)"},
+		{`{"foo": "x"}`, "abs(foo)[?a]"},
+		{`{"foo": "x", "bar": 1}`, "abs(foo)[?a] | not_null(@, 'z')"},
+		{`{"foo": {"bar": "x", "baz": 1}}`, "foo.abs(bar)[?a].b"},
+		{`{"foo": [{"a": "x"}]}`, "foo[?abs(a)][?b]"},
+		{`{"foo": [[1]]}`, "foo[::0][?a]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.document+" "+tt.expression, func(t *testing.T) {
+			assert := assert.New(t)
+			var data any
+			assert.NoError(json.Unmarshal([]byte(tt.document), &data))
+			node, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(err)
+			want, wantErr := interpreter.NewInterpreter(data, nil).Execute(node, data)
+			got, err := Search(context.Background(), node, json.NewDecoder(strings.NewReader(tt.document)))
+			assert.Equal(wantErr, err)
+			assert.Equal(want, got)
+		})
+	}
+}
+
+func TestSearchScalars(t *testing.T) {
+	for _, document := range []string{`"abc"`, `1`, `true`, `null`, `[]`, `{}`} {
+		for _, expression := range []string{"foo", "[0]", "[0:2]", "[*]", "[?@]", "@"} {
+			t.Run(document+" "+expression, func(t *testing.T) {
+				assert := assert.New(t)
+				var data any
+				assert.NoError(json.Unmarshal([]byte(document), &data))
+				node, err := parsing.NewParser().Parse(expression)
+				assert.NoError(err)
+				want, wantErr := interpreter.NewInterpreter(data, nil).Execute(node, data)
+				got, err := Search(context.Background(), node, json.NewDecoder(strings.NewReader(document)))
+				assert.Equal(wantErr, err)
+				assert.Equal(want, got)
+			})
+		}
+	}
+}
+
+func TestSearchStreams(t *testing.T) {
+	tests := []struct {
+		expression string
+		streamed   bool
+	}{
+		{"records[?status=='error'].id", true},
+		{"meta.count", true},
+		{"records[1].tags[0]", true},
+		{"records[1:4].id", true},
+		{"records[*].id | [0]", true},
+		{"records[-1]", false},
+		{"records[::-1]", false},
+		{"records[].id", false},
+		{"length(records)", false},
+		{"abs(records)[?id]", false},
+		{"records[?id].abs(id)", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			node, err := parsing.NewParser().Parse(tt.expression)
+			assert.NoError(t, err)
+			p := compile(node, plan{node: parsing.ASTNode{NodeType: parsing.ASTIdentity}})
+			assert.Equal(t, tt.streamed, p.stream != nil)
+		})
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	assert := assert.New(t)
+	node, err := parsing.NewParser().Parse("records[*].id")
+	assert.NoError(err)
+	_, err = Search(context.Background(), node, json.NewDecoder(strings.NewReader(`{"records": [{"id": 1}, {"id": }]}`)))
+	assert.Error(err)
+	node, err = parsing.NewParser().Parse("records[*].abs(id)")
+	assert.NoError(err)
+	_, err = Search(context.Background(), node, json.NewDecoder(strings.NewReader(`{"records": [{"id": 1}, {"id": "a"}]}`)))
+	assert.ErrorContains(err, "invalid type")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Search(ctx, node, json.NewDecoder(strings.NewReader(`{"records": [{"id": 1}]}`)))
+	assert.ErrorIs(err, context.Canceled)
+	// the errors of the left side of filters are ignored, unlike exceeded budgets
+	node, err = parsing.NewParser().Parse("abs(foo)[?a]")
+	assert.NoError(err)
+	result, err := Search(context.Background(), node, json.NewDecoder(strings.NewReader(`{"foo": "x"}`)))
+	assert.NoError(err)
+	assert.Nil(result)
+	_, err = Search(context.Background(), node, json.NewDecoder(strings.NewReader(`{"foo": "x"}`)), interpreter.WithMaxSteps(2))
+	var budgetErr *interpreter.BudgetExceededError
+	assert.ErrorAs(err, &budgetErr)
+}
+
+func TestSearchUseNumber(t *testing.T) {
+	assert := assert.New(t)
+	node, err := parsing.NewParser().Parse("records[?id > `1`].id")
+	assert.NoError(err)
+	decoder := json.NewDecoder(strings.NewReader(`{"records": [{"id": 1}, {"id": 9007199254740993}]}`))
+	decoder.UseNumber()
+	result, err := Search(context.Background(), node, decoder)
+	assert.NoError(err)
+	assert.Equal([]any{json.Number("9007199254740993")}, result)
+}
@@ -0,0 +1,142 @@
+package util
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// InexactDigits is the number of decimal digits kept when a number
+// has no finite decimal representation, like the result of 1 / 3.
+const InexactDigits = 34
+
+// ToRat converts a number to an exact rational, see ToNumber. Floating point
+// values are converted from their shortest decimal representation, so that
+// float64(0.1) is converted to 1/10. Infinities and NaN cannot be converted.
+func ToRat(v any) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(string(n))
+	case float64:
+		return floatToRat(n, 64)
+	}
+	value := reflect.ValueOf(v)
+	switch {
+	case value.CanFloat():
+		return floatToRat(value.Float(), value.Type().Bits())
+	case value.CanInt():
+		return new(big.Rat).SetInt64(value.Int()), true
+	case value.CanUint():
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(value.Uint())), true
+	}
+	return nil, false
+}
+
+func floatToRat(f float64, bitSize int) (*big.Rat, bool) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+// ToJSONNumber converts a number to a json.Number without losing precision.
+func ToJSONNumber(v any) (json.Number, bool) {
+	if n, ok := v.(json.Number); ok {
+		return n, true
+	}
+	r, ok := ToRat(v)
+	if !ok {
+		return "", false
+	}
+	return RatToJSONNumber(r), true
+}
+
+// RatToJSONNumber converts a rational to a json.Number. Rationals without a finite
+// decimal representation are rounded to InexactDigits decimal digits.
+func RatToJSONNumber(r *big.Rat) json.Number {
+	if r.IsInt() {
+		return json.Number(r.Num().String())
+	}
+	digits, exact := decimalDigits(r.Denom())
+	if !exact {
+		digits = InexactDigits
+	}
+	s := r.FloatString(digits)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		s = "0"
+	}
+	return json.Number(s)
+}
+
+// decimalDigits returns the number of decimal digits needed to represent
+// the inverse of a denominator, if it is finite.
+func decimalDigits(denominator *big.Int) (int, bool) {
+	d := new(big.Int).Set(denominator)
+	two, five := big.NewInt(2), big.NewInt(5)
+	var twos, fives int
+	remainder := new(big.Int)
+	for {
+		quotient, r := new(big.Int).QuoRem(d, two, remainder)
+		if r.Sign() != 0 {
+			break
+		}
+		d, twos = quotient, twos+1
+	}
+	for {
+		quotient, r := new(big.Int).QuoRem(d, five, remainder)
+		if r.Sign() != 0 {
+			break
+		}
+		d, fives = quotient, fives+1
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
+
+// CompareNumbers compares two numbers exactly, it returns -1, 0 or 1 when the
+// left number is less than, equal to or greater than the right number.
+// NaN is not ordered.
+func CompareNumbers(left any, right any) (int, bool) {
+	if l, ok := left.(float64); ok {
+		if r, ok := right.(float64); ok {
+			return compareFloats(l, r)
+		}
+	}
+	l, lok := ToRat(left)
+	r, rok := ToRat(right)
+	if lok && rok {
+		return l.Cmp(r), true
+	}
+	// infinities and NaN are only represented as floats
+	lf, lfok := ToNumber(left)
+	rf, rfok := ToNumber(right)
+	switch {
+	case lok && rfok && math.IsInf(rf, 0):
+		return -int(math.Copysign(1, rf)), true
+	case rok && lfok && math.IsInf(lf, 0):
+		return int(math.Copysign(1, lf)), true
+	case lfok && rfok:
+		return compareFloats(lf, rf)
+	}
+	return 0, false
+}
+
+func compareFloats(l float64, r float64) (int, bool) {
+	switch {
+	case l < r:
+		return -1, true
+	case l > r:
+		return 1, true
+	}
+	return 0, l == r
+}
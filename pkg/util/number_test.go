@@ -0,0 +1,82 @@
+package util
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToJSONNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  any
+		want   json.Number
+		wantOk bool
+	}{
+		{"json.Number", json.Number("9007199254740993"), "9007199254740993", true},
+		{"float64", 0.1, "0.1", true},
+		{"float32", float32(0.1), "0.1", true},
+		{"int64", int64(math.MaxInt64), "9223372036854775807", true},
+		{"uint64", uint64(math.MaxUint64), "18446744073709551615", true},
+		{"exponent", 1e21, "1000000000000000000000", true},
+		{"infinity", math.Inf(1), "", false},
+		{"NaN", math.NaN(), "", false},
+		{"string", "1", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ToJSONNumber(tt.value)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOk, ok)
+		})
+	}
+}
+
+func TestRatToJSONNumber(t *testing.T) {
+	tests := []struct {
+		rat  string
+		want json.Number
+	}{
+		{"3", "3"},
+		{"-3/4", "-0.75"},
+		{"1/80", "0.0125"},
+		{"1/3", "0.3333333333333333333333333333333333"},
+		{"-2/3", "-0.6666666666666666666666666666666667"},
+		{"-1/30000000000000000000000000000000000000", "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rat, func(t *testing.T) {
+			r, ok := new(big.Rat).SetString(tt.rat)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, RatToJSONNumber(r))
+		})
+	}
+}
+
+func TestCompareNumbers(t *testing.T) {
+	tests := []struct {
+		name   string
+		left   any
+		right  any
+		want   int
+		wantOk bool
+	}{
+		{"floats", 1.0, 2.0, -1, true},
+		{"beyond float64", json.Number("9007199254740993"), json.Number("9007199254740992"), 1, true},
+		{"json.Number and int", json.Number("1.0"), 1, 0, true},
+		{"json.Number and float", json.Number("0.1"), 0.1, 0, true},
+		{"infinity", json.Number("1e400"), math.Inf(1), -1, true},
+		{"NaN", math.NaN(), 1.0, 0, false},
+		{"not a number", "1", 1.0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := CompareNumbers(tt.left, tt.right)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOk, ok)
+		})
+	}
+}
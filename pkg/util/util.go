@@ -1,10 +1,13 @@
 package util
 
 import (
-	"errors"
+	"encoding/json"
 	"math"
 	"reflect"
+	"sort"
 
+	jperror "github.com/jmespath-community/go-jmespath/pkg/error"
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
 	"golang.org/x/exp/constraints"
 )
 
@@ -21,6 +24,8 @@ func IsFalse(value any) bool {
 		return len(v) == 0
 	case map[string]any:
 		return len(v) == 0
+	case *ordered.Object:
+		return v.Len() == 0
 	case string:
 		return len(v) == 0
 	case nil:
@@ -49,8 +54,46 @@ func IsFalse(value any) bool {
 
 // ObjsEqual is a generic object equality check.
 // It will take two arbitrary objects and recursively determine
-// if they are equal.
+// if they are equal. Numbers are equal when they have the same
+// value, whatever their Go type, see CompareNumbers.
 func ObjsEqual(left any, right any) bool {
+	if l, ok := ToNumber(left); ok {
+		r, ok := ToNumber(right)
+		if !ok {
+			return false
+		}
+		if c, ok := CompareNumbers(left, right); ok {
+			return c == 0
+		}
+		// infinities and NaN
+		return l == r
+	}
+	if IsSliceType(left) && IsSliceType(right) {
+		l, r := reflect.ValueOf(left), reflect.ValueOf(right)
+		if l.Len() != r.Len() {
+			return false
+		}
+		for i := 0; i < l.Len(); i++ {
+			if !ObjsEqual(l.Index(i).Interface(), r.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+	if l, ok := ToObject(left); ok {
+		// the order of keys does not matter
+		r, ok := ToObject(right)
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for key, value := range l {
+			other, ok := r[key]
+			if !ok || !ObjsEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	}
 	return reflect.DeepEqual(left, right)
 }
 
@@ -98,7 +141,7 @@ func computeSliceParams(length int, parts []SliceParam) ([]int, error) {
 	if !parts[2].Specified {
 		step = 1
 	} else if parts[2].N == 0 {
-		return nil, errors.New("invalid slice, step cannot be 0")
+		return nil, jperror.New(jperror.InvalidValue, "invalid slice, step cannot be 0")
 	} else {
 		step = parts[2].N
 	}
@@ -170,15 +213,31 @@ func ToArrayArray(data any) ([][]any, bool) {
 	return result, true
 }
 
-// ToArrayNum converts an empty interface type to a slice of float64.
+// ToArray converts any slice to a slice of empty interfaces.
+// Slices of empty interfaces are returned as is.
+func ToArray(data any) ([]any, bool) {
+	if d, ok := data.([]any); ok {
+		return d, true
+	}
+	if !IsSliceType(data) {
+		return nil, false
+	}
+	v := reflect.ValueOf(data)
+	result := make([]any, v.Len())
+	for i := range result {
+		result[i] = v.Index(i).Interface()
+	}
+	return result, true
+}
+
+// ToArrayNum converts any slice of numbers to a slice of float64, see ToNumber.
 // If any element in the array cannot be converted, then nil is returned
 // along with a second value of false.
 func ToArrayNum(data any) ([]float64, bool) {
-	// Is there a better way to do this with reflect?
 	if d, ok := data.([]any); ok {
 		result := make([]float64, len(d))
 		for i, el := range d {
-			item, ok := el.(float64)
+			item, ok := ToNumber(el)
 			if !ok {
 				return nil, false
 			}
@@ -186,7 +245,19 @@ func ToArrayNum(data any) ([]float64, bool) {
 		}
 		return result, true
 	}
-	return nil, false
+	if !IsSliceType(data) {
+		return nil, false
+	}
+	v := reflect.ValueOf(data)
+	result := make([]float64, v.Len())
+	for i := range result {
+		item, ok := ToNumber(v.Index(i).Interface())
+		if !ok {
+			return nil, false
+		}
+		result[i] = item
+	}
+	return result, true
 }
 
 // ToArrayStr converts an empty interface type to a slice of strings.
@@ -211,11 +282,11 @@ func ToArrayStr(data any) ([]string, bool) {
 }
 
 // ToInteger converts an empty interface to a integer.
-// It expects the empty interface to represent a float64 JSON number.
+// It expects the empty interface to represent a number, see ToNumber.
 // If the empty interface cannot be converted or if the number
 // is not an integer, the function returns a second boolean value false.
 func ToInteger(v any) (int, bool) {
-	if num, ok := v.(float64); ok {
+	if num, ok := ToNumber(v); ok {
 		if math.Floor(num) != num {
 			return 0, false
 		}
@@ -224,8 +295,16 @@ func ToInteger(v any) (int, bool) {
 	return 0, false
 }
 
-// ToNumber converts a numeric interface to a float64.
+// ToNumber converts a numeric interface to a float64. Numbers are
+// values of any Go integer or floating point kind, and json.Number.
 func ToNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		num, err := n.Float64()
+		return num, err == nil
+	}
 	value := reflect.ValueOf(v)
 	if value.CanFloat() {
 		return value.Float(), true
@@ -239,6 +318,12 @@ func ToNumber(v any) (float64, bool) {
 	return 0, false
 }
 
+// IsNumber reports whether a value is a number, see ToNumber.
+func IsNumber(v any) bool {
+	_, ok := ToNumber(v)
+	return ok
+}
+
 func ToPositiveInteger(v any) (int, bool) {
 	num, ok := ToInteger(v)
 	return num, ok && num >= 0
@@ -251,6 +336,71 @@ func IsSliceType(v any) bool {
 	return reflect.TypeOf(v).Kind() == reflect.Slice
 }
 
+// IsObject reports whether a value is a JSON object, a map[string]any or an *ordered.Object.
+func IsObject(v any) bool {
+	switch v.(type) {
+	case map[string]any, *ordered.Object:
+		return true
+	}
+	return false
+}
+
+// IsMapType reports whether a value is a Go map with string keys, e.g. a map[string]int.
+func IsMapType(v any) bool {
+	if v == nil {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String
+}
+
+// ToObject returns the keys and values of a JSON object as a map, see IsObject.
+// Go maps with string keys are converted to a map[string]any, see IsMapType.
+// Nested ordered objects are not converted.
+func ToObject(v any) (map[string]any, bool) {
+	switch o := v.(type) {
+	case map[string]any:
+		return o, true
+	case *ordered.Object:
+		return o.Map(), true
+	}
+	if !IsMapType(v) {
+		return nil, false
+	}
+	value := reflect.ValueOf(v)
+	result := make(map[string]any, value.Len())
+	iter := value.MapRange()
+	for iter.Next() {
+		result[iter.Key().String()] = iter.Value().Interface()
+	}
+	return result, true
+}
+
+// ObjectKeys returns the keys of a JSON object, in order for an *ordered.Object
+// and in lexicographic order for a map, see ToObject.
+func ObjectKeys(v any) ([]string, bool) {
+	switch o := v.(type) {
+	case map[string]any:
+		return SortedKeys(o), true
+	case *ordered.Object:
+		return o.Keys(), true
+	}
+	if object, ok := ToObject(v); ok {
+		return SortedKeys(object), true
+	}
+	return nil, false
+}
+
+// SortedKeys returns the keys of an object in lexicographic order.
+func SortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func Min[T constraints.Ordered](a T, b T) T {
 	if a < b {
 		return a
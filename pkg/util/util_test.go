@@ -1,8 +1,10 @@
 package util
 
 import (
+	"encoding/json"
 	"testing"
 
+	"github.com/jmespath-community/go-jmespath/pkg/ordered"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -72,6 +74,50 @@ func TestObjsEqual(t *testing.T) {
 	assert.True(!ObjsEqual(nil, "foo"))
 	assert.True(ObjsEqual([]int{}, []int{}))
 	assert.True(!ObjsEqual([]int{}, nil))
+	assert.True(ObjsEqual(1, 1.0))
+	assert.True(ObjsEqual(json.Number("1"), uint8(1)))
+	assert.True(!ObjsEqual(1, "1"))
+	assert.True(ObjsEqual([]int{1, 2}, []any{1.0, json.Number("2")}))
+	assert.True(!ObjsEqual([]int{1, 2}, []any{1.0}))
+	assert.True(ObjsEqual(map[string]any{"a": []any{1}}, map[string]any{"a": []float64{1}}))
+	assert.True(!ObjsEqual(map[string]any{"a": 1}, map[string]any{"b": 1}))
+	object := ordered.NewObject(2)
+	object.Set("b", 1)
+	object.Set("a", ordered.NewObject(0))
+	assert.True(ObjsEqual(object, map[string]any{"a": map[string]any{}, "b": 1.0}))
+	assert.True(ObjsEqual(map[string]any{"a": map[string]any{}, "b": 1.0}, object))
+	assert.True(!ObjsEqual(object, map[string]any{"b": 1.0}))
+}
+
+func TestIsFalseOrderedObject(t *testing.T) {
+	assert := assert.New(t)
+	object := ordered.NewObject(0)
+	assert.True(IsFalse(object))
+	object.Set("a", nil)
+	assert.False(IsFalse(object))
+}
+
+func TestToArrayNum(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  any
+		want   []float64
+		wantOk bool
+	}{
+		{"floats", []any{1.0, 2.0}, []float64{1, 2}, true},
+		{"mixed", []any{1, int64(2), json.Number("3"), float32(4)}, []float64{1, 2, 3, 4}, true},
+		{"typed", []int{1, 2}, []float64{1, 2}, true},
+		{"empty", []string{}, []float64{}, true},
+		{"strings", []any{1.0, "2"}, nil, false},
+		{"not an array", 1.0, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ToArrayNum(tt.value)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantOk, ok)
+		})
+	}
 }
 
 func TestToNumber(t *testing.T) {
@@ -135,6 +181,21 @@ func TestToNumber(t *testing.T) {
 		value:  "42",
 		want:   0,
 		wantOk: false,
+	}, {
+		name:   "json number",
+		value:  json.Number("4.2e1"),
+		want:   42,
+		wantOk: true,
+	}, {
+		name:   "invalid json number",
+		value:  json.Number("foo"),
+		want:   0,
+		wantOk: false,
+	}, {
+		name:   "bool",
+		value:  true,
+		want:   0,
+		wantOk: false,
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -148,3 +209,36 @@ func TestToNumber(t *testing.T) {
 		})
 	}
 }
+
+func TestSortedKeys(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]string{}, SortedKeys(map[string]any{}))
+	assert.Equal([]string{"", "B", "a", "b"}, SortedKeys(map[string]any{"b": 1, "a": 2, "B": 3, "": 4}))
+}
+
+func TestToObject(t *testing.T) {
+	type name string
+	tests := []struct {
+		value any
+		want  map[string]any
+		ok    bool
+	}{
+		{map[string]any{"a": 1.0}, map[string]any{"a": 1.0}, true},
+		{map[string]int{"a": 1}, map[string]any{"a": 1}, true},
+		{map[name]bool{"a": true}, map[string]any{"a": true}, true},
+		{map[int]string{1: "a"}, nil, false},
+		{[]any{"a"}, nil, false},
+		{nil, nil, false},
+	}
+	for _, tt := range tests {
+		assert := assert.New(t)
+		object, ok := ToObject(tt.value)
+		assert.Equal(tt.ok, ok)
+		assert.Equal(tt.want, object)
+		keys, ok := ObjectKeys(tt.value)
+		assert.Equal(tt.ok, ok)
+		if tt.ok {
+			assert.Equal([]string{"a"}, keys)
+		}
+	}
+}